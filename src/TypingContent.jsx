@@ -1,11 +1,17 @@
 import { useState, useEffect, useRef, useCallback } from 'react'
 import { useAppContext } from './App.jsx'
 import VolumeControl from './components/VolumeControl.jsx'
+import LevelControl from './components/LevelControl.jsx'
 import { GrLinkPrevious } from 'react-icons/gr'
 import { GrLinkNext } from 'react-icons/gr'
 
+// CPM などの速度を表示用に整数に丸める (記録がない場合は '-')
+function formatRate (value) {
+  return value > 0 ? Math.round(value) : '-'
+}
+
 function TypingContent () {
-  const { speak } = useAppContext()
+  const { selectedLevel, speak } = useAppContext()
   // 0: 初期状態スタートボタン表示
   // 1: タイピング表示
   const [progress, setProgress] = useState(0)
@@ -25,6 +31,8 @@ function TypingContent () {
   const [questionIndex2, setQuestionIndex2] = useState(0)
   // 入力途中の文字列
   const [inputCharacters, setInputCharacters] = useState('')
+  // ローマ字のガイド (入力済みのキーと残りのおすすめのキー)
+  const [guide, setGuide] = useState({ typed: '', rest: '' })
   // 最後に押されたキー
   const [pressedKey, setPressedKey] = useState('')
   // typing-contentのref
@@ -32,27 +40,69 @@ function TypingContent () {
   // 英語か日本語かどちらを行っているか
   const whichRef = useRef(1)
   const timerIdRef = useRef(null)
-  // --- タイピング速度計測用の状態 ---
-  const [startTime, setStartTime] = useState(null) // 問題ごとのタイピング開始時間
-  const [currentCPM, setCurrentCPM] = useState(0) // 現在の問題のCPM
-  const [averageCPM, setAverageCPM] = useState(0) // 全体の平均CPM
-  const [allProblemStats, setAllProblemStats] = useState([]) // 各問題の統計 [{ cpm: number, duration: number, charCount: number }]
-  const isTypingStartedForProblem = useRef(false) // 現在の問題でタイピングが開始されたかフラグ
+  // --- タイピング速度の記録 (時間の計測と計算はGo側で行う) ---
+  const [lastQuestion, setLastQuestion] = useState(null) // 前回の問題の結果 { en, jp }
+  const [typingStats, setTypingStats] = useState(null) // GetTypingStats の結果
+  const [newBest, setNewBest] = useState([]) // 前回の問題で自己ベストを更新したモード ("en", "jp")
+  const [accuracy, setAccuracy] = useState(null) // セッション全体の正確さ (0.0〜1.0)
+  // --- ゴースト (同じ問題文の自己ベストの記録) ---
+  const [ghost, setGhost] = useState(null) // ゴーストの現在の入力位置 (GetTypingGhost の結果)
+  const [ghostResult, setGhostResult] = useState(null) // 前回の問題のゴーストとの比較 (TypingKeyDown の replay)
+  // ローマ字の対応表 ("standard", "azik", "kana", "custom")
+  const [romajiTable, setRomajiTable] = useState('standard')
+  // 練習する文の種類 ("sentence": 例文, "word": 単語)
+  const [practice, setPractice] = useState('sentence')
+  // 英語の入力の判定の設定
+  const [ignoreCase, setIgnoreCase] = useState(false)
+  const [skipPunctuation, setSkipPunctuation] = useState(false)
 
   // WASMの関数で問題のセットアップと問題数を返す
+  // レベルと練習の設定が変わったら問題を作り直し、スタート画面に戻る
   useEffect(() => {
     const initializeTyping = async () => {
       try {
-        const result = await window.CreateTyping()
+        const result = await window.CreateTyping(parseInt(selectedLevel, 10), {
+          practice,
+          ignoreCase,
+          skipPunctuation
+        })
         console.log('タイピング問題セットアップ完了:', result)
         setMaxIndex(result)
+        setProgress(0)
       } catch (error) {
         console.error('タイピング問題のセットアップに失敗しました:', error)
       }
     }
     initializeTyping()
+  }, [selectedLevel, practice, ignoreCase, skipPunctuation])
+
+  // 保存されているローマ字の対応表の設定を取得
+  useEffect(() => {
+    const loadRomajiTable = async () => {
+      const setting = await window.GetTypingRomajiTable()
+      setRomajiTable(setting.table)
+    }
+    loadRomajiTable()
   }, [])
 
+  // タイピング中はゴーストの入力位置を毎フレーム取得する
+  useEffect(() => {
+    if (progress === 0) return
+    let frameId = null
+    let cancelled = false
+    const update = async () => {
+      const position = await window.GetTypingGhost(performance.now())
+      if (cancelled) return
+      setGhost(position)
+      frameId = requestAnimationFrame(update)
+    }
+    frameId = requestAnimationFrame(update)
+    return () => {
+      cancelled = true
+      cancelAnimationFrame(frameId)
+    }
+  }, [progress])
+
   // フォーカスをする
   useEffect(() => {
     if (progress === 0) return
@@ -69,18 +119,14 @@ function TypingContent () {
       setQuestionTextArray1(array1)
       const array2 = await window.GetTypingQuestionSlice(2)
       setQuestionTextArray2(array2)
+      setGuide(await window.GetTypingGuide(2))
 
       setQuestionIndex1(0)
       setQuestionIndex2(0)
       setInputCharacters('')
       setPressedKey('')
 
-      // --- 速度関連の状態リセット ---
-      setStartTime(null) // 開始時間をリセット
-      // setCurrentCPM(0) // 現在の問題のCPMをリセット
-      isTypingStartedForProblem.current = false // タイピング開始フラグをリセット
       whichRef.current = 1 // 英語から開始
-      // --- ここまで ---
 
       if (startFlag) {
         setProgress(1)
@@ -91,11 +137,55 @@ function TypingContent () {
     [speak]
   )
 
+  // ローマ字の対応表のプリセットを選択
+  const handleTableChange = useCallback(async event => {
+    try {
+      const result = await window.SetTypingRomajiTable({
+        preset: event.target.value
+      })
+      setRomajiTable(result.table)
+    } catch (error) {
+      alert(`対応表の設定に失敗しました: ${error}`)
+    }
+  }, [])
+
+  // Google日本語入力の形式(TSV)のローマ字テーブルを読み込む
+  const handleTableImport = useCallback(event => {
+    const file = event.target.files[0]
+    if (!file) return
+    const reader = new FileReader()
+    reader.onload = async e => {
+      try {
+        const result = await window.SetTypingRomajiTable({
+          tsv: e.target.result
+        })
+        setRomajiTable(result.table)
+        alert(
+          result.skipped > 0
+            ? `ローマ字テーブルを読み込みました(${result.skipped}件は未対応のため読み飛ばしました)。`
+            : 'ローマ字テーブルを読み込みました。'
+        )
+      } catch (error) {
+        alert(`ローマ字テーブルの読み込みに失敗しました: ${error}`)
+      } finally {
+        event.target.value = ''
+      }
+    }
+    reader.onerror = () => {
+      alert('ファイルの読み込みに失敗しました。')
+      event.target.value = ''
+    }
+    reader.readAsText(file)
+  }, [])
+
   // タイピング開始
   const handleStart = useCallback(async () => {
     // --- 全体の統計情報をリセット ---
-    setAllProblemStats([])
-    setAverageCPM(0)
+    setLastQuestion(null)
+    setNewBest([])
+    setAccuracy(null)
+    setGhostResult(null)
+    setTypingStats(await window.GetTypingStats())
     // --- ここまで ---
     await selectQuestion(0, true)
     // 開始時にフォーカス
@@ -143,19 +233,12 @@ function TypingContent () {
       if (timerIdRef.current) return
 
       const moji = e.key
+      // Shift などの1文字でないキーは判定しない
+      if (moji.length !== 1) return
 
-      // --- 最初の有効なキー入力でタイマー開始 ---
-      if (!isTypingStartedForProblem.current && moji.length === 1) {
-        // 1文字のキー入力で開始
-        setStartTime(Date.now())
-        isTypingStartedForProblem.current = true
-      }
-
-      let input = inputCharacters + moji
-      setInputCharacters(input)
       setPressedKey(moji)
 
-      let result = 0
+      let result = null
       let currentQuestionIndex = 0
       let currentQuestionArray = []
       let nextWhich = whichRef.current
@@ -165,89 +248,69 @@ function TypingContent () {
         // 英語
         currentQuestionIndex = questionIndex1
         currentQuestionArray = questionTextArray1
-        result = await window.TypingKeyDown(input, currentQuestionIndex, 1)
-        if (result > currentQuestionIndex) {
-          setQuestionIndex1(result)
-          setInputCharacters('')
-          if (result >= currentQuestionArray.length) {
-            nextWhich = 2 // 日本語へ
+        // 入力途中のキーはGo側で保持する
+        result = await window.TypingKeyDown(moji, 1, e.timeStamp)
+        setInputCharacters(result.buffer)
+        if (result.index > currentQuestionIndex) {
+          setQuestionIndex1(result.index)
+          if (result.index >= currentQuestionArray.length) {
+            if (questionTextArray2.length > 0) {
+              nextWhich = 2 // 日本語へ
+            } else {
+              // 日本語がない問題(単語の練習で意味がかなでない場合)は英語だけで完了
+              questionCompleted = true
+            }
           }
         }
       } else if (whichRef.current === 2) {
         // 日本語
         currentQuestionIndex = questionIndex2
         currentQuestionArray = questionTextArray2
-        result = await window.TypingKeyDown(input, currentQuestionIndex, 2)
-        if (result > currentQuestionIndex) {
-          setQuestionIndex2(result)
-          setInputCharacters('')
-          if (result >= currentQuestionArray.length) {
-            // --- 問題完了時の速度計算 ---
+        result = await window.TypingKeyDown(moji, 2, e.timeStamp)
+        setInputCharacters(result.buffer)
+        setGuide(result.guide)
+        if (result.index > currentQuestionIndex) {
+          setQuestionIndex2(result.index)
+          if (result.index >= currentQuestionArray.length) {
             questionCompleted = true
-            const endTime = Date.now()
-            if (startTime) {
-              // startTimeが記録されている場合のみ計算
-              const duration = (endTime - startTime) / 1000 // 秒
-              const totalChars =
-                questionTextArray1.length + questionTextArray2.length
-              const cpm =
-                totalChars > 0 && duration > 0
-                  ? Math.round((totalChars / duration) * 60)
-                  : 0
-
-              setCurrentCPM(cpm) // 現在の問題のCPMをセット
-
-              const newStat = {
-                cpm,
-                duration,
-                charCount: totalChars,
-                index: currentIndex
-              }
-              const updatedStats = [...allProblemStats, newStat]
-              setAllProblemStats(updatedStats)
-
-              // 平均CPMを計算
-              const totalCPM = updatedStats.reduce(
-                (sum, stat) => sum + stat.cpm,
-                0
-              )
-              const avgCPM =
-                updatedStats.length > 0
-                  ? Math.round(totalCPM / updatedStats.length)
-                  : 0
-              setAverageCPM(avgCPM)
-            }
-            // --- 計算ここまで ---
-
-            // 次の問題へ遷移
-            timerIdRef.current = setTimeout(async () => {
-              const nextIndex =
-                currentIndex + 1 >= maxIndex ? 0 : currentIndex + 1
-              await selectQuestion(nextIndex)
-              timerIdRef.current = null
-            }, 500) // 500ms待機
-
-            nextWhich = 1 // 次は英語から
           }
         }
       }
 
+      if (questionCompleted) {
+        // --- 問題完了時の記録の取得 (キー入力の時刻からGo側で計算する) ---
+        const sessionStats = await window.GetTypingSessionStats()
+        setLastQuestion(sessionStats.current)
+        setAccuracy(sessionStats.accuracy)
+        setNewBest(result.personalBest)
+        setGhostResult(result.replay)
+        setTypingStats(await window.GetTypingStats())
+        // --- ここまで ---
+
+        // 次の問題へ遷移
+        timerIdRef.current = setTimeout(async () => {
+          const nextIndex =
+            currentIndex + 1 >= maxIndex ? 0 : currentIndex + 1
+          await selectQuestion(nextIndex)
+          timerIdRef.current = null
+        }, 500) // 500ms待機
+
+        nextWhich = 1 // 次は英語から
+      }
+
       // whichRef の更新は状態遷移が発生した場合のみ
       if (nextWhich !== whichRef.current) {
         whichRef.current = nextWhich
       }
     },
     [
-      inputCharacters,
       questionIndex1,
       questionIndex2,
       questionTextArray1,
       questionTextArray2,
-      startTime,
       currentIndex,
       maxIndex,
-      selectQuestion,
-      allProblemStats
+      selectQuestion
     ]
   )
 
@@ -262,7 +325,66 @@ function TypingContent () {
   let content = null
 
   if (progress === 0) {
-    content = <button onClick={handleStart}>タイピング開始</button>
+    content = (
+      <>
+        <div className='romaji-table-area'>
+          <label>
+            入力方式:{' '}
+            <select value={romajiTable} onChange={handleTableChange}>
+              <option value='standard'>ローマ字入力</option>
+              <option value='azik'>AZIK</option>
+              <option value='kana'>かな入力</option>
+              {romajiTable === 'custom' && (
+                <option value='custom' disabled>
+                  カスタム
+                </option>
+              )}
+            </select>
+          </label>
+          <input
+            type='file'
+            id='romajiTableFile'
+            accept='.txt,.tsv'
+            onChange={handleTableImport}
+            className='hidden-input'
+          />
+          <label htmlFor='romajiTableFile' className='custom-file-button'>
+            ローマ字テーブルを読み込む
+          </label>
+        </div>
+        <div className='romaji-table-area'>
+          <label>
+            練習:{' '}
+            <select
+              value={practice}
+              onChange={event => setPractice(event.target.value)}
+            >
+              <option value='sentence'>例文</option>
+              <option value='word'>単語</option>
+            </select>
+          </label>
+          <label>
+            <input
+              type='checkbox'
+              checked={ignoreCase}
+              onChange={event => setIgnoreCase(event.target.checked)}
+            />
+            大文字と小文字を区別しない
+          </label>
+          <label>
+            <input
+              type='checkbox'
+              checked={skipPunctuation}
+              onChange={event => setSkipPunctuation(event.target.checked)}
+            />
+            英語の句読点を飛ばす
+          </label>
+        </div>
+        <button onClick={handleStart} disabled={maxIndex === 0}>
+          タイピング開始
+        </button>
+      </>
+    )
   } else if (progress === 1) {
     content = (
       <div
@@ -304,18 +426,62 @@ function TypingContent () {
             ))}
         </div>
 
+        <div className='romaji-area'>
+          <span className='correct-char'>{guide.typed}</span>
+          <span>{guide.rest}</span>
+        </div>
+
         <div className='kanji-area'>{questionText.jp2}</div>
 
+        <div className='ghost-area'>
+          ゴースト:{' '}
+          {ghost ? (
+            <>
+              <progress value={ghost.progress} max='1' />
+              <span>{(ghost.duration / 1000).toFixed(2)}秒</span>
+            </>
+          ) : (
+            <span>この問題の記録はまだありません</span>
+          )}
+          {ghostResult?.comparison && (
+            <span>
+              前回の問題: ゴーストより
+              {(Math.abs(ghostResult.comparison.duration) / 1000).toFixed(2)}秒
+              {ghostResult.comparison.duration < 0 ? '速い' : '遅い'}
+            </span>
+          )}
+          {ghostResult?.newGhost && <span>(自己ベスト更新!)</span>}
+        </div>
+
         <div className='key-area'>
           最後に押されたキー: <span>{pressedKey}</span>
+          入力中: <span>{inputCharacters}</span>
         </div>
 
         <div className='stats-area'>
-          <span>前回のCPM: {currentCPM > 0 ? currentCPM : '-'}</span>
-          <span>平均CPM: {averageCPM > 0 ? averageCPM : '-'}</span>
+          <span>
+            前回のCPM: 英語 {formatRate(lastQuestion?.en.cpm)} / 日本語{' '}
+            {formatRate(lastQuestion?.jp.cpm)}
+          </span>
+          <span>
+            平均: 英語 {formatRate(typingStats?.session?.en.cpm)} CPM (
+            {formatRate(typingStats?.session?.en.wpm)} WPM) / 日本語{' '}
+            {formatRate(typingStats?.session?.jp.kpm)} KPM
+          </span>
+          <span>
+            正確さ: {accuracy !== null ? `${Math.round(accuracy * 100)}%` : '-'}
+          </span>
+          <span>
+            自己ベスト: 英語 {formatRate(typingStats?.bests.en?.result.cpm)} /
+            日本語 {formatRate(typingStats?.bests.jp?.result.cpm)} CPM
+            {newBest.length > 0 && ' (更新!)'}
+          </span>
         </div>
 
-        <p>CPM: 1分あたりに入力できる文字数</p>
+        <p>
+          CPM: 1分あたりに入力できる文字数、WPM: 1分あたりの語数 (5文字で1語)、
+          KPM: 1分あたりの正しいキー入力の数
+        </p>
 
         <div className='button-container'>
           <button onClick={handlePrevious}>
@@ -335,6 +501,7 @@ function TypingContent () {
     <>
       <div className='typing-container'>{content}</div>
       <VolumeControl />
+      <LevelControl />
     </>
   )
 }
@@ -0,0 +1,63 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"slices"
+	"syscall/js"
+)
+
+// GetLevelStats はJavaScriptから呼び出され、レベルごとの直近の正答率と、
+// レベル指定 "auto" で出題される各レベルの割合の見込みを返します。
+//
+// 引数:
+//   - なし (args は使用されません)
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: レベルの昇順に並んだ `{level, accuracy, answered, share}` の配列と、目標の正答率 target を
+//     `{target, levels}` にまとめたもので解決されます。
+//     accuracy は直近の解答の正答率 (解答がない場合は null)、answered はその計算に使った解答数、
+//     share は "auto" で出題される割合 (0.0〜1.0) の見込みです。
+//   - 失敗時: エラーメッセージで拒否されます。
+func GetLevelStats(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if appData.Data == nil {
+				reject.Invoke(js.ValueOf("Go関数(GetLevelStats)エラー: appDataが初期化されていません。InitializeAppDataが正常に完了したか確認してください。"))
+				return
+			}
+			mix := appData.LevelMix(appData.Query(objects.Filter{Excluded: objects.ExcludedNot, Sort: objects.SortID}))
+			levels := make([]int, 0, len(mix))
+			for level := range mix {
+				levels = append(levels, level)
+			}
+			slices.Sort(levels)
+
+			jsLevels := make([]interface{}, len(levels))
+			for i, level := range levels {
+				accuracy, answered := appData.LevelAccuracy(level)
+				var jsAccuracy interface{}
+				if answered > 0 {
+					jsAccuracy = accuracy
+				}
+				jsLevels[i] = map[string]interface{}{
+					"level":    level,
+					"accuracy": jsAccuracy,
+					"answered": answered,
+					"share":    mix[level],
+				}
+			}
+			resolve.Invoke(map[string]interface{}{
+				"target": objects.AdaptiveTarget,
+				"levels": jsLevels,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
@@ -0,0 +1,139 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"fmt"
+	"syscall/js"
+)
+
+// CreateClozeQuiz はJavaScriptから呼び出され、英語例文の見出し語を空欄にした穴埋め問題を準備して返します。
+// 例文に見出し語 (変化形を含む) が含まれない単語は出題されません。
+//
+// 引数:
+//   - args[0]: 絞り込み条件 (オブジェクト型、形式は parseFilter を参照) または level (数値型)。
+//     数値の場合の扱いは CreateQuiz と同じです。
+//   - args[1]: choiceCount (数値型) - 生成する選択肢の数（正解を含む）。
+//   - args[2]: options (オブジェクト型、省略可能) - ダミー選択肢の選び方 (distractors)、周回 (missedOnly) と乱数のシード (seed)。
+//     形式は parseQuizOptions を参照。
+//     direction は無視されます。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{id, en, jp, sentence, hint, answer, choices, difficulty, round}` で解決されます。
+//     sentence は見出し語を "_____" に置き換えた例文、hint は日本語例文、
+//     answer は空欄に入る例文中の実際の表記 (例: "took")、
+//     choices は選択肢 (`{id, text}`、text は空欄と同じ語形に変化させた英単語) の配列、
+//     round は周回の出題状況 (roundToJS を参照) です。
+//   - 失敗時: エラーメッセージで拒否されます。
+func CreateClozeQuiz(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if appData.Data == nil {
+				reject.Invoke(js.ValueOf("Go関数(CreateClozeQuiz)エラー: appDataが初期化されていません。InitializeAppDataが正常に完了したか確認してください。"))
+				return
+			}
+			if len(args) != 2 && len(args) != 3 {
+				reject.Invoke(js.ValueOf("Go関数(CreateClozeQuiz)エラー: 引数は2つまたは3つ必要です"))
+				return
+			}
+			filter, err := parseFilter(args[0], objects.LevelFilter)
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CreateClozeQuiz)エラー: 引数0が不正です: %v", err)))
+				return
+			}
+			if args[1].Type() != js.TypeNumber {
+				reject.Invoke(js.ValueOf("Go関数(CreateClozeQuiz)エラー: 引数1は数値である必要があります。"))
+				return
+			}
+			choiceCount := args[1].Int()
+			var options quizOptions
+			if len(args) == 3 {
+				options, err = parseQuizOptions(args[2])
+				if err != nil {
+					reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CreateClozeQuiz)エラー: 引数2が不正です: %v", err)))
+					return
+				}
+			}
+			if clozeData.FilteredArray == nil || !clozeData.Filter.Equal(filter) || !sameSeed(clozeData.Seed, options.seed) {
+				clozeData.Seed = options.seed
+				clozeData.Init(&appData, filter, choiceCount)
+			}
+			clozeData.Strategies = options.strategies
+			clozeData.Round.MissedOnly = options.missedOnly
+			clozeData.Next()
+			cloze := clozeData.Current
+			if cloze == nil {
+				reject.Invoke(js.ValueOf("Go関数(CreateClozeQuiz)エラー: 次の問題の取得に失敗しました。例文に見出し語を含むデータがない可能性があります。"))
+				return
+			}
+
+			choices := make([]interface{}, len(cloze.Options))
+			for i, option := range cloze.Options {
+				choices[i] = map[string]interface{}{
+					"id":   option.ID,
+					"text": option.Text,
+				}
+			}
+			resolve.Invoke(map[string]interface{}{
+				"id":         cloze.Datum.ID,
+				"en":         cloze.Datum.Word,
+				"jp":         cloze.Datum.DefinitionJa,
+				"sentence":   cloze.Sentence,
+				"hint":       cloze.Hint,
+				"answer":     cloze.Answer,
+				"choices":    choices,
+				"difficulty": clozeData.Difficulty,
+				"round":      roundToJS(&clozeData.Round),
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// CheckClozeAnswer はJavaScriptから呼び出され、現在の穴埋め問題に対する記述式の解答を採点します。
+// 大文字・小文字と前後の空白は区別しません。
+//
+// 引数:
+//   - args[0]: ユーザーが入力した解答 (文字列型)。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{correct, close, answer}` で解決されます。
+//     close は見出し語の別の変化形 (例: took に対する take) を解答した場合に true、
+//     answer は正解 (例文中の実際の表記) です。
+//   - 失敗時: エラーメッセージで拒否されます。
+func CheckClozeAnswer(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if clozeData.Current == nil {
+				reject.Invoke(js.ValueOf("Go関数(CheckClozeAnswer)エラー: 問題がありません。CreateClozeQuizを先に呼び出してください。"))
+				return
+			}
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(CheckClozeAnswer)エラー: 引数は1つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeString {
+				reject.Invoke(js.ValueOf("Go関数(CheckClozeAnswer)エラー: 引数は文字列型である必要があります"))
+				return
+			}
+			result := clozeData.Check(args[0].String())
+			resolve.Invoke(map[string]interface{}{
+				"correct": result.Correct,
+				"close":   result.Close,
+				"answer":  result.Answer,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
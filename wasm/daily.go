@@ -0,0 +1,261 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"english_app_for_japanese/wasm/quiz"
+	"fmt"
+	"slices"
+	"syscall/js"
+	"time"
+)
+
+// CreateDailyChallenge はJavaScriptから呼び出され、デイリーチャレンジを開始します。
+// 同じ日付では、除外リストや出題のオプションに関係なく、すべての学習者に同じ問題と選択肢が同じ順に出題されます。
+// 呼び出すたびに最初の問題から挑戦し直します。
+//
+// 引数:
+//   - args[0]: choiceCount (数値型) - 生成する選択肢の数（正解を含む）。
+//   - args[1]: options (オブジェクト型、省略可能) - date (YYYY-MM-DD 形式の文字列、省略時は今日) と
+//     count (問題数、省略時は quiz.DailyCount)。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{date, total, played, record}` で解決されます。
+//     played はその日付のデイリーチャレンジをすでに解いた場合に true、
+//     record は履歴に残っている結果 (形式は dailyResultToJS を参照、解いていない場合は null) です。
+//   - 失敗時: エラーメッセージで拒否されます。
+func CreateDailyChallenge(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if appData.Data == nil {
+				reject.Invoke(js.ValueOf("Go関数(CreateDailyChallenge)エラー: appDataが初期化されていません。InitializeAppDataが正常に完了したか確認してください。"))
+				return
+			}
+			if len(args) != 1 && len(args) != 2 {
+				reject.Invoke(js.ValueOf("Go関数(CreateDailyChallenge)エラー: 引数は1つまたは2つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeNumber {
+				reject.Invoke(js.ValueOf("Go関数(CreateDailyChallenge)エラー: 引数0は数値である必要があります。"))
+				return
+			}
+			choiceCount := args[0].Int()
+			date := time.Now().Format(quiz.DailyDateLayout)
+			count := quiz.DailyCount
+			if len(args) == 2 && !args[1].IsUndefined() && !args[1].IsNull() {
+				if args[1].Type() != js.TypeObject {
+					reject.Invoke(js.ValueOf("Go関数(CreateDailyChallenge)エラー: 引数1はオブジェクトである必要があります"))
+					return
+				}
+				value, err := getString(args[1], "date")
+				if err != nil {
+					reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CreateDailyChallenge)エラー: %v", err)))
+					return
+				}
+				if value != "" {
+					if _, err := time.Parse(quiz.DailyDateLayout, value); err != nil {
+						reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CreateDailyChallenge)エラー: date は YYYY-MM-DD 形式である必要があります: %s", value)))
+						return
+					}
+					date = value
+				}
+				if v := args[1].Get("count"); !v.IsUndefined() && !v.IsNull() {
+					if v.Type() != js.TypeNumber || v.Int() <= 0 {
+						reject.Invoke(js.ValueOf("Go関数(CreateDailyChallenge)エラー: count は 1 以上の数値である必要があります"))
+						return
+					}
+					count = v.Int()
+				}
+			}
+			dailyData.Init(&appData, date, count, choiceCount)
+			if len(dailyData.FilteredArray) == 0 {
+				reject.Invoke(js.ValueOf("Go関数(CreateDailyChallenge)エラー: 出題できるデータがありません。"))
+				return
+			}
+			record, played := dailyHistory[date]
+			var jsRecord interface{}
+			if played {
+				jsRecord = dailyResultToJS(record)
+			}
+			resolve.Invoke(map[string]interface{}{
+				"date":   date,
+				"total":  len(dailyData.FilteredArray),
+				"played": played,
+				"record": jsRecord,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// NextDailyQuestion はJavaScriptから呼び出され、デイリーチャレンジの次の問題と選択肢を返します。
+// 解答時間は、この関数を呼び出した時刻から計測します。
+//
+// 引数:
+//   - なし (args は使用されません)
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{id, en, jp, en2, jp2, direction, prompt, choices, round}` で解決されます。
+//     choices は選択肢 (`{id, jp, en, text}`、CreateQuizChoices と同じ形式) の配列、
+//     round は出題状況 (roundToJS を参照) です。すべての問題を出題し終えた場合は null で解決されます。
+//   - 失敗時: エラーメッセージで拒否されます。
+func NextDailyQuestion(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if dailyData.FilteredArray == nil {
+				reject.Invoke(js.ValueOf("Go関数(NextDailyQuestion)エラー: デイリーチャレンジが開始されていません。CreateDailyChallengeを先に呼び出してください。"))
+				return
+			}
+			dailyData.Next()
+			answer := dailyData.CorrectAnswer
+			if answer == nil {
+				resolve.Invoke(js.Null())
+				return
+			}
+			choices := make([]interface{}, len(dailyData.OptionsArray))
+			for i, choice := range dailyData.OptionsArray {
+				choices[i] = map[string]interface{}{
+					"id":   choice.ID,
+					"jp":   choice.DefinitionJa,
+					"en":   choice.Word,
+					"text": dailyData.OptionText(&choice),
+				}
+			}
+			resolve.Invoke(map[string]interface{}{
+				"id":        answer.ID,
+				"en":        answer.Word,
+				"jp":        answer.DefinitionJa,
+				"en2":       answer.ExampleEn,
+				"jp2":       answer.ExampleJa,
+				"direction": string(dailyData.CurrentDirection),
+				"prompt":    dailyData.PromptText(),
+				"choices":   choices,
+				"round":     roundToJS(&dailyData.Round),
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// AnswerDailyChallenge はJavaScriptから呼び出され、デイリーチャレンジの現在の問題に対する解答を照合します。
+// すべての問題に解答すると結果を計算して履歴に追加し、ブラウザの localStorage に保存します。
+// 同じ日付の2回目以降の挑戦では、履歴の結果 (最初の挑戦の結果) は変わらず、挑戦した回数のみが増えます。
+//
+// 引数:
+//   - args[0]: 選択された選択肢の単語ID (数値型)。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{correct, answer, elapsed, correctCount, answered, done, result, record}` で解決されます。
+//     answer は正解の単語 (`{id, en, jp}`)、elapsed は解答時間 (ミリ秒)、
+//     correctCount と answered はこれまでの正解数と解答数です。
+//     done はすべての問題に解答した場合に true で、その場合のみ result (今回の結果) と
+//     record (履歴に残った結果) が設定されます (形式は dailyResultToJS を参照)。
+//   - 失敗時: エラーメッセージで拒否されます。
+func AnswerDailyChallenge(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(AnswerDailyChallenge)エラー: 引数は1つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeNumber {
+				reject.Invoke(js.ValueOf("Go関数(AnswerDailyChallenge)エラー: 引数は数値型が必要です"))
+				return
+			}
+			result, err := dailyData.Answer(args[0].Int())
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(AnswerDailyChallenge)エラー: %v", err)))
+				return
+			}
+			if errMsg := saveProgress(); errMsg != "" {
+				reject.Invoke(js.ValueOf(errMsg))
+				return
+			}
+			jsResult := map[string]interface{}{
+				"correct": result.Correct,
+				"answer": map[string]interface{}{
+					"id": result.CorrectAnswer.ID,
+					"en": result.CorrectAnswer.Word,
+					"jp": result.CorrectAnswer.DefinitionJa,
+				},
+				"elapsed":      result.Elapsed.Milliseconds(),
+				"correctCount": result.Score,
+				"answered":     result.Answered,
+				"done":         dailyData.Done(),
+				"result":       nil,
+				"record":       nil,
+			}
+			if dailyData.Done() {
+				daily := dailyData.Result()
+				record := dailyHistory.Record(daily)
+				if errMsg := saveDailyHistory(); errMsg != "" {
+					reject.Invoke(js.ValueOf(errMsg))
+					return
+				}
+				jsResult["result"] = dailyResultToJS(daily)
+				jsResult["record"] = dailyResultToJS(record)
+			}
+			resolve.Invoke(jsResult)
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// GetDailyHistory はJavaScriptから呼び出され、デイリーチャレンジの結果の履歴を新しい日付から順に返します。
+//
+// 引数:
+//   - なし (args は使用されません)
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: 結果 (形式は dailyResultToJS を参照) の配列で解決されます。
+func GetDailyHistory(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		go func() {
+			dates := make([]string, 0, len(dailyHistory))
+			for date := range dailyHistory {
+				dates = append(dates, date)
+			}
+			// YYYY-MM-DD 形式なので文字列の降順が新しい順になる
+			slices.Sort(dates)
+			slices.Reverse(dates)
+			results := make([]interface{}, len(dates))
+			for i, date := range dates {
+				results[i] = dailyResultToJS(dailyHistory[date])
+			}
+			resolve.Invoke(results)
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// dailyResultToJS はデイリーチャレンジの結果をJavaScriptのオブジェクト
+// (`{date, score, correct, total, totalTime, attempts}`、totalTime はミリ秒) に変換します。
+func dailyResultToJS(r quiz.DailyResult) map[string]interface{} {
+	return map[string]interface{}{
+		"date":      r.Date,
+		"score":     r.Score,
+		"correct":   r.Correct,
+		"total":     r.Total,
+		"totalTime": r.TotalTime,
+		"attempts":  r.Attempts,
+	}
+}
@@ -0,0 +1,247 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"fmt"
+	"math"
+	"syscall/js"
+	"time"
+)
+
+// parseFilter はJavaScriptから渡された絞り込み条件を objects.Filter に変換します。
+// 従来の数値によるレベル指定との互換性のため、数値が渡された場合は legacy で変換します。
+// 文字列 "auto" が渡された場合は、正答率が目標 (objects.AdaptiveTarget) 前後になるよう
+// レベルの配分を自動で調整する objects.AutoFilter になります。
+//
+// JavaScriptのオブジェクトで指定する場合のプロパティ (すべて省略可能):
+//   - levels: 対象のレベルの配列 (例: [1, 2])。省略時はすべてのレベル。
+//   - excluded: 除外リストに対する条件。"not" (既定値), "only", "all" のいずれか。
+//   - tags: タグの配列。いずれかのタグを持つデータのみを対象にします。
+//   - minId, maxId: ID の範囲。
+//   - minLength, maxLength: 見出し語の文字数の範囲。
+//   - hasExample: 英語例文の有無 (真偽値)。
+//   - minMastery, maxMastery: 習熟度 (0.0〜1.0) の範囲。
+//   - sort: 並び順。"shuffle" (既定値), "id", "id_desc", "word", "word_desc", "level", "mastery", "mastery_desc",
+//     "adaptive" (推定正答率が目標に近い単語から) のいずれか。
+//
+// 引数:
+//   - value: JavaScriptの数値 (従来のレベル指定)、文字列 "auto" またはオブジェクト。
+//   - legacy: 数値が渡された場合に Filter を作成する関数。
+//
+// 戻り値:
+//   - 変換された objects.Filter。
+//   - 値の型や内容が不正な場合のエラー。
+func parseFilter(value js.Value, legacy func(level int) objects.Filter) (objects.Filter, error) {
+	switch value.Type() {
+	case js.TypeNumber:
+		return legacy(value.Int()), nil
+	case js.TypeString:
+		if value.String() != "auto" {
+			return objects.Filter{}, fmt.Errorf("絞り込み条件の文字列は \"auto\" のみ指定できます: %s", value.String())
+		}
+		return objects.AutoFilter(), nil
+	case js.TypeObject:
+	default:
+		return objects.Filter{}, fmt.Errorf("絞り込み条件は数値、\"auto\" またはオブジェクトである必要があります")
+	}
+
+	var f objects.Filter
+	var err error
+	if f.Levels, err = getIntArray(value, "levels"); err != nil {
+		return f, err
+	}
+	if f.Tags, err = getStringArray(value, "tags"); err != nil {
+		return f, err
+	}
+
+	excluded, err := getString(value, "excluded")
+	if err != nil {
+		return f, err
+	}
+	switch objects.ExcludedState(excluded) {
+	case "", objects.ExcludedNot:
+		f.Excluded = objects.ExcludedNot
+	case objects.ExcludedOnly, objects.ExcludedAll:
+		f.Excluded = objects.ExcludedState(excluded)
+	default:
+		return f, fmt.Errorf("excluded の値が不正です: %s", excluded)
+	}
+
+	sort, err := getString(value, "sort")
+	if err != nil {
+		return f, err
+	}
+	switch objects.SortOrder(sort) {
+	case "", objects.SortShuffle:
+		f.Sort = objects.SortShuffle
+	case objects.SortID, objects.SortIDDesc, objects.SortWord, objects.SortWordDesc,
+		objects.SortLevel, objects.SortMastery, objects.SortMasteryDesc, objects.SortAdaptive:
+		f.Sort = objects.SortOrder(sort)
+	default:
+		return f, fmt.Errorf("sort の値が不正です: %s", sort)
+	}
+
+	for key, dst := range map[string]*int{
+		"minId":     &f.MinID,
+		"maxId":     &f.MaxID,
+		"minLength": &f.MinLength,
+		"maxLength": &f.MaxLength,
+	} {
+		v := value.Get(key)
+		if v.IsUndefined() || v.IsNull() {
+			continue
+		}
+		if v.Type() != js.TypeNumber {
+			return f, fmt.Errorf("%s は数値である必要があります", key)
+		}
+		*dst = v.Int()
+	}
+
+	if v := value.Get("hasExample"); !v.IsUndefined() && !v.IsNull() {
+		if v.Type() != js.TypeBoolean {
+			return f, fmt.Errorf("hasExample は真偽値である必要があります")
+		}
+		hasExample := v.Bool()
+		f.HasExample = &hasExample
+	}
+
+	for key, dst := range map[string]**float64{
+		"minMastery": &f.MinMastery,
+		"maxMastery": &f.MaxMastery,
+	} {
+		v := value.Get(key)
+		if v.IsUndefined() || v.IsNull() {
+			continue
+		}
+		if v.Type() != js.TypeNumber {
+			return f, fmt.Errorf("%s は数値である必要があります", key)
+		}
+		mastery := v.Float()
+		*dst = &mastery
+	}
+
+	return f, nil
+}
+
+// searchLevelFilter は SearchData の従来のレベル指定を objects.Filter に変換します。
+// 0 は除外リストに含まれるデータ、それ以外は除外リストに含まれない指定レベルのデータを意味します。
+func searchLevelFilter(level int) objects.Filter {
+	if level == 0 {
+		return objects.Filter{Excluded: objects.ExcludedOnly, Sort: objects.SortShuffle}
+	}
+	return objects.Filter{Levels: []int{level}, Excluded: objects.ExcludedNot, Sort: objects.SortShuffle}
+}
+
+// getString はJavaScriptのオブジェクトから文字列のプロパティを取得します。
+// プロパティが存在しない場合は空文字列を返します。
+func getString(obj js.Value, key string) (string, error) {
+	v := obj.Get(key)
+	if v.IsUndefined() || v.IsNull() {
+		return "", nil
+	}
+	if v.Type() != js.TypeString {
+		return "", fmt.Errorf("%s は文字列である必要があります", key)
+	}
+	return v.String(), nil
+}
+
+// getBool はJavaScriptのオブジェクトから真偽値のプロパティを取得します。
+// プロパティが存在しない場合は false を返します。
+func getBool(obj js.Value, key string) (bool, error) {
+	v := obj.Get(key)
+	if v.IsUndefined() || v.IsNull() {
+		return false, nil
+	}
+	if v.Type() != js.TypeBoolean {
+		return false, fmt.Errorf("%s は真偽値である必要があります", key)
+	}
+	return v.Bool(), nil
+}
+
+// getSeed はJavaScriptのオブジェクトから乱数のシード (0 以上の整数) のプロパティを取得します。
+// プロパティが存在しない場合は nil を返します。
+func getSeed(obj js.Value, key string) (*uint64, error) {
+	v := obj.Get(key)
+	if v.IsUndefined() || v.IsNull() {
+		return nil, nil
+	}
+	if v.Type() != js.TypeNumber {
+		return nil, fmt.Errorf("%s は数値である必要があります", key)
+	}
+	f := v.Float()
+	if f < 0 || f != math.Trunc(f) || f > 1<<53 {
+		return nil, fmt.Errorf("%s は 0 以上の整数である必要があります", key)
+	}
+	seed := uint64(f)
+	return &seed, nil
+}
+
+// getDuration はJavaScriptのオブジェクトからミリ秒単位の時間 (0 以上の数値) のプロパティを取得します。
+// プロパティが存在しない場合は 0 を返します。
+func getDuration(obj js.Value, key string) (time.Duration, error) {
+	v := obj.Get(key)
+	if v.IsUndefined() || v.IsNull() {
+		return 0, nil
+	}
+	if v.Type() != js.TypeNumber || v.Float() < 0 {
+		return 0, fmt.Errorf("%s は 0 以上の数値 (ミリ秒) である必要があります", key)
+	}
+	return time.Duration(v.Float() * float64(time.Millisecond)), nil
+}
+
+// sameSeed は2つのシードが同じかどうか (どちらも nil の場合を含む) を判定します。
+func sameSeed(a, b *uint64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// getIntArray はJavaScriptのオブジェクトから数値の配列のプロパティを取得します。
+// プロパティが存在しない場合は nil を返します。
+func getIntArray(obj js.Value, key string) ([]int, error) {
+	v := obj.Get(key)
+	if v.IsUndefined() || v.IsNull() {
+		return nil, nil
+	}
+	if !isJSArray(v) {
+		return nil, fmt.Errorf("%s は配列である必要があります", key)
+	}
+	results := make([]int, v.Length())
+	for i := range results {
+		item := v.Index(i)
+		if item.Type() != js.TypeNumber {
+			return nil, fmt.Errorf("%s の要素は数値である必要があります", key)
+		}
+		results[i] = item.Int()
+	}
+	return results, nil
+}
+
+// getStringArray はJavaScriptのオブジェクトから文字列の配列のプロパティを取得します。
+// プロパティが存在しない場合は nil を返します。
+func getStringArray(obj js.Value, key string) ([]string, error) {
+	v := obj.Get(key)
+	if v.IsUndefined() || v.IsNull() {
+		return nil, nil
+	}
+	if !isJSArray(v) {
+		return nil, fmt.Errorf("%s は配列である必要があります", key)
+	}
+	results := make([]string, v.Length())
+	for i := range results {
+		item := v.Index(i)
+		if item.Type() != js.TypeString {
+			return nil, fmt.Errorf("%s の要素は文字列である必要があります", key)
+		}
+		results[i] = item.String()
+	}
+	return results, nil
+}
+
+// isJSArray はJavaScriptの値が配列かどうかを判定します。
+func isJSArray(v js.Value) bool {
+	return js.Global().Get("Array").Call("isArray", v).Bool()
+}
@@ -0,0 +1,173 @@
+package lemma
+
+import "strings"
+
+// Form は英単語の語形 (原形に対する変化の種類) です。
+type Form int
+
+const (
+	FormBase        Form = iota // 原形 (例: take)
+	FormS                       // 三人称単数現在形・複数形 (例: takes, children)
+	FormPast                    // 過去形 (例: took)
+	FormParticiple              // 過去分詞 (例: taken)
+	FormIng                     // 現在分詞・動名詞 (例: taking)
+	FormComparative             // 比較級 (例: bigger)
+	FormSuperlative             // 最上級 (例: biggest)
+)
+
+// detectOrder は FormOf で語形を判定する順序です。
+// 過去形と過去分詞が同じ綴りの語 (例: made) は過去形と判定します。
+var detectOrder = []Form{FormS, FormPast, FormParticiple, FormIng, FormComparative, FormSuperlative}
+
+// Inflect は原形 base を指定された語形に変化させます。
+// 不規則変化の一覧にある語はその形を、それ以外は規則変化 (語尾の e の省略、y → i、
+// 子音字の重複など) で作った形を返します。"give up" のような複数語の場合は先頭の語を変化させます。
+// 結果は小文字です。
+//
+// 引数:
+//   - base: 原形の英単語。
+//   - form: 変化させる語形。
+//
+// 戻り値:
+//   - 変化した英単語。base が空の場合は空文字列。
+func Inflect(base string, form Form) string {
+	w := normalize(base)
+	if w == "" {
+		return ""
+	}
+	if head, rest, found := strings.Cut(w, " "); found {
+		return Inflect(head, form) + " " + rest
+	}
+
+	switch form {
+	case FormS:
+		switch w {
+		case "be":
+			return "is"
+		case "have":
+			return "has"
+		}
+		for _, forms := range irregularPlurals {
+			if forms[0] == w {
+				return forms[1]
+			}
+		}
+		switch {
+		case endsWithConsonantY(w):
+			return w[:len(w)-1] + "ies"
+		case strings.HasSuffix(w, "s"), strings.HasSuffix(w, "x"), strings.HasSuffix(w, "z"),
+			strings.HasSuffix(w, "ch"), strings.HasSuffix(w, "sh"), strings.HasSuffix(w, "o"):
+			return w + "es"
+		}
+		return w + "s"
+	case FormPast, FormParticiple:
+		for _, forms := range irregularVerbs {
+			if forms[0] == w {
+				if form == FormPast {
+					return forms[1]
+				}
+				return forms[2]
+			}
+		}
+		return addSuffix(w, "ed")
+	case FormIng:
+		switch {
+		case strings.HasSuffix(w, "ie"):
+			return w[:len(w)-2] + "ying"
+		case strings.HasSuffix(w, "e") && !strings.HasSuffix(w, "ee") && !strings.HasSuffix(w, "ye") &&
+			!strings.HasSuffix(w, "oe") && len(w) > 2:
+			return w[:len(w)-1] + "ing"
+		}
+		return doubleFinal(w) + "ing"
+	case FormComparative, FormSuperlative:
+		for _, forms := range irregularComparisons {
+			if forms[0] == w {
+				if form == FormComparative {
+					return forms[1]
+				}
+				return forms[2]
+			}
+		}
+		if form == FormComparative {
+			return addSuffix(w, "er")
+		}
+		return addSuffix(w, "est")
+	}
+	return w
+}
+
+// FormOf は文中の単語 surface が見出し語 headword のどの語形であるかを判定します。
+// 原形と同じ綴り、または判定できない場合は FormBase を返します。大文字・小文字は区別しません。
+//
+// 引数:
+//   - surface: 文中に現れた単語 (例: "Took")。
+//   - headword: 見出し語 (例: "take")。
+//
+// 戻り値:
+//   - surface の語形。
+func FormOf(surface, headword string) Form {
+	s := normalize(surface)
+	h := normalize(headword)
+	if s == h {
+		return FormBase
+	}
+	for _, form := range detectOrder {
+		if Inflect(h, form) == s {
+			return form
+		}
+	}
+	// 別の綴り (例: burned と burnt) の場合は語尾で判定する
+	switch {
+	case strings.HasSuffix(s, "ing"):
+		return FormIng
+	case strings.HasSuffix(s, "ed"):
+		return FormPast
+	case strings.HasSuffix(s, "est"):
+		return FormSuperlative
+	case strings.HasSuffix(s, "er") && !strings.HasSuffix(h, "er"):
+		return FormComparative
+	case strings.HasSuffix(s, "s") && !strings.HasSuffix(h, "s"):
+		return FormS
+	}
+	return FormBase
+}
+
+// addSuffix は "ed", "er", "est" のように e で始まる語尾を規則変化で付けます。
+func addSuffix(w, suffix string) string {
+	switch {
+	case strings.HasSuffix(w, "e"):
+		return w + suffix[1:]
+	case endsWithConsonantY(w):
+		return w[:len(w)-1] + "i" + suffix
+	}
+	return doubleFinal(w) + suffix
+}
+
+// doubleFinal は短母音 + 子音字で終わる短い語 (例: stop, big) の語末の子音字を重ねます。
+// 2音節以上の語は強勢の位置が分からないため、1音節とみなせる語 (3文字の語と、
+// 子音字2つで始まる4文字の語) のみを対象にします。
+func doubleFinal(w string) string {
+	n := len(w)
+	if n < 3 || n > 4 {
+		return w
+	}
+	last, middle, first := w[n-1], w[n-2], w[n-3]
+	if isVowel(last) || strings.IndexByte("wxy", last) >= 0 {
+		return w
+	}
+	if !isVowel(middle) || isVowel(first) || (n == 4 && isVowel(w[0])) {
+		return w
+	}
+	return w + string(last)
+}
+
+// endsWithConsonantY は語が「子音字 + y」で終わるかどうかを判定します (例: study, happy)。
+func endsWithConsonantY(w string) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == 'y' && !isVowel(w[n-2])
+}
+
+// isVowel は英字が母音字 (a, e, i, o, u) かどうかを判定します。
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
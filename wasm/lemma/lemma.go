@@ -0,0 +1,545 @@
+package lemma
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// irregularVerbs は不規則動詞の {原形, 過去形, 過去分詞} の一覧です。
+// init で irregularForms に過去形・過去分詞から原形への対応が追加されます。
+var irregularVerbs = [][3]string{
+	{"be", "was", "been"},
+	{"have", "had", "had"},
+	{"do", "did", "done"},
+	{"arise", "arose", "arisen"},
+	{"awake", "awoke", "awoken"},
+	{"bear", "bore", "borne"},
+	{"beat", "beat", "beaten"},
+	{"become", "became", "become"},
+	{"begin", "began", "begun"},
+	{"bend", "bent", "bent"},
+	{"bet", "bet", "bet"},
+	{"bind", "bound", "bound"},
+	{"bite", "bit", "bitten"},
+	{"bleed", "bled", "bled"},
+	{"blow", "blew", "blown"},
+	{"break", "broke", "broken"},
+	{"breed", "bred", "bred"},
+	{"bring", "brought", "brought"},
+	{"build", "built", "built"},
+	{"burn", "burnt", "burnt"},
+	{"burst", "burst", "burst"},
+	{"buy", "bought", "bought"},
+	{"catch", "caught", "caught"},
+	{"choose", "chose", "chosen"},
+	{"cling", "clung", "clung"},
+	{"come", "came", "come"},
+	{"cost", "cost", "cost"},
+	{"creep", "crept", "crept"},
+	{"cut", "cut", "cut"},
+	{"deal", "dealt", "dealt"},
+	{"dig", "dug", "dug"},
+	{"draw", "drew", "drawn"},
+	{"dream", "dreamt", "dreamt"},
+	{"drink", "drank", "drunk"},
+	{"drive", "drove", "driven"},
+	{"eat", "ate", "eaten"},
+	{"fall", "fell", "fallen"},
+	{"feed", "fed", "fed"},
+	{"feel", "felt", "felt"},
+	{"fight", "fought", "fought"},
+	{"find", "found", "found"},
+	{"flee", "fled", "fled"},
+	{"fly", "flew", "flown"},
+	{"forbid", "forbade", "forbidden"},
+	{"forget", "forgot", "forgotten"},
+	{"forgive", "forgave", "forgiven"},
+	{"freeze", "froze", "frozen"},
+	{"get", "got", "gotten"},
+	{"give", "gave", "given"},
+	{"go", "went", "gone"},
+	{"grind", "ground", "ground"},
+	{"grow", "grew", "grown"},
+	{"hang", "hung", "hung"},
+	{"hear", "heard", "heard"},
+	{"hide", "hid", "hidden"},
+	{"hit", "hit", "hit"},
+	{"hold", "held", "held"},
+	{"hurt", "hurt", "hurt"},
+	{"keep", "kept", "kept"},
+	{"kneel", "knelt", "knelt"},
+	{"know", "knew", "known"},
+	{"lay", "laid", "laid"},
+	{"lead", "led", "led"},
+	{"leap", "leapt", "leapt"},
+	{"learn", "learnt", "learnt"},
+	{"leave", "left", "left"},
+	{"lend", "lent", "lent"},
+	{"let", "let", "let"},
+	{"lie", "lay", "lain"},
+	{"light", "lit", "lit"},
+	{"lose", "lost", "lost"},
+	{"make", "made", "made"},
+	{"mean", "meant", "meant"},
+	{"meet", "met", "met"},
+	{"mistake", "mistook", "mistaken"},
+	{"pay", "paid", "paid"},
+	{"prove", "proved", "proven"},
+	{"put", "put", "put"},
+	{"quit", "quit", "quit"},
+	{"read", "read", "read"},
+	{"ride", "rode", "ridden"},
+	{"ring", "rang", "rung"},
+	{"rise", "rose", "risen"},
+	{"run", "ran", "run"},
+	{"say", "said", "said"},
+	{"see", "saw", "seen"},
+	{"seek", "sought", "sought"},
+	{"sell", "sold", "sold"},
+	{"send", "sent", "sent"},
+	{"set", "set", "set"},
+	{"shake", "shook", "shaken"},
+	{"shed", "shed", "shed"},
+	{"shine", "shone", "shone"},
+	{"shoot", "shot", "shot"},
+	{"show", "showed", "shown"},
+	{"shrink", "shrank", "shrunk"},
+	{"shut", "shut", "shut"},
+	{"sing", "sang", "sung"},
+	{"sink", "sank", "sunk"},
+	{"sit", "sat", "sat"},
+	{"sleep", "slept", "slept"},
+	{"slide", "slid", "slid"},
+	{"speak", "spoke", "spoken"},
+	{"speed", "sped", "sped"},
+	{"spend", "spent", "spent"},
+	{"spill", "spilt", "spilt"},
+	{"spin", "spun", "spun"},
+	{"spit", "spat", "spat"},
+	{"split", "split", "split"},
+	{"spread", "spread", "spread"},
+	{"spring", "sprang", "sprung"},
+	{"stand", "stood", "stood"},
+	{"steal", "stole", "stolen"},
+	{"stick", "stuck", "stuck"},
+	{"sting", "stung", "stung"},
+	{"stink", "stank", "stank"},
+	{"stride", "strode", "strode"},
+	{"strike", "struck", "struck"},
+	{"strive", "strove", "striven"},
+	{"swear", "swore", "sworn"},
+	{"sweep", "swept", "swept"},
+	{"swim", "swam", "swum"},
+	{"swing", "swung", "swung"},
+	{"take", "took", "taken"},
+	{"teach", "taught", "taught"},
+	{"tear", "tore", "torn"},
+	{"tell", "told", "told"},
+	{"think", "thought", "thought"},
+	{"throw", "threw", "thrown"},
+	{"understand", "understood", "understood"},
+	{"undertake", "undertook", "undertaken"},
+	{"upset", "upset", "upset"},
+	{"wake", "woke", "woken"},
+	{"wear", "wore", "worn"},
+	{"weave", "wove", "woven"},
+	{"weep", "wept", "wept"},
+	{"win", "won", "won"},
+	{"wind", "wound", "wound"},
+	{"withdraw", "withdrew", "withdrawn"},
+	{"write", "wrote", "written"},
+}
+
+// irregularPlurals は不規則な複数形の {単数形, 複数形} の一覧です。
+var irregularPlurals = [][2]string{
+	{"man", "men"}, {"woman", "women"}, {"child", "children"}, {"person", "people"},
+	{"foot", "feet"}, {"tooth", "teeth"}, {"goose", "geese"}, {"mouse", "mice"},
+	{"ox", "oxen"}, {"datum", "data"}, {"criterion", "criteria"}, {"phenomenon", "phenomena"},
+	{"analysis", "analyses"}, {"crisis", "crises"}, {"thesis", "theses"}, {"basis", "bases"},
+	{"medium", "media"}, {"life", "lives"}, {"wife", "wives"}, {"knife", "knives"},
+	{"leaf", "leaves"}, {"half", "halves"}, {"wolf", "wolves"}, {"shelf", "shelves"},
+	{"thief", "thieves"}, {"self", "selves"}, {"loaf", "loaves"},
+}
+
+// irregularComparisons は不規則な比較変化の {原級, 比較級, 最上級} の一覧です。
+// 同じ原級が複数ある場合は先に書かれたものが Inflect で使われます。
+var irregularComparisons = [][3]string{
+	{"good", "better", "best"},
+	{"well", "better", "best"},
+	{"bad", "worse", "worst"},
+	{"many", "more", "most"},
+	{"much", "more", "most"},
+	{"little", "less", "least"},
+	{"far", "farther", "farthest"},
+	{"far", "further", "furthest"},
+	{"old", "elder", "eldest"},
+}
+
+// irregularForms は不規則変化形（過去形・過去分詞・複数形・比較級など）から
+// 原形への対応表です。キーと値はすべて小文字です。
+// 不規則動詞・不規則な複数形・不規則な比較変化は irregularVerbs、irregularPlurals、
+// irregularComparisons から init で追加されます。
+var irregularForms = map[string]string{
+	// be / have / do
+	"am": "be", "is": "be", "are": "be", "was": "be", "were": "be", "been": "be", "being": "be",
+	"has": "have", "had": "have", "having": "have",
+	"does": "do", "goes": "go",
+	// 助動詞
+	"could": "can", "would": "will", "should": "shall", "might": "may",
+}
+
+// regularAlso は不規則変化表にあるが、規則変化としても別の語の変化形になる語です
+// (例: lives は life の複数形だが live の三人称単数でもある)。
+// これらの語は不規則変化の原形に加えて、規則変化を取り除いた候補も原形として扱います。
+var regularAlso = map[string]bool{
+	"lives": true, "leaves": true, "bases": true,
+}
+
+// init は不規則変化の一覧から、変化形から原形への対応を irregularForms に追加します。
+func init() {
+	add := func(base string, forms ...string) {
+		for _, form := range forms {
+			// 原形と同じ綴りの変化形 (cut など) や、登録済みの変化形 (was, had など) は追加しない
+			if _, exists := irregularForms[form]; !exists && form != base {
+				irregularForms[form] = base
+			}
+		}
+	}
+	for _, forms := range irregularVerbs {
+		add(forms[0], forms[1:]...)
+	}
+	for _, forms := range irregularPlurals {
+		add(forms[0], forms[1])
+	}
+	for _, forms := range irregularComparisons {
+		add(forms[0], forms[1:]...)
+	}
+}
+
+// Candidates は与えられた英単語について、原形の候補を優先度順に返します。
+// 先頭には入力そのもの（小文字化したもの）が入り、続いて不規則変化表の原形、
+// 規則変化（-s, -es, -ies, -ed, -ied, -ing, -er, -est, 子音の重複など）を
+// 取り除いた形が続きます。候補は重複しません。
+//
+// 規則による候補は実在しない語を含むことがあるため、辞書の見出し語と照合して使うことを想定しています。
+//
+// 引数:
+//   - word: 原形を求めたい英単語。
+//
+// 戻り値:
+//   - 原形の候補のスライス（小文字）。空文字列の場合は空文字列1つのみを返します。
+func Candidates(word string) []string {
+	w := normalize(word)
+	results := []string{w}
+	if base, ok := irregularForms[w]; ok && base != w {
+		results = append(results, base)
+	}
+	for _, c := range regularCandidates(w) {
+		if !slices.Contains(results, c) {
+			results = append(results, c)
+		}
+	}
+	return results
+}
+
+// regularCandidates は小文字化した英単語 w から規則変化の語尾を取り除いた原形の候補を、優先度順に重複なく返します。
+// w そのものは含みません。
+func regularCandidates(w string) []string {
+	var results []string
+	seen := map[string]bool{w: true}
+	add := func(s string) {
+		// 2文字未満の語幹は誤検出の元になるため除外
+		if len(s) < 2 || seen[s] {
+			return
+		}
+		seen[s] = true
+		results = append(results, s)
+	}
+
+	switch {
+	// 名詞の複数形・動詞の三人称単数
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		add(w[:len(w)-3] + "y")
+		add(w[:len(w)-1])
+	case strings.HasSuffix(w, "ves") && len(w) > 4:
+		add(w[:len(w)-3] + "f")
+		add(w[:len(w)-3] + "fe")
+		add(w[:len(w)-1])
+	case strings.HasSuffix(w, "es") && len(w) > 3:
+		add(w[:len(w)-1])
+		add(w[:len(w)-2])
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us") && len(w) > 3:
+		add(w[:len(w)-1])
+	}
+
+	switch {
+	// 過去形・過去分詞
+	case strings.HasSuffix(w, "ied") && len(w) > 4:
+		add(w[:len(w)-3] + "y")
+	case strings.HasSuffix(w, "ed") && len(w) > 3:
+		stem := w[:len(w)-2]
+		add(stem)
+		add(w[:len(w)-1])
+		add(undouble(stem))
+	}
+
+	switch {
+	// 現在分詞・動名詞
+	case strings.HasSuffix(w, "ying") && len(w) > 4:
+		add(w[:len(w)-4] + "ie")
+		add(w[:len(w)-3])
+	case strings.HasSuffix(w, "ing") && len(w) > 4:
+		stem := w[:len(w)-3]
+		add(stem)
+		add(stem + "e")
+		add(undouble(stem))
+	}
+
+	switch {
+	// 比較級・最上級
+	case strings.HasSuffix(w, "iest") && len(w) > 5:
+		add(w[:len(w)-4] + "y")
+	case strings.HasSuffix(w, "est") && len(w) > 4:
+		stem := w[:len(w)-3]
+		add(stem)
+		add(stem + "e")
+		add(undouble(stem))
+	case strings.HasSuffix(w, "ier") && len(w) > 4:
+		add(w[:len(w)-3] + "y")
+	case strings.HasSuffix(w, "er") && len(w) > 3:
+		stem := w[:len(w)-2]
+		add(stem)
+		add(stem + "e")
+		add(undouble(stem))
+	}
+
+	return results
+}
+
+// Lemmatize は英単語を辞書の見出し語に戻します。次の順に isHeadword で判定し、最初に見出し語と判定されたものを返します。
+//  1. 入力そのもの (小文字化したもの)。入力が見出し語であれば、別の語の変化形とはみなしません (corner は corn にしない)。
+//  2. 不規則変化表の原形。不規則変化表にある語は、規則変化を取り除いた候補を使いません (better は bet にしない)。
+//  3. 規則変化を取り除いた候補 (優先度順)。
+//
+// どの候補も見出し語でない場合は、入力を小文字化したものを返します。
+//
+// 引数:
+//   - word: 原形に戻したい英単語。
+//   - isHeadword: 候補が辞書の見出し語かどうかを判定する関数。
+//
+// 戻り値:
+//   - 見出し語、または見つからなかった場合は小文字化した入力。
+func Lemmatize(word string, isHeadword func(string) bool) string {
+	w := normalize(word)
+	if isHeadword(w) {
+		return w
+	}
+	base, irregular := irregularForms[w]
+	if irregular && isHeadword(base) {
+		return base
+	}
+	if !irregular || regularAlso[w] {
+		for _, c := range regularCandidates(w) {
+			if isHeadword(c) {
+				return c
+			}
+		}
+	}
+	return w
+}
+
+// Matches は文中の単語 token が見出し語 headword の変化形（または同形）であるかを判定します。
+// 大文字・小文字は区別しません。規則変化を取り除いた形 (corner に対する corn など) は実在しない語や別の語になることがあるため、
+// 同じ綴りと不規則変化表を優先し、規則変化による一致は token 自体が辞書の見出し語でない場合にのみ認めます
+// (Lemmatize を参照)。
+//
+// 引数:
+//   - token: 文中の単語。
+//   - headword: 見出し語。
+//   - isHeadword: 辞書の見出し語かどうかを判定する関数。nil の場合は token が見出し語かどうかを確認しません。
+func Matches(token, headword string, isHeadword func(string) bool) bool {
+	t, h := normalize(token), normalize(headword)
+	if h == "" {
+		return false
+	}
+	if t == h {
+		return true
+	}
+	base, irregular := irregularForms[t]
+	if irregular && base == h {
+		return true
+	}
+	if irregular && !regularAlso[t] {
+		return false
+	}
+	if isHeadword != nil && isHeadword(t) {
+		return false
+	}
+	return slices.Contains(regularCandidates(t), h)
+}
+
+// Match は例文中で見つかった見出し語の出現箇所を表します。
+type Match struct {
+	Text  string // 例文中に現れた実際の文字列 (例: "went", "Running")
+	Start int    // 例文中の開始位置 (バイトオフセット)
+	End   int    // 例文中の終了位置 (バイトオフセット、この位置は含まない)
+}
+
+// Span は例文中の見出し語の出現範囲を、JavaScript の文字列インデックス
+// (UTF-16 のコード単位) で表します。フロントエンドで String.prototype.slice にそのまま渡せます。
+type Span struct {
+	Start int // 開始位置 (UTF-16 コード単位)
+	End   int // 終了位置 (UTF-16 コード単位、この位置は含まない)
+}
+
+// Spans は英文 sentence 中の見出し語 headword の出現箇所を、変化形や大文字で始まる形も含めて探し、
+// JavaScript の文字列インデックスでの範囲として返します。
+//
+// 引数:
+//   - sentence: 検索対象の英文 (例: Datum.ExampleEn)。
+//   - headword: 見出し語 (例: Datum.Word)。
+//   - isHeadword: 辞書の見出し語かどうかを判定する関数 (Matches を参照)。
+//
+// 戻り値:
+//   - 出現範囲のスライス。文中の出現順に並びます。見つからない場合は空のスライス。
+func Spans(sentence, headword string, isHeadword func(string) bool) []Span {
+	matches := Find(sentence, headword, isHeadword)
+	spans := make([]Span, len(matches))
+	for i, m := range matches {
+		spans[i] = Span{
+			Start: utf16Len(sentence[:m.Start]),
+			End:   utf16Len(sentence[:m.End]),
+		}
+	}
+	return spans
+}
+
+// utf16Len は文字列を UTF-16 で表したときのコード単位数 (JavaScript の length) を返します。
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// token は文を単語に分割した際の1語とその位置を表します。
+type token struct {
+	text       string
+	start, end int
+}
+
+// Find は英文 sentence の中から見出し語 headword の出現箇所を、変化形も含めてすべて探します。
+// "give up" のような複数語の見出し語は、各語がそれぞれ変化形として連続して現れる箇所を探します
+// (例: "gave up")。
+//
+// 引数:
+//   - sentence: 検索対象の英文 (例: Datum.ExampleEn)。
+//   - headword: 見出し語 (例: Datum.Word)。
+//   - isHeadword: 辞書の見出し語かどうかを判定する関数 (Matches を参照)。
+//
+// 戻り値:
+//   - 出現箇所のスライス。文中の出現順に並びます。見つからない場合は nil。
+func Find(sentence, headword string, isHeadword func(string) bool) []Match {
+	parts := strings.Fields(headword)
+	if len(parts) == 0 {
+		return nil
+	}
+	tokens := tokenize(sentence)
+	var matches []Match
+	for i := 0; i+len(parts) <= len(tokens); i++ {
+		ok := true
+		for j, part := range parts {
+			if !Matches(tokens[i+j].text, part, isHeadword) {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		start := tokens[i].start
+		end := tokens[i+len(parts)-1].end
+		matches = append(matches, Match{Text: sentence[start:end], Start: start, End: end})
+		// 重なった出現を避けるため、一致した語の分だけ進める
+		i += len(parts) - 1
+	}
+	return matches
+}
+
+// tokenize は英文を単語に分割し、それぞれの位置 (バイトオフセット) とともに返します。
+// 英字・数字を単語の構成文字とし、単語の内部にあるアポストロフィとハイフンは単語の一部として扱います。
+// 末尾の所有格 "'s" は単語に含めません。
+func tokenize(sentence string) []token {
+	var tokens []token
+	runes := []rune(sentence)
+	// rune インデックスからバイトオフセットへの変換表
+	offsets := make([]int, len(runes)+1)
+	pos := 0
+	for i, r := range runes {
+		offsets[i] = pos
+		pos += len(string(r))
+	}
+	offsets[len(runes)] = pos
+
+	isWordRune := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	isJoiner := func(r rune) bool {
+		return r == '\'' || r == '’' || r == '-'
+	}
+
+	i := 0
+	for i < len(runes) {
+		if !isWordRune(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) {
+			if isWordRune(runes[i]) {
+				i++
+				continue
+			}
+			// 単語の内部にあるアポストロフィやハイフン (例: don't, well-known)
+			if isJoiner(runes[i]) && i+1 < len(runes) && isWordRune(runes[i+1]) {
+				i++
+				continue
+			}
+			break
+		}
+		end := i
+		// 所有格 "'s" は単語から外す
+		if end-start > 2 && (runes[end-2] == '\'' || runes[end-2] == '’') && unicode.ToLower(runes[end-1]) == 's' {
+			end -= 2
+		}
+		tokens = append(tokens, token{
+			text:  string(runes[start:end]),
+			start: offsets[start],
+			end:   offsets[end],
+		})
+	}
+	return tokens
+}
+
+// normalize は単語の前後の空白を取り除き、小文字化し、
+// タイポグラフィ用のアポストロフィを ASCII のアポストロフィに揃えます。
+func normalize(word string) string {
+	w := strings.ToLower(strings.TrimSpace(word))
+	return strings.ReplaceAll(w, "’", "'")
+}
+
+// undouble は語末の子音が重複している語幹 (例: "runn", "stopp") から重複を取り除きます。
+// 重複していない場合は入力をそのまま返します。
+func undouble(stem string) string {
+	n := len(stem)
+	if n < 3 || stem[n-1] != stem[n-2] {
+		return stem
+	}
+	if strings.ContainsRune("aeiouyw", rune(stem[n-1])) {
+		return stem
+	}
+	return stem[:n-1]
+}
@@ -0,0 +1,182 @@
+package lemma
+
+import "testing"
+
+func TestLemmatize(t *testing.T) {
+	headwords := map[string]bool{
+		"go": true, "study": true, "run": true, "make": true, "big": true,
+		"happy": true, "child": true, "box": true, "knife": true, "lie": true,
+		"walk": true, "watch": true, "stop": true, "running": true,
+		"bet": true, "corn": true, "corner": true,
+	}
+	isHeadword := func(s string) bool { return headwords[s] }
+
+	testCases := []struct {
+		name     string
+		word     string
+		expected string
+	}{
+		{"Irregular past", "went", "go"},
+		{"Irregular plural", "children", "child"},
+		{"-ies", "studies", "study"},
+		{"-ied", "studied", "study"},
+		{"-es", "boxes", "box"},
+		{"-ches", "watches", "watch"},
+		{"-ves", "knives", "knife"},
+		{"-ed", "walked", "walk"},
+		{"Doubled consonant (ed)", "stopped", "stop"},
+		{"Silent e (ing)", "making", "make"},
+		{"-ying", "lying", "lie"},
+		{"Doubled consonant (er)", "bigger", "big"},
+		{"-iest", "happiest", "happy"},
+		{"Capitalized", "Went", "go"},
+		{"Headword itself preferred", "running", "running"},
+		{"Unknown word", "xyzzy", "xyzzy"},
+		{"Irregular form is not stripped", "better", "better"},
+		{"Headword is not stripped", "corner", "corner"},
+		{"Plural of stripped headword", "corns", "corn"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Lemmatize(tc.word, isHeadword)
+			if got != tc.expected {
+				t.Errorf("Lemmatize(%q) failed: expected %q, got %q", tc.word, tc.expected, got)
+			}
+		})
+	}
+}
+
+// dictionary は TestFind と TestSpans で使う見出し語の一覧です。
+var dictionary = map[string]bool{
+	"like": true, "go": true, "run": true, "child": true, "give up": true, "walk": true, "stop": true,
+	"corn": true, "corner": true, "let": true, "letter": true, "bet": true, "good": true,
+	"new": true, "news": true, "even": true, "evening": true, "see": true, "seed": true, "live": true, "life": true,
+}
+
+func isDictionaryWord(s string) bool { return dictionary[s] }
+
+func TestFind(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sentence string
+		headword string
+		expected []Match
+	}{
+		{"Exact", "I like apples.", "like", []Match{{"like", 2, 6}}},
+		{"Inflected", "She went home early.", "go", []Match{{"went", 4, 8}}},
+		{"Capitalized", "Running is fun.", "run", []Match{{"Running", 0, 7}}},
+		{"Possessive", "The child's toy broke.", "child", []Match{{"child", 4, 9}}},
+		{"Phrase", "He gave up smoking.", "give up", []Match{{"gave up", 3, 10}}},
+		{"Multiple", "Walk or walked?", "walk", []Match{{"Walk", 0, 4}, {"walked", 8, 14}}},
+		{"Not found", "Nothing here.", "apple", nil},
+		{"Multibyte offsets", "“Stop,” she stopped.", "stop", []Match{{"Stop", 3, 7}, {"stopped", 16, 23}}},
+		{"Regular form of another headword", "She lives here.", "live", []Match{{"lives", 4, 9}}},
+		// 語尾を取り除いた形が見出し語でも、語自体が見出し語であれば変化形とはみなさない
+		{"Headword corner is not corn", "Turn at the corner.", "corn", nil},
+		{"Headword letter is not let", "I got a letter.", "let", nil},
+		{"Headword news is not new", "Good news!", "new", nil},
+		{"Headword evening is not even", "See you this evening.", "even", nil},
+		{"Headword seed is not see", "Plant a seed.", "see", nil},
+		// 不規則変化表にある語は規則変化を取り除かない
+		{"Irregular better is not bet", "This is better.", "bet", nil},
+		{"Irregular better is good", "This is better.", "good", []Match{{"better", 8, 14}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Find(tc.sentence, tc.headword, isDictionaryWord)
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Find(%q, %q) failed: expected %v, got %v", tc.sentence, tc.headword, tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("Find(%q, %q)[%d] failed: expected %v, got %v", tc.sentence, tc.headword, i, tc.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSpans(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sentence string
+		headword string
+		expected []Span
+	}{
+		{"ASCII", "She went home.", "go", []Span{{4, 8}}},
+		{"Curly quotes", "“Stop,” she stopped.", "stop", []Span{{1, 5}, {12, 19}}},
+		{"Surrogate pair", "😀 I ran.", "run", []Span{{5, 8}}},
+		{"Not found", "Nothing here.", "apple", []Span{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Spans(tc.sentence, tc.headword, isDictionaryWord)
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Spans(%q, %q) failed: expected %v, got %v", tc.sentence, tc.headword, tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("Spans(%q, %q)[%d] failed: expected %v, got %v", tc.sentence, tc.headword, i, tc.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInflect(t *testing.T) {
+	testCases := []struct {
+		base     string
+		form     Form
+		expected string
+	}{
+		{"take", FormS, "takes"},
+		{"watch", FormS, "watches"},
+		{"study", FormS, "studies"},
+		{"child", FormS, "children"},
+		{"take", FormPast, "took"},
+		{"take", FormParticiple, "taken"},
+		{"come", FormParticiple, "come"},
+		{"stop", FormPast, "stopped"},
+		{"decide", FormPast, "decided"},
+		{"study", FormPast, "studied"},
+		{"visit", FormPast, "visited"},
+		{"make", FormIng, "making"},
+		{"lie", FormIng, "lying"},
+		{"run", FormIng, "running"},
+		{"see", FormIng, "seeing"},
+		{"big", FormComparative, "bigger"},
+		{"happy", FormSuperlative, "happiest"},
+		{"good", FormComparative, "better"},
+		{"give up", FormPast, "gave up"},
+	}
+	for _, tc := range testCases {
+		if got := Inflect(tc.base, tc.form); got != tc.expected {
+			t.Errorf("Inflect(%q, %d) failed: expected %q, got %q", tc.base, tc.form, tc.expected, got)
+		}
+	}
+}
+
+func TestFormOf(t *testing.T) {
+	testCases := []struct {
+		surface  string
+		headword string
+		expected Form
+	}{
+		{"Take", "take", FormBase},
+		{"took", "take", FormPast},
+		{"taken", "take", FormParticiple},
+		{"made", "make", FormPast},
+		{"studies", "study", FormS},
+		{"running", "run", FormIng},
+		{"burned", "burn", FormPast},
+		{"happier", "happy", FormComparative},
+	}
+	for _, tc := range testCases {
+		if got := FormOf(tc.surface, tc.headword); got != tc.expected {
+			t.Errorf("FormOf(%q, %q) failed: expected %d, got %d", tc.surface, tc.headword, tc.expected, got)
+		}
+	}
+}
@@ -2,8 +2,27 @@
 
 package main
 
-import "syscall/js"
+import (
+	"english_app_for_japanese/wasm/objects"
+	"fmt"
+	"syscall/js"
+)
 
+// GetListeningData はJavaScriptから呼び出され、次のリスニング問題のデータを返します。
+// 問題は周回単位で出題され、周回の問題をすべて出題し終えると、次の呼び出しで新しい周回 (再シャッフル) が始まります。
+//
+// 引数:
+//   - args[0]: 絞り込み条件 (オブジェクト型、形式は parseFilter を参照)、level (数値型) または "auto" (文字列型)。
+//     "auto" の場合は、クイズの正答率が約80%になるようにレベルの配分と単語を自動で選びます。
+//   - args[1]: options (オブジェクト型、省略可能) - missedOnly が true の場合、次の周回は
+//     前の周回で MarkListeningMissed により記録した問題のみにします。
+//     seed (0 以上の整数) を指定すると出題順が毎回同じになります。シードを変更すると最初から出題し直します。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{id, en, ee, jp, en2, jp2, level, targets, spans, round}` で解決されます。
+//     round は周回の出題状況 (roundToJS を参照) です。
+//   - 失敗時: エラーメッセージで拒否されます。
 func GetListeningData(this js.Value, args []js.Value) any {
 	// Promiseを返すためのハンドラ
 	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
@@ -16,22 +35,39 @@ func GetListeningData(this js.Value, args []js.Value) any {
 				reject.Invoke(js.ValueOf("Go関数(GetListeningData)エラー: appDataが初期化されていません。CreateObjectを先に呼び出してください。"))
 				return
 			}
-			if len(args) != 1 {
-				reject.Invoke(js.ValueOf("Go関数(GetListeningData)エラー: 引数は1つ必要です"))
+			if len(args) != 1 && len(args) != 2 {
+				reject.Invoke(js.ValueOf("Go関数(GetListeningData)エラー: 引数は1つまたは2つ必要です"))
 				return
 			}
-			if args[0].Type() != js.TypeNumber {
-				reject.Invoke(js.ValueOf("Go関数(GetListeningData)エラー: 引数は数値型である必要があります"))
+			filter, err := parseFilter(args[0], objects.LevelFilter)
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(GetListeningData)エラー: %v", err)))
 				return
 			}
+			missedOnly := false
+			var seed *uint64
+			if len(args) == 2 && !args[1].IsUndefined() && !args[1].IsNull() {
+				if args[1].Type() != js.TypeObject {
+					reject.Invoke(js.ValueOf("Go関数(GetListeningData)エラー: 引数1はオブジェクトである必要があります"))
+					return
+				}
+				if missedOnly, err = getBool(args[1], "missedOnly"); err != nil {
+					reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(GetListeningData)エラー: %v", err)))
+					return
+				}
+				if seed, err = getSeed(args[1], "seed"); err != nil {
+					reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(GetListeningData)エラー: %v", err)))
+					return
+				}
+			}
+			consoleLog.Invoke(js.ValueOf("Go関数(GetListeningData)で使用した絞り込み条件:"), js.ValueOf(fmt.Sprintf("%+v", filter)))
 
-			level := args[0].Int()
-			consoleLog.Invoke(js.ValueOf("Go関数(GetListeningData)で使用したレベル:"), js.ValueOf(level))
-
-			if listeningData.FilteredArray == nil || listeningData.Level != level {
-				listeningData.Init(&appData, level)
+			if listeningData.FilteredArray == nil || !listeningData.Filter.Equal(filter) || !sameSeed(listeningData.Seed, seed) {
+				listeningData.Seed = seed
+				listeningData.Init(&appData, filter)
 			}
 
+			listeningData.Round.MissedOnly = missedOnly
 			listeningData.Next()
 			if listeningData.CurrentData == nil {
 				reject.Invoke(js.ValueOf("Go関数(GetListeningData)エラー: 次の問題の取得に失敗しました。データがない可能性があります。"))
@@ -47,6 +83,12 @@ func GetListeningData(this js.Value, args []js.Value) any {
 				"en2":   listeningData.CurrentData.ExampleEn,
 				"jp2":   listeningData.CurrentData.ExampleJa,
 				"level": listeningData.CurrentData.Level,
+				// 例文中に現れる見出し語の実際の表記 (変化形を含む)
+				"targets": toJSStringArray(listeningData.CurrentData.TargetsInExample(appData.IsHeadword)),
+				// 例文中の見出し語の出現範囲 (JavaScript の文字列インデックス)
+				"spans": toJSSpanArray(listeningData.CurrentData.ExampleSpans(appData.IsHeadword)),
+				// 周回の出題状況 (n/total と完了)
+				"round": roundToJS(&listeningData.Round),
 			}
 
 			resolve.Invoke(result)
@@ -59,3 +101,31 @@ func GetListeningData(this js.Value, args []js.Value) any {
 	return promiseConstructor.New(handler)
 
 }
+
+// MarkListeningMissed はJavaScriptから呼び出され、現在のリスニング問題を聞き取れなかった問題として記録します。
+// GetListeningData の options で missedOnly を指定すると、記録した問題だけが次の周回で出題されます。
+//
+// 引数:
+//   - なし (args は使用されません)
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: 現在の周回で記録された問題の数で解決されます。
+//   - 失敗時: エラーメッセージで拒否されます。
+func MarkListeningMissed(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if listeningData.CurrentData == nil {
+				reject.Invoke(js.ValueOf("Go関数(MarkListeningMissed)エラー: 問題がありません。GetListeningDataを先に呼び出してください。"))
+				return
+			}
+			listeningData.MarkMissed()
+			resolve.Invoke(len(listeningData.Round.Missed()))
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
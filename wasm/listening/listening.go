@@ -1,53 +1,63 @@
 package listening
 
-import "english_app_for_japanese/wasm/objects"
+import (
+	"english_app_for_japanese/wasm/objects"
+	"math/rand/v2"
+)
 
 // Listening はリスニング学習モードのデータと状態を管理する構造体です。
 type Listening struct {
 	appData       *objects.AppData // アプリケーション全体のデータへのポインタ
 	FilteredArray []objects.Datum  // フィルタリングおよびシャッフルされた問題データのスライス
-	index         int              // FilteredArray 内の現在の問題インデックス
-	Level         int              // 現在選択されている問題のレベル (0 は全レベル)
+	Filter        objects.Filter   // 現在選択されている問題の絞り込み条件
 	CurrentData   *objects.Datum   // 現在表示または再生中の問題データへのポインタ
+	Round         objects.Round    // 現在の周回の出題状況
+	Seed          *uint64          // 乱数のシード (nil の場合はグローバルな乱数生成器を使用)
+	rng           *rand.Rand       // Seed から作成した乱数生成器 (Init で作り直す)
 }
 
 // Init は Listening 構造体を初期化します。
-// 指定されたレベルに基づいて、アプリケーションデータから未学習の問題をフィルタリングし、
-// シャッフルして内部の FilteredArray に格納します。
+// 指定された絞り込み条件に基づいて、アプリケーションデータから問題をフィルタリングし、
+// 並び替えて (既定ではシャッフルして) 内部の FilteredArray に格納します。
+// Seed が設定されている場合は、乱数生成器をそのシードで作り直します。
 //
 // 引数:
 //   - appData: アプリケーション全体のデータ (objects.AppData) へのポインタ。
-//   - level: フィルタリングする問題のレベル。0 を指定するとレベルに関係なくフィルタリングします。
-func (l *Listening) Init(appData *objects.AppData, level int) {
+//   - filter: 問題の絞り込み条件。objects.LevelFilter(0) でレベルに関係なく未学習の問題を対象にします。
+func (l *Listening) Init(appData *objects.AppData, filter objects.Filter) {
 	l.appData = appData
-	l.Level = level
-	// LocalStorageに含まれていない（未学習の）データを取得
-	tmp := l.appData.FilterNotInStorage()
-	// level が 0 以外の場合、指定されたレベルでさらにフィルタリング
-	if l.Level != 0 {
-		tmp = objects.FilterByLevel(tmp, l.Level)
+	l.Filter = filter
+	l.rng = nil
+	if l.Seed != nil {
+		l.rng = objects.NewRand(*l.Seed)
 	}
-	// フィルタリングされたデータをシャッフルして格納
-	l.FilteredArray = objects.ShuffleCopy(tmp)
-	// インデックスを初期化
-	l.index = 0
+	// 絞り込み条件に一致するデータを並び替えて格納
+	l.FilteredArray = l.appData.QueryRand(l.Filter, l.rng)
+	// 周回を初期化
+	l.Round = objects.Round{}
 }
 
 // Next は次のリスニング問題に進みます。
-// FilteredArray から現在のインデックスに対応する問題データを CurrentData に設定し、
-// インデックスを次に進めます。配列の末尾に達した場合は、インデックスを 0 に戻してループさせます。
+// 問題は周回 (Round) 単位で出題し、現在の周回の次の問題を CurrentData に設定します。
+// 周回の問題をすべて出題し終えている場合は、新しい周回を開始します。新しい周回の問題は
+// 絞り込み条件で取り出し直して並び替え (既定ではシャッフルし) ますが、Round.MissedOnly が true の場合は
+// 前の周回で間違えた (MarkMissed で記録した) 問題のみにします。
 func (l *Listening) Next() {
-	// FilteredArray が空でないことを確認（Init が呼ばれている前提）
-	if len(l.FilteredArray) == 0 {
+	// 最初の周回は Init で並び替えた順に出題し、以降は取り出し直した問題で新しい周回を始める
+	if !l.Round.Continue(l.rng, l.FilteredArray, func() []objects.Datum {
+		l.FilteredArray = l.appData.QueryRand(l.Filter, l.rng)
+		return l.FilteredArray
+	}) {
 		l.CurrentData = nil // データがない場合は nil を設定
 		return
 	}
-	// 現在のインデックスのデータを CurrentData に設定
-	l.CurrentData = &l.FilteredArray[l.index]
-	// インデックスを次に進める
-	l.index++
-	// インデックスが配列の範囲を超えたら 0 に戻す
-	if l.index >= len(l.FilteredArray) {
-		l.index = 0
+	l.CurrentData = l.Round.Next()
+}
+
+// MarkMissed は現在の問題を聞き取れなかった (間違えた) 問題として記録します。
+// Round.MissedOnly が true の場合、記録した問題が次の周回で再び出題されます。
+func (l *Listening) MarkMissed() {
+	if l.CurrentData != nil {
+		l.Round.MarkMissed(*l.CurrentData)
 	}
 }
@@ -0,0 +1,73 @@
+package listening
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"testing"
+)
+
+// newTestAppData はテスト用の単語データを作成します。
+func newTestAppData() *objects.AppData {
+	appData := &objects.AppData{}
+	for _, d := range []objects.Datum{
+		{ID: 1, Word: "apple", DefinitionJa: "りんご", Level: 1},
+		{ID: 2, Word: "banana", DefinitionJa: "バナナ", Level: 1},
+		{ID: 3, Word: "cherry", DefinitionJa: "さくらんぼ", Level: 1},
+	} {
+		appData.AddData(d)
+	}
+	return appData
+}
+
+func TestListeningRounds(t *testing.T) {
+	var l Listening
+	l.Init(newTestAppData(), objects.Filter{Sort: objects.SortID})
+	l.Round.MissedOnly = true
+
+	// 1周目: 3問を Init の順に1回ずつ出題し、2問目を聞き取れなかった問題として記録する
+	for i := 1; i <= 3; i++ {
+		l.Next()
+		if l.CurrentData == nil || l.CurrentData.ID != i || l.Round.Number != 1 || l.Round.Position() != i {
+			t.Fatalf("question %d: unexpected %v in round %d (%d/%d)", i, l.CurrentData, l.Round.Number, l.Round.Position(), l.Round.Total())
+		}
+		if i == 2 {
+			l.MarkMissed()
+			l.MarkMissed()
+		}
+	}
+	if missed := l.Round.Missed(); len(missed) != 1 || missed[0].ID != 2 {
+		t.Fatalf("expected only question 2 to be missed, got %v", missed)
+	}
+
+	// 2周目: 記録した問題のみ
+	l.Next()
+	if l.Round.Number != 2 || l.Round.Total() != 1 || l.CurrentData.ID != 2 {
+		t.Errorf("expected round 2 with only question 2, got round %d with %d questions (%v)", l.Round.Number, l.Round.Total(), l.CurrentData)
+	}
+
+	// 3周目: 記録した問題がない場合はすべての問題を出題する
+	l.Next()
+	if l.Round.Number != 3 || l.Round.Total() != 3 {
+		t.Errorf("expected round 3 with every question, got round %d with %d questions", l.Round.Number, l.Round.Total())
+	}
+
+	// MissedOnly が false の場合は、記録した問題があってもすべての問題を出題する
+	l.Init(newTestAppData(), objects.Filter{Sort: objects.SortID})
+	for range 3 {
+		l.Next()
+		l.MarkMissed()
+	}
+	l.Next()
+	if l.Round.Number != 2 || l.Round.Total() != 3 {
+		t.Errorf("expected round 2 with every question without MissedOnly, got round %d with %d questions", l.Round.Number, l.Round.Total())
+	}
+}
+
+func TestListeningNoData(t *testing.T) {
+	var l Listening
+	l.Init(&objects.AppData{}, objects.Filter{})
+	l.Next()
+	l.MarkMissed()
+	if l.CurrentData != nil || len(l.Round.Missed()) != 0 {
+		t.Errorf("expected no question without data, got %v", l.CurrentData)
+	}
+}
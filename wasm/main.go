@@ -4,9 +4,11 @@ package main
 
 import (
 	"encoding/json"
+	"english_app_for_japanese/wasm/lemma"
 	"english_app_for_japanese/wasm/listening"
 	"english_app_for_japanese/wasm/objects"
 	"english_app_for_japanese/wasm/quiz"
+	"english_app_for_japanese/wasm/spelling"
 	"english_app_for_japanese/wasm/typing"
 	"fmt"
 	"strings"
@@ -14,12 +16,25 @@ import (
 )
 
 const localStorageKey = "excludedWords"
+const progressStorageKey = "wordProgress"
+const levelHistoryStorageKey = "levelHistory"
+const dailyHistoryStorageKey = "dailyHistory"
+const typingSettingsStorageKey = "typingSettings"
+const typingHistoryStorageKey = "typingHistory"
+const typingGhostsStorageKey = "typingGhosts"
 
 var consoleLog js.Value
 var appData objects.AppData
 var quizData quiz.Quiz
+var clozeData quiz.ClozeQuiz
+var dailyData quiz.DailyChallenge
+var dailyHistory quiz.DailyHistory
 var typingData typing.Typing
+var typingConfig typingSettings
+var typingHistory typing.History
+var typingGhosts typing.Ghosts
 var listeningData listening.Listening
+var spellingData spelling.Spelling
 
 // init はGoプログラムの初期化関数です。
 // JavaScriptの `console.log` 関数への参照を取得し、
@@ -29,8 +44,13 @@ func init() {
 	consoleLog = js.Global().Get("console").Get("log")
 	appData = objects.AppData{}
 	quizData = quiz.Quiz{}
-	typingData = typing.Typing{}
+	clozeData = quiz.ClozeQuiz{}
+	dailyData = quiz.DailyChallenge{}
+	dailyHistory = make(quiz.DailyHistory)
+	typingGhosts = make(typing.Ghosts)
+	typingData = typing.Typing{Ghosts: typingGhosts}
 	listeningData = listening.Listening{}
+	spellingData = spelling.Spelling{}
 }
 
 // InitializeAppData はJavaScriptから呼び出され、アプリケーションの初期化を行います。
@@ -53,6 +73,10 @@ func init() {
 //  3. レスポンスをテキストとして取得します。
 //  4. テキストデータを改行とタブで分割し、各行を Datum オブジェクトに変換して `appData.Data` に追加します。
 //  5. ブラウザの `localStorage` から `localStorageKey` に対応する値を取得し、デコードして `appData.LocalStorage` に設定します。
+//     また、`progressStorageKey` に対応する単語ごとの解答記録を `appData.Progress` に、
+//     `dailyHistoryStorageKey` に対応するデイリーチャレンジの履歴を `dailyHistory` に設定します。
+//     タイピングの履歴とゴースト (`typingHistoryStorageKey`、`typingGhostsStorageKey`) は、
+//     読み込めない場合もエラーにせず、ログを出力して初期状態から始めます。
 //  6. すべての処理が成功した場合、Promiseを `true` で解決 (resolve) します。
 //  7. いずれかのステップでエラーが発生した場合、Promiseをエラーメッセージで拒否 (reject) します。
 func InitializeAppData(this js.Value, args []js.Value) any {
@@ -104,7 +128,8 @@ func InitializeAppData(this js.Value, args []js.Value) any {
 						continue
 					}
 					fields := strings.Split(line, "\t")
-					if len(fields) == 9 {
+					// 10列目 (タグ) は省略可能
+					if len(fields) == 9 || len(fields) == 10 {
 						for j := range fields {
 							fields[j] = strings.TrimSpace(fields[j])
 							// バックスラッシュを削除
@@ -115,14 +140,19 @@ func InitializeAppData(this js.Value, args []js.Value) any {
 							continue
 						}
 						obj := objects.NewDatum(fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6], fields[7], fields[8])
+						if len(fields) == 10 {
+							obj.Tags = objects.ParseTags(fields[9])
+						}
 						appData.AddData(obj)
 						addedCount++
 					} else if len(strings.TrimSpace(line)) > 0 {
-						consoleLog.Invoke(js.ValueOf(fmt.Sprintf("Go関数(InitializeAppData): 不正な行 %d をスキップします (期待されるフィールド数: 9, 実際のフィールド数: %d): %s", i+1, len(fields), line)))
+						consoleLog.Invoke(js.ValueOf(fmt.Sprintf("Go関数(InitializeAppData): 不正な行 %d をスキップします (期待されるフィールド数: 9 または 10, 実際のフィールド数: %d): %s", i+1, len(fields), line)))
 					}
 				}
 				finalCount := len(appData.Data)
 				consoleLog.Invoke(js.ValueOf(fmt.Sprintf("Go関数(InitializeAppData): CSVから %d 件のデータをロードしました。合計データ数: %d (以前: %d)。", addedCount, finalCount, initialCount)))
+				// 類似語グラフはここで一度だけ作成し、以降はデータが変更されるまで再利用する
+				currentSimilarGraph()
 
 				// --- ローカルストレージ取得処理 ---
 				localStorage := global.Get("localStorage")
@@ -139,19 +169,13 @@ func InitializeAppData(this js.Value, args []js.Value) any {
 						return nil // 処理中断
 					}
 
-					// appData.Data に存在するIDを効率的に検索するためのセットを作成
-					validDataIDs := make(map[int]struct{}, len(appData.Data))
-					for _, datum := range appData.Data {
-						validDataIDs[datum.ID] = struct{}{}
-					}
-
 					// 既存のLocalStorageをクリアし、有効なIDのみを追加する
-					appData.LocalStorage = make([]int, 0)
+					appData.ClearStorage()
 					addedCount := 0
 					skippedCount := 0
 					for _, id := range loadedStorage {
-						// appData.Data に ID が存在するか確認
-						if _, exists := validDataIDs[id]; exists {
+						// appData.Data に ID が存在するか確認 (IDの索引を使用)
+						if appData.HasID(id) {
 							// 存在する場合のみ追加 (AddStorageは重複チェックを行う)
 							appData.AddStorage(id)
 							addedCount++
@@ -171,6 +195,23 @@ func InitializeAppData(this js.Value, args []js.Value) any {
 					consoleLog.Invoke(js.ValueOf("Go関数(InitializeAppData): ローカルストレージにデータが見つかりませんでした。"))
 				}
 
+				// --- 解答記録の取得処理 ---
+				if errMsg := loadProgress(); errMsg != "" {
+					reject.Invoke(js.ValueOf(errMsg))
+					return nil // 処理中断
+				}
+				if errMsg := loadDailyHistory(); errMsg != "" {
+					reject.Invoke(js.ValueOf(errMsg))
+					return nil // 処理中断
+				}
+				if errMsg := loadTypingSettings(); errMsg != "" {
+					reject.Invoke(js.ValueOf(errMsg))
+					return nil // 処理中断
+				}
+				// タイピングの履歴とゴーストは読み込めなくても起動を止めない (ログを出力して初期状態から始める)
+				loadTypingHistory()
+				loadTypingGhosts()
+
 				// すべての処理が成功したのでPromiseをtrueで解決
 				resolve.Invoke(js.ValueOf(true))
 				return nil
@@ -193,14 +234,13 @@ func InitializeAppData(this js.Value, args []js.Value) any {
 	return promiseConstructor.New(handler)
 }
 
-// SearchData はJavaScriptから呼び出され、指定されたレベルに基づいてデータを検索し、
-// シャッフルされた結果をJavaScriptのオブジェクト配列として返します。
+// SearchData はJavaScriptから呼び出され、指定された絞り込み条件に基づいてデータを検索し、
+// 並び替えた (既定ではシャッフルした) 結果をJavaScriptのオブジェクト配列として返します。
 //
 // 引数:
-//   - args[0]: level (数値型)
+//   - args[0]: 絞り込み条件 (オブジェクト型、形式は parseFilter を参照) または level (数値型)
 //   - 0: ローカルストレージ（学習済みなど）に含まれるデータを検索
-//   - 1: ローカルストレージに含まれないレベル1のデータを検索
-//   - 2: ローカルストレージに含まれないレベル2のデータを検索
+//   - 1 以上: ローカルストレージに含まれない指定レベルのデータを検索
 //
 // 戻り値:
 //   - JavaScriptのPromiseオブジェクト。成功時には検索結果のオブジェクト配列、
@@ -208,12 +248,11 @@ func InitializeAppData(this js.Value, args []js.Value) any {
 //
 // 処理内容:
 //  1. appDataが初期化されているか確認します。
-//  2. 引数の数と型を検証します。
-//  3. 指定されたlevelに基づいてデータをフィルタリングします。
-//     - level 0: appData.FilterInStorage() を使用します。
-//     - level 1, 2: appData.FilterNotInStorage() と objects.FilterByLevel() を使用します。
-//  4. フィルタリングされた結果を objects.ShuffleCopy() でシャッフルします。
+//  2. 引数の数を検証し、parseFilter() で絞り込み条件に変換します。
+//  3. appData.Query() で絞り込み条件に一致するデータを取得します。
+//  4. 絞り込み条件の並び順 (既定ではシャッフル) に並び替えます。
 //  5. 検索結果の各DatumオブジェクトをJavaScriptで扱いやすい形式 (map[string]interface{}) に変換します。
+//     例文 (en2) 中の見出し語の出現範囲も spans (`{start, end}` の配列) として含めます。
 //  6. 変換されたオブジェクトの配列をPromiseのresolve関数に渡して返します。
 //  7. エラーが発生した場合は、Promiseのreject関数にエラーメッセージを渡します。
 func SearchData(this js.Value, args []js.Value) any {
@@ -230,28 +269,12 @@ func SearchData(this js.Value, args []js.Value) any {
 				reject.Invoke(js.ValueOf("Go関数(SearchData)エラー: 引数は1つ必要です"))
 				return
 			}
-			if args[0].Type() != js.TypeNumber {
-				reject.Invoke(js.ValueOf("Go関数(SearchData)エラー: 引数は数値型である必要があります"))
-				return
-			}
-			level := args[0].Int()
-			var results []objects.Datum
-			switch level {
-			case 0:
-				r := appData.FilterInStorage()
-				results = objects.ShuffleCopy(r)
-			case 1:
-				r := appData.FilterNotInStorage()
-				r = objects.FilterByLevel(r, 1)
-				results = objects.ShuffleCopy(r)
-			case 2:
-				r := appData.FilterNotInStorage()
-				r = objects.FilterByLevel(r, 2)
-				results = objects.ShuffleCopy(r)
-			default:
-				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(SearchData)エラー: 無効なlevel値です: %d", level)))
+			filter, err := parseFilter(args[0], searchLevelFilter)
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(SearchData)エラー: %v", err)))
 				return
 			}
+			results := appData.Query(filter)
 			consoleLog.Invoke(js.ValueOf("Go関数(SearchData)で検索したデータの長さ:"), js.ValueOf(len(results)))
 			// --- JavaScriptのデータに変換 ---
 			jsResult := make([]interface{}, len(results))
@@ -263,6 +286,7 @@ func SearchData(this js.Value, args []js.Value) any {
 					"en2":   v.ExampleEn,
 					"jp2":   v.ExampleJa,
 					"level": v.Level,
+					"spans": toJSSpanArray(v.ExampleSpans(appData.IsHeadword)),
 				}
 				jsResult[i] = obj
 			}
@@ -274,6 +298,16 @@ func SearchData(this js.Value, args []js.Value) any {
 	return promiseConstructor.New(handler)
 }
 
+// SearchWord はJavaScriptから呼び出され、見出し語にキーワードを含むデータを検索します。
+// キーワードが変化形 (例: "went", "studies", "running") の場合も、
+// lemma パッケージで原形の候補を求め、一致する見出し語 (例: "go", "study", "run") を検索結果に含めます。
+//
+// 引数:
+//   - args[0]: keyWord (文字列型) - 検索キーワード。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。成功時には検索結果のオブジェクト配列、
+//     失敗時にはエラーメッセージで解決または拒否されます。
 func SearchWord(this js.Value, args []js.Value) any {
 	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
 		resolve := promiseArgs[0]
@@ -295,8 +329,12 @@ func SearchWord(this js.Value, args []js.Value) any {
 			keyWord := args[0].String()
 			var results []objects.Datum
 
+			// 変化形 (went, studies, running など) から見出し語を引けるように原形を求める
+			// (検索語自体が見出し語の場合は規則変化を取り除かないため、corner で corn は見つからない)
+			base := lemma.Lemmatize(keyWord, appData.IsHeadword)
+
 			for _, v := range appData.Data {
-				if strings.Contains(v.Word, keyWord) {
+				if strings.Contains(v.Word, keyWord) || strings.EqualFold(v.Word, base) {
 					results = append(results, v)
 				}
 			}
@@ -313,6 +351,7 @@ func SearchWord(this js.Value, args []js.Value) any {
 					"en2":   v.ExampleEn,
 					"jp2":   v.ExampleJa,
 					"level": v.Level,
+					"spans": toJSSpanArray(v.ExampleSpans(appData.IsHeadword)),
 				}
 				jsResult[i] = obj
 			}
@@ -344,29 +383,11 @@ func SearchSimilar(this js.Value, args []js.Value) any {
 			}
 			keyWord := args[0].String()
 
-			var ids []int
-			ok := false
-
-			for _, v := range appData.Data {
-				if v.Word == keyWord {
-					ok = true
-					ids = v.SimilarIDs
-					break
-				}
-			}
-
 			var jsResult []interface{}
 
-			if ok {
-				var results []objects.Datum
-				for _, v := range appData.Data {
-					for _, id := range ids {
-						if v.ID == id {
-							results = append(results, v)
-							break
-						}
-					}
-				}
+			if origin := appData.GetByWord(keyWord); origin != nil {
+				// IDの索引を使って類似語を取得
+				results := appData.GetByIDs(origin.SimilarIDs)
 				consoleLog.Invoke(js.ValueOf("Go関数(SearchSimilar)で検索したデータの長さ:"), js.ValueOf(len(results)))
 				// --- JavaScriptのデータに変換 ---
 				for _, v := range results {
@@ -378,6 +399,7 @@ func SearchSimilar(this js.Value, args []js.Value) any {
 						"en2":   v.ExampleEn,
 						"jp2":   v.ExampleJa,
 						"level": v.Level,
+						"spans": toJSSpanArray(v.ExampleSpans(appData.IsHeadword)),
 					}
 					jsResult = append(jsResult, obj)
 				}
@@ -390,6 +412,112 @@ func SearchSimilar(this js.Value, args []js.Value) any {
 	return promiseConstructor.New(handler)
 }
 
+// GetWordsByIDs はJavaScriptから呼び出され、指定されたIDの単語データをIDの指定順に返します。
+// IDの索引を使用するため、単語数の多いデッキでも高速に取得できます。存在しないIDはスキップされます。
+//
+// 引数:
+//   - args[0]: 単語IDの配列 (数値の配列)。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。成功時には単語データのオブジェクト配列、
+//     失敗時にはエラーメッセージで解決または拒否されます。
+func GetWordsByIDs(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if appData.Data == nil {
+				// InitializeAppDataが完了していないか、失敗した可能性
+				reject.Invoke(js.ValueOf("Go関数(GetWordsByIDs)エラー: appDataが初期化されていません。InitializeAppDataが正常に完了したか確認してください。"))
+				return
+			}
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(GetWordsByIDs)エラー: 引数は1つ必要です"))
+				return
+			}
+			if !isJSArray(args[0]) {
+				reject.Invoke(js.ValueOf("Go関数(GetWordsByIDs)エラー: 引数は配列である必要があります"))
+				return
+			}
+			ids := make([]int, args[0].Length())
+			for i := range ids {
+				item := args[0].Index(i)
+				if item.Type() != js.TypeNumber {
+					reject.Invoke(js.ValueOf("Go関数(GetWordsByIDs)エラー: 配列の要素は数値である必要があります"))
+					return
+				}
+				ids[i] = item.Int()
+			}
+			results := appData.GetByIDs(ids)
+
+			consoleLog.Invoke(js.ValueOf("Go関数(GetWordsByIDs)で取得したデータの長さ:"), js.ValueOf(len(results)))
+			// --- JavaScriptのデータに変換 ---
+			jsResult := make([]interface{}, len(results))
+			for i, v := range results {
+				obj := map[string]interface{}{
+					"id":    v.ID,
+					"en":    v.Word,
+					"ee":    v.DefinitionEn,
+					"jp":    v.DefinitionJa,
+					"en2":   v.ExampleEn,
+					"jp2":   v.ExampleJa,
+					"level": v.Level,
+					"spans": toJSSpanArray(v.ExampleSpans(appData.IsHeadword)),
+				}
+				jsResult[i] = obj
+			}
+			resolve.Invoke(jsResult)
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// toJSStringArray は Go の文字列スライスを、js.ValueOf で JavaScript の配列に変換できる
+// []interface{} に変換します。
+func toJSStringArray(values []string) []interface{} {
+	jsArray := make([]interface{}, len(values))
+	for i, v := range values {
+		jsArray[i] = v
+	}
+	return jsArray
+}
+
+// toJSIntArray は Go の int スライスを、js.ValueOf で JavaScript の配列に変換できる
+// []interface{} に変換します。
+func toJSIntArray(values []int) []interface{} {
+	jsArray := make([]interface{}, len(values))
+	for i, v := range values {
+		jsArray[i] = v
+	}
+	return jsArray
+}
+
+// roundToJS は周回の出題状況を、JavaScript の `{number, position, total, done}` オブジェクトに変換します。
+// position は現在の問題が周回の何問目か、done は現在の問題が周回の最後の問題であることを表します。
+func roundToJS(r *objects.Round) map[string]interface{} {
+	return map[string]interface{}{
+		"number":   r.Number,
+		"position": r.Position(),
+		"total":    r.Total(),
+		"done":     r.Done(),
+	}
+}
+
+// toJSSpanArray は見出し語の出現範囲のスライスを、JavaScript の `{start, end}` オブジェクトの配列に
+// 変換できる []interface{} に変換します。
+func toJSSpanArray(spans []lemma.Span) []interface{} {
+	jsArray := make([]interface{}, len(spans))
+	for i, span := range spans {
+		jsArray[i] = map[string]interface{}{
+			"start": span.Start,
+			"end":   span.End,
+		}
+	}
+	return jsArray
+}
+
 // main はWASMモジュールのエントリーポイントです。
 // Goで実装された各種機能をJavaScriptのグローバルスコープに登録し、
 // JavaScript側から呼び出せるようにします。
@@ -408,19 +536,49 @@ func main() {
 	js.Global().Set("SearchData", js.FuncOf(SearchData))
 	js.Global().Set("SearchWord", js.FuncOf(SearchWord))
 	js.Global().Set("SearchSimilar", js.FuncOf(SearchSimilar))
+	js.Global().Set("GetWordsByIDs", js.FuncOf(GetWordsByIDs))
+
+	// 類似語グラフ関連の関数を登録
+	js.Global().Set("SearchSimilarGraph", js.FuncOf(SearchSimilarGraph))
+	js.Global().Set("FindSimilarPath", js.FuncOf(FindSimilarPath))
+	js.Global().Set("GetSimilarClusters", js.FuncOf(GetSimilarClusters))
+	js.Global().Set("CheckSimilarLinks", js.FuncOf(CheckSimilarLinks))
 
 	// クイズ関連の関数を登録
 	js.Global().Set("CreateQuiz", js.FuncOf(CreateQuiz))
 	js.Global().Set("CreateQuizChoices", js.FuncOf(CreateQuizChoices))
+	js.Global().Set("AnswerQuiz", js.FuncOf(AnswerQuiz))
+	js.Global().Set("TimeoutQuiz", js.FuncOf(TimeoutQuiz))
+	js.Global().Set("GetQuizSummary", js.FuncOf(GetQuizSummary))
+	js.Global().Set("GetLevelStats", js.FuncOf(GetLevelStats))
+	js.Global().Set("CreateDailyChallenge", js.FuncOf(CreateDailyChallenge))
+	js.Global().Set("NextDailyQuestion", js.FuncOf(NextDailyQuestion))
+	js.Global().Set("AnswerDailyChallenge", js.FuncOf(AnswerDailyChallenge))
+	js.Global().Set("GetDailyHistory", js.FuncOf(GetDailyHistory))
+	js.Global().Set("CreateClozeQuiz", js.FuncOf(CreateClozeQuiz))
+	js.Global().Set("CheckClozeAnswer", js.FuncOf(CheckClozeAnswer))
 
 	// リスニング関連の関数を登録
 	js.Global().Set("GetListeningData", js.FuncOf(GetListeningData))
+	js.Global().Set("MarkListeningMissed", js.FuncOf(MarkListeningMissed))
+
+	// スペリング関連の関数を登録
+	js.Global().Set("CreateSpelling", js.FuncOf(CreateSpelling))
+	js.Global().Set("CheckSpelling", js.FuncOf(CheckSpelling))
 
 	// タイピング関連の関数を登録
 	js.Global().Set("CreateTyping", js.FuncOf(CreateTyping))
 	js.Global().Set("GetTypingQuestion", js.FuncOf(GetTypingQuestion))
 	js.Global().Set("GetTypingQuestionSlice", js.FuncOf(GetTypingQuestionSlice))
 	js.Global().Set("TypingKeyDown", js.FuncOf(TypingKeyDown))
+	js.Global().Set("GetTypingSessionStats", js.FuncOf(GetTypingSessionStats))
+	js.Global().Set("GetTypingStats", js.FuncOf(GetTypingStats))
+	js.Global().Set("GetTypingGhost", js.FuncOf(GetTypingGhost))
+	js.Global().Set("SetTypingGhost", js.FuncOf(SetTypingGhost))
+	js.Global().Set("GetTypingRecordings", js.FuncOf(GetTypingRecordings))
+	js.Global().Set("GetTypingGuide", js.FuncOf(GetTypingGuide))
+	js.Global().Set("SetTypingRomajiTable", js.FuncOf(SetTypingRomajiTable))
+	js.Global().Set("GetTypingRomajiTable", js.FuncOf(GetTypingRomajiTable))
 
 	// 初期化完了をコンソールに出力
 	consoleLog.Invoke(js.ValueOf("Go WASMが初期化され、関数が登録されました。"))
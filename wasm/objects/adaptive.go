@@ -0,0 +1,136 @@
+package objects
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// 適応的な出題 (SortAdaptive) の設定です。
+const (
+	AdaptiveTarget = 0.8  // 目標とする正答率
+	adaptiveWindow = 20   // レベルごとの正答率の計算に使う直近の解答数
+	adaptivePrior  = 4.0  // 解答の少ないレベル・単語の推定正答率を事前の推定値に近づける強さ (仮想的な解答数)
+	adaptiveWidth  = 0.15 // 推定正答率が目標からどれだけ離れると出題されにくくなるかの幅
+	adaptiveFloor  = 0.05 // 目標から遠い単語にも残す最低限の出題の重み
+)
+
+// AutoFilter は「おまかせ」(レベル指定 "auto") の Filter を作成します。
+// すべてのレベルの除外リストに含まれないデータを対象にし、正答率が AdaptiveTarget 前後になるよう
+// レベルの配分と単語を選んで並べます (SortAdaptive)。
+func AutoFilter() Filter {
+	return Filter{Excluded: ExcludedNot, Sort: SortAdaptive}
+}
+
+// recordLevel はレベルごとの直近の解答結果 (LevelHistory) に解答結果を追加します。
+// 直近 adaptiveWindow 件より古い結果は捨てます。
+func (a *AppData) recordLevel(level int, correct bool) {
+	if a.LevelHistory == nil {
+		a.LevelHistory = make(map[int][]bool)
+	}
+	history := append(a.LevelHistory[level], correct)
+	if len(history) > adaptiveWindow {
+		history = slices.Clone(history[len(history)-adaptiveWindow:])
+	}
+	a.LevelHistory[level] = history
+}
+
+// LevelAccuracy は指定されたレベルの直近の正答率 (0.0〜1.0) と、その計算に使った解答数を返します。
+// 解答がない場合は 0, 0 を返します。
+func (a *AppData) LevelAccuracy(level int) (float64, int) {
+	history := a.LevelHistory[level]
+	if len(history) == 0 {
+		return 0, 0
+	}
+	correct := 0
+	for _, c := range history {
+		if c {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(history)), len(history)
+}
+
+// levelEstimate はレベルの推定正答率を返します。
+// 解答の少ないレベルは目標の正答率に近い値と推定するため、まだ解いていないレベルも出題されます。
+func (a *AppData) levelEstimate(level int) float64 {
+	accuracy, n := a.LevelAccuracy(level)
+	return (accuracy*float64(n) + AdaptiveTarget*adaptivePrior) / (float64(n) + adaptivePrior)
+}
+
+// EstimateCorrect は単語に正解する確率 (0.0〜1.0) を推定します。
+// レベルの推定正答率を事前の推定値とし、その単語自身の解答記録 (Progress) で補正します。
+func (a *AppData) EstimateCorrect(d *Datum) float64 {
+	p := a.Progress[d.ID]
+	return (a.levelEstimate(d.Level)*adaptivePrior + float64(p.Correct)) / (adaptivePrior + float64(p.Correct+p.Incorrect))
+}
+
+// adaptiveWeight は単語の出題の重みを計算します。推定正答率が目標に近いほど大きくなります。
+func (a *AppData) adaptiveWeight(d *Datum) float64 {
+	diff := (a.EstimateCorrect(d) - AdaptiveTarget) / adaptiveWidth
+	return math.Exp(-diff*diff) + adaptiveFloor
+}
+
+// LevelMix は SortAdaptive で出題される各レベルの割合 (合計 1.0) の見込みを返します。
+// 対象の単語の出題の重みをレベルごとに合計したものです。
+//
+// 引数:
+//   - data: 対象の単語データ (例: AutoFilter で絞り込んだ結果)。
+//
+// 戻り値:
+//   - レベルから出題の割合へのマップ。data が空の場合は空のマップ。
+func (a *AppData) LevelMix(data []Datum) map[int]float64 {
+	mix := make(map[int]float64)
+	total := 0.0
+	for i := range data {
+		w := a.adaptiveWeight(&data[i])
+		mix[data[i].Level] += w
+		total += w
+	}
+	for level := range mix {
+		mix[level] /= total
+	}
+	return mix
+}
+
+// NextAdaptive は周回 round の次の問題を、その時点の出題の重みに比例した確率で選んで返します (Round.NextWeighted を参照)。
+// 解答のたびにレベルと単語の推定正答率が変わるため、間違いが続いたレベルは以降の問題で出題されにくくなります。
+// r が nil の場合はグローバルな乱数生成器を使用します。
+func (a *AppData) NextAdaptive(r *rand.Rand, round *Round) *Datum {
+	return round.NextWeighted(r, a.adaptiveWeight)
+}
+
+// adaptiveOrder は単語データを出題の重みに比例した確率で前に来るよう、ランダムに並べ替えます。
+// 並べ替えた時点の重みを使うため、解答に合わせて出題を変える場合は NextAdaptive で1問ずつ選びます。
+// 重み付きの非復元抽出 (各要素に u^(1/w) のキーを付けて降順に並べる方法) を使います。
+// r が nil の場合はグローバルな乱数生成器を使用します。
+func (a *AppData) adaptiveOrder(r *rand.Rand, data []Datum) []Datum {
+	type keyed struct {
+		datum Datum
+		key   float64
+	}
+	items := make([]keyed, len(data))
+	for i := range data {
+		var u float64
+		if r != nil {
+			u = r.Float64()
+		} else {
+			u = rand.Float64()
+		}
+		items[i] = keyed{data[i], math.Pow(u, 1/a.adaptiveWeight(&data[i]))}
+	}
+	slices.SortStableFunc(items, func(x, y keyed) int {
+		switch {
+		case x.key > y.key:
+			return -1
+		case x.key < y.key:
+			return 1
+		}
+		return 0
+	})
+	results := make([]Datum, len(items))
+	for i, item := range items {
+		results[i] = item.datum
+	}
+	return results
+}
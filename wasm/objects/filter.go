@@ -0,0 +1,190 @@
+package objects
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExcludedState は除外リスト (AppData.LocalStorage) に対する絞り込み条件です。
+type ExcludedState string
+
+const (
+	ExcludedNot  ExcludedState = "not"  // 除外リストに含まれないデータのみ (既定値)
+	ExcludedOnly ExcludedState = "only" // 除外リストに含まれるデータのみ
+	ExcludedAll  ExcludedState = "all"  // 除外リストに関係なくすべてのデータ
+)
+
+// SortOrder は絞り込み結果の並び順です。
+type SortOrder string
+
+const (
+	SortShuffle     SortOrder = "shuffle"      // ランダムに並べる (既定値)
+	SortID          SortOrder = "id"           // ID の昇順
+	SortIDDesc      SortOrder = "id_desc"      // ID の降順
+	SortWord        SortOrder = "word"         // 見出し語のアルファベット順
+	SortWordDesc    SortOrder = "word_desc"    // 見出し語のアルファベットの逆順
+	SortLevel       SortOrder = "level"        // レベルの昇順 (同じレベル内は ID 順)
+	SortMastery     SortOrder = "mastery"      // 習熟度 (AppData.Mastery) の低い順 (苦手な単語から)
+	SortMasteryDesc SortOrder = "mastery_desc" // 習熟度 (AppData.Mastery) の高い順
+	SortAdaptive    SortOrder = "adaptive"     // 推定正答率が AdaptiveTarget に近い単語ほど前に来るようランダムに並べる
+)
+
+// Filter は単語データを絞り込む条件をまとめた構造体です。
+// SearchData、クイズ、リスニング、タイピングの各モードで共通して使用します。
+// ゼロ値のフィールドは「条件なし」を意味します (Excluded と Sort はそれぞれ既定値になります)。
+type Filter struct {
+	Levels     []int         // 対象のレベルの集合 (空の場合はすべてのレベル)
+	Excluded   ExcludedState // 除外リストに対する条件 (空の場合は ExcludedNot)
+	Tags       []string      // いずれかのタグを持つデータのみ (空の場合は条件なし)
+	MinID      int           // ID の下限 (0 の場合は条件なし)
+	MaxID      int           // ID の上限 (0 の場合は条件なし)
+	MinLength  int           // 見出し語の文字数の下限 (0 の場合は条件なし)
+	MaxLength  int           // 見出し語の文字数の上限 (0 の場合は条件なし)
+	HasExample *bool         // 英語例文の有無 (nil の場合は条件なし)
+	MinMastery *float64      // 習熟度 (0.0〜1.0、AppData.Mastery) の下限 (nil の場合は条件なし)
+	MaxMastery *float64      // 習熟度 (0.0〜1.0、AppData.Mastery) の上限 (nil の場合は条件なし)
+	Sort       SortOrder     // 並び順 (空の場合は SortShuffle)
+}
+
+// LevelFilter は単一のレベル指定から Filter を作成します。
+// 従来の数値によるレベル指定との互換用で、level が 0 の場合はすべてのレベルを対象にします。
+// 除外リストに含まれるデータは対象外になります。
+//
+// 引数:
+//   - level: 対象のレベル。0 の場合はすべてのレベル。
+//
+// 戻り値:
+//   - 作成された Filter。
+func LevelFilter(level int) Filter {
+	f := Filter{Excluded: ExcludedNot}
+	if level != 0 {
+		f.Levels = []int{level}
+	}
+	return f
+}
+
+// Equal は2つの Filter が同じ条件かどうかを判定します。
+func (f Filter) Equal(other Filter) bool {
+	return reflect.DeepEqual(f, other)
+}
+
+// Match は Datum が Filter の条件 (並び順を除く) をすべて満たすかを判定します。
+//
+// 引数:
+//   - a: 除外リストと習熟度の参照に使用する AppData へのポインタ。
+//   - d: 判定対象の Datum。
+//
+// 戻り値:
+//   - 条件をすべて満たす場合は true。
+func (f Filter) Match(a *AppData, d *Datum) bool {
+	if len(f.Levels) > 0 && !slices.Contains(f.Levels, d.Level) {
+		return false
+	}
+	switch f.Excluded {
+	case ExcludedOnly:
+		if !a.InStorage(d.ID) {
+			return false
+		}
+	case ExcludedAll:
+	default:
+		if a.InStorage(d.ID) {
+			return false
+		}
+	}
+	if len(f.Tags) > 0 && !slices.ContainsFunc(f.Tags, d.HasTag) {
+		return false
+	}
+	if f.MinID != 0 && d.ID < f.MinID {
+		return false
+	}
+	if f.MaxID != 0 && d.ID > f.MaxID {
+		return false
+	}
+	length := utf8.RuneCountInString(d.Word)
+	if f.MinLength != 0 && length < f.MinLength {
+		return false
+	}
+	if f.MaxLength != 0 && length > f.MaxLength {
+		return false
+	}
+	if f.HasExample != nil && (d.ExampleEn != "") != *f.HasExample {
+		return false
+	}
+	if f.MinMastery != nil || f.MaxMastery != nil {
+		mastery := a.Mastery(d.ID)
+		if f.MinMastery != nil && mastery < *f.MinMastery {
+			return false
+		}
+		if f.MaxMastery != nil && mastery > *f.MaxMastery {
+			return false
+		}
+	}
+	return true
+}
+
+// Query は AppData の Data スライスから Filter の条件を満たす Datum を取り出し、
+// 指定された並び順に並べた新しいスライスとして返します。
+// 元の Data スライスは変更されません。
+//
+// 引数:
+//   - f: 絞り込み条件と並び順。
+//
+// 戻り値:
+//   - 条件を満たす Datum のスライス。
+func (a *AppData) Query(f Filter) []Datum {
+	return a.QueryRand(f, nil)
+}
+
+// QueryRand は Query と同じですが、SortShuffle と SortAdaptive の並べ替えに指定された乱数生成器を使います。
+// r が nil の場合はグローバルな乱数生成器を使用します。
+//
+// 引数:
+//   - f: 絞り込み条件と並び順。
+//   - r: 並べ替えに使う乱数生成器 (nil の場合はグローバルな乱数生成器)。
+//
+// 戻り値:
+//   - 条件を満たす Datum のスライス。
+func (a *AppData) QueryRand(f Filter, r *rand.Rand) []Datum {
+	results := make([]Datum, 0)
+	for i := range a.Data {
+		if f.Match(a, &a.Data[i]) {
+			results = append(results, a.Data[i])
+		}
+	}
+
+	switch f.Sort {
+	case SortID:
+		slices.SortStableFunc(results, func(x, y Datum) int { return cmp.Compare(x.ID, y.ID) })
+	case SortIDDesc:
+		slices.SortStableFunc(results, func(x, y Datum) int { return cmp.Compare(y.ID, x.ID) })
+	case SortWord:
+		slices.SortStableFunc(results, func(x, y Datum) int {
+			return cmp.Compare(strings.ToLower(x.Word), strings.ToLower(y.Word))
+		})
+	case SortWordDesc:
+		slices.SortStableFunc(results, func(x, y Datum) int {
+			return cmp.Compare(strings.ToLower(y.Word), strings.ToLower(x.Word))
+		})
+	case SortLevel:
+		slices.SortStableFunc(results, func(x, y Datum) int {
+			return cmp.Or(cmp.Compare(x.Level, y.Level), cmp.Compare(x.ID, y.ID))
+		})
+	case SortMastery:
+		slices.SortStableFunc(results, func(x, y Datum) int {
+			return cmp.Or(cmp.Compare(a.Mastery(x.ID), a.Mastery(y.ID)), cmp.Compare(x.ID, y.ID))
+		})
+	case SortMasteryDesc:
+		slices.SortStableFunc(results, func(x, y Datum) int {
+			return cmp.Or(cmp.Compare(a.Mastery(y.ID), a.Mastery(x.ID)), cmp.Compare(x.ID, y.ID))
+		})
+	case SortAdaptive:
+		results = a.adaptiveOrder(r, results)
+	default:
+		results = ShuffleCopyRand(r, results)
+	}
+	return results
+}
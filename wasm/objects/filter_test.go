@@ -0,0 +1,120 @@
+package objects
+
+import (
+	"slices"
+	"testing"
+)
+
+// idsOf は Datum のスライスから ID のスライスを取り出します。
+func idsOf(data []Datum) []int {
+	ids := make([]int, len(data))
+	for i, d := range data {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+func TestQuery(t *testing.T) {
+	appData := AppData{
+		Data: []Datum{
+			{ID: 1, Word: "apple", Level: 1, ExampleEn: "I ate an apple."},
+			{ID: 2, Word: "banana", Level: 2, Tags: []string{"fruit"}},
+			{ID: 3, Word: "cat", Level: 1, ExampleEn: "The cat sleeps."},
+			{ID: 4, Word: "dog", Level: 3, ExampleEn: "The dog barks."},
+			{ID: 5, Word: "elephant", Level: 2, Tags: []string{"Animal"}},
+		},
+	}
+	appData.AddStorage(3)
+	appData.RecordAnswer(1, true)
+	appData.RecordAnswer(4, true)
+	appData.RecordAnswer(4, false)
+
+	yes := true
+	half := 0.5
+
+	testCases := []struct {
+		name     string
+		filter   Filter
+		expected []int
+	}{
+		{"Default excludes storage", Filter{Sort: SortID}, []int{1, 2, 4, 5}},
+		{"Only excluded", Filter{Excluded: ExcludedOnly, Sort: SortID}, []int{3}},
+		{"All", Filter{Excluded: ExcludedAll, Sort: SortID}, []int{1, 2, 3, 4, 5}},
+		{"Level set", Filter{Levels: []int{2, 3}, Sort: SortID}, []int{2, 4, 5}},
+		{"Level 3 without code change", LevelFilter(3), []int{4}},
+		{"Tags (case-insensitive)", Filter{Tags: []string{"animal", "fruit"}, Sort: SortID}, []int{2, 5}},
+		{"ID range", Filter{MinID: 2, MaxID: 4, Excluded: ExcludedAll, Sort: SortID}, []int{2, 3, 4}},
+		{"Word length", Filter{MinLength: 4, MaxLength: 6, Sort: SortID}, []int{1, 2}},
+		{"Has example", Filter{HasExample: &yes, Sort: SortID}, []int{1, 4}},
+		{"Mastery range", Filter{MinMastery: &half, Sort: SortID}, []int{1, 4}},
+		{"Sort by word desc", Filter{Sort: SortWordDesc}, []int{5, 4, 2, 1}},
+		{"Sort by level", Filter{Sort: SortLevel}, []int{1, 2, 5, 4}},
+		{"Sort by mastery", Filter{Sort: SortMastery}, []int{2, 5, 4, 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := idsOf(appData.Query(tc.filter))
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Query(%+v) failed: expected %v, got %v", tc.filter, tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Fatalf("Query(%+v) failed: expected %v, got %v", tc.filter, tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMasteryFollowsAnswers(t *testing.T) {
+	var appData AppData
+	appData.SetData([]Datum{{ID: 1, Word: "apple"}, {ID: 2, Word: "banana"}, {ID: 3, Word: "cat"}})
+	half := 0.5
+	weak := Filter{MaxMastery: &half, Sort: SortMastery}
+	// 解答記録がない間はすべて習熟度 0 で、ID 順に並ぶ
+	if got := idsOf(appData.Query(weak)); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("expected all words before answering, got %v", got)
+	}
+	appData.RecordAnswer(1, true)
+	appData.RecordAnswer(2, true)
+	appData.RecordAnswer(2, false)
+	appData.RecordAnswer(2, false)
+	// 正解した単語は苦手な単語から外れ、習熟度の低い順に並ぶ
+	if got := idsOf(appData.Query(weak)); !slices.Equal(got, []int{3, 2}) {
+		t.Errorf("expected the answered word to leave the weak set, got %v", got)
+	}
+	if got := idsOf(appData.Query(Filter{Sort: SortMasteryDesc})); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected mastery_desc to follow the answers, got %v", got)
+	}
+}
+
+func TestAdaptive(t *testing.T) {
+	appData := AppData{}
+	for id := 1; id <= 20; id++ {
+		appData.AddData(Datum{ID: id, Word: "word", Level: (id-1)/10 + 1})
+	}
+	// レベル1はすべて正解 (易しすぎる)、レベル2は 8/10 正解 (目標どおり)
+	for id := 1; id <= 10; id++ {
+		appData.RecordAnswer(id, true)
+		appData.RecordAnswer(id+10, id <= 8)
+	}
+
+	if accuracy, n := appData.LevelAccuracy(2); accuracy != 0.8 || n != 10 {
+		t.Errorf("LevelAccuracy(2) failed: expected 0.8 from 10 answers, got %v from %d", accuracy, n)
+	}
+	for range 30 {
+		appData.RecordAnswer(1, true)
+	}
+	if _, n := appData.LevelAccuracy(1); n != adaptiveWindow {
+		t.Errorf("expected the level history to keep the last %d answers, got %d", adaptiveWindow, n)
+	}
+
+	mix := appData.LevelMix(appData.Query(AutoFilter()))
+	if mix[2] <= mix[1] {
+		t.Errorf("expected level 2 (near the target) to be served more often than level 1, got %v", mix)
+	}
+	if got := appData.Query(AutoFilter()); len(got) != 20 {
+		t.Errorf("expected every word to be ordered, got %d", len(got))
+	}
+}
@@ -1,8 +1,11 @@
 package objects
 
 import (
+	"english_app_for_japanese/wasm/lemma"
 	"errors"
+	"iter"
 	"math/rand/v2"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -18,6 +21,7 @@ type Datum struct {
 	Kana         string
 	Level        int
 	SimilarIDs   []int
+	Tags         []string // 任意のタグ (CSV の10列目、カンマ区切り)
 }
 
 // NewDatum は文字列形式のデータから新しい Datum オブジェクトを生成します。
@@ -60,54 +64,227 @@ func NewDatum(idText, word, definitionEn, definitionJa, exampleEn, exampleJa, ka
 	}
 }
 
+// ParseTags はカンマ区切りのタグ文字列をタグのスライスに変換します。
+// 各タグの前後の空白は取り除かれ、空のタグはスキップされます。
+func ParseTags(tagText string) []string {
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(tagText, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// HasTag は Datum が指定されたタグを持つかどうかを判定します。大文字・小文字は区別しません。
+func (d *Datum) HasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindWordInExample は英語例文 (ExampleEn) の中から見出し語 (Word) の出現箇所を、
+// 変化形 (例: go に対する went) も含めて探します。
+//
+// 引数:
+//   - isHeadword: 辞書の見出し語かどうかを判定する関数 (通常は AppData.IsHeadword)。
+//     例文中の語が別の見出し語である場合は、規則変化による一致とみなしません (corner は corn の変化形ではない)。
+//
+// 戻り値:
+//   - 出現箇所のスライス。見つからない場合は nil。
+func (d *Datum) FindWordInExample(isHeadword func(string) bool) []lemma.Match {
+	return lemma.Find(d.ExampleEn, d.Word, isHeadword)
+}
+
+// TargetsInExample は英語例文 (ExampleEn) 中に現れる見出し語の実際の表記
+// (例: "went", "Running") を出現順に返します。見つからない場合は空のスライスを返します。
+// isHeadword は FindWordInExample と同じです。
+func (d *Datum) TargetsInExample(isHeadword func(string) bool) []string {
+	matches := d.FindWordInExample(isHeadword)
+	targets := make([]string, len(matches))
+	for i, m := range matches {
+		targets[i] = m.Text
+	}
+	return targets
+}
+
+// ExampleSpans は英語例文 (ExampleEn) 中の見出し語の出現範囲を、変化形や大文字で始まる形も含めて、
+// JavaScript の文字列インデックス (UTF-16 コード単位) で返します。isHeadword は FindWordInExample と同じです。
+func (d *Datum) ExampleSpans(isHeadword func(string) bool) []lemma.Span {
+	return lemma.Spans(d.ExampleEn, d.Word, isHeadword)
+}
+
 // AppData はアプリケーション全体のデータ（単語データとローカルストレージ情報）を保持します。
 type AppData struct {
-	Data         []Datum // すべての単語データのスライス
-	LocalStorage []int   // ローカルストレージに保存されている（学習済みなどの）単語IDのスライス (重複なし)
+	Data         []Datum              // すべての単語データのスライス
+	LocalStorage map[int]struct{}     // ローカルストレージに保存されている（学習済みなどの）単語IDのセット
+	Progress     map[int]WordProgress // 単語IDごとの解答記録
+	LevelHistory map[int][]bool       // レベルごとの直近の解答結果 (古い順、適応的な出題に使用)
+	indexByID    map[int]int          // 単語IDから Data スライスのインデックスへのマップ
+	indexByWord  map[string]int       // 小文字化した見出し語から Data スライスのインデックスへのマップ
+	generation   uint64               // Data を変更するたびに増える世代番号 (Generation を参照)
 }
 
-// AddData は AppData の Data スライスに新しい Datum を追加します。
+// SetData は Data スライスを置き換え、IDと見出し語の索引を作り直します。
+// Data を置き換える場合は、Data に直接代入せずにこのメソッドを使用します。
+//
+// 引数:
+//   - data: 新しい単語データのスライス。
+func (a *AppData) SetData(data []Datum) {
+	a.Data = data
+	a.Reindex()
+}
+
+// AddData は AppData の Data スライスに新しい Datum を追加し、IDと見出し語の索引を更新します。
+// 同じIDの Datum がすでに存在する場合も追加されますが、索引は後から追加したものを指します。
 //
 // 引数:
 //   - datum: 追加する Datum オブジェクト。
 func (a *AppData) AddData(datum Datum) {
+	if a.indexByID == nil {
+		a.Reindex()
+	}
 	a.Data = append(a.Data, datum)
+	a.addIndex(len(a.Data) - 1)
+	a.generation++
+}
+
+// Reindex はIDと見出し語の索引を Data スライスから作り直し、世代番号を進めます。
+// Data の要素の ID・見出し語・類似語を直接書き換えた場合 (類似語のリンクの修正など) に呼び出します。
+func (a *AppData) Reindex() {
+	a.indexByID = make(map[int]int, len(a.Data))
+	a.indexByWord = make(map[string]int, len(a.Data))
+	for i := range a.Data {
+		a.addIndex(i)
+	}
+	a.generation++
+}
+
+// Generation は Data の世代番号を返します。SetData、AddData、Reindex のたびに増えるため、
+// Data から作成したキャッシュ (類似語グラフなど) が古くなっていないかの判定に使用します。
+func (a *AppData) Generation() uint64 {
+	return a.generation
+}
+
+// addIndex は Data スライスの i 番目の Datum を索引に登録します。
+func (a *AppData) addIndex(i int) {
+	a.indexByID[a.Data[i].ID] = i
+	word := strings.ToLower(a.Data[i].Word)
+	// 同じ見出し語が複数ある場合は最初のものを優先する
+	if _, exists := a.indexByWord[word]; !exists {
+		a.indexByWord[word] = i
+	}
+}
+
+// GetByID は指定されたIDの Datum へのポインタを返します。
+// ポインタは Data スライスの要素を指すため、AddData で Data が再割り当てされた場合や SetData で置き換えた場合は
+// 古い要素を指したままになり、以降の変更が見えなくなります。呼び出しをまたいで単語を保持する場合は
+// ポインタではなくIDを保持し、使うたびに GetByID で引き直してください。
+//
+// 引数:
+//   - id: 取得する単語ID。
+//
+// 戻り値:
+//   - Datum へのポインタ。存在しない場合は nil。
+func (a *AppData) GetByID(id int) *Datum {
+	i, exists := a.indexByID[id]
+	if !exists {
+		return nil
+	}
+	return &a.Data[i]
+}
+
+// HasID は指定されたIDの Datum が存在するかを判定します。
+func (a *AppData) HasID(id int) bool {
+	return a.GetByID(id) != nil
+}
+
+// GetByIDs は指定されたIDの Datum を、IDの指定順に並べた新しいスライスとして返します。
+// 存在しないIDはスキップされます。
+//
+// 引数:
+//   - ids: 取得する単語IDのスライス。
+//
+// 戻り値:
+//   - 見つかった Datum のスライス。
+func (a *AppData) GetByIDs(ids []int) []Datum {
+	results := make([]Datum, 0, len(ids))
+	for _, id := range ids {
+		if d := a.GetByID(id); d != nil {
+			results = append(results, *d)
+		}
+	}
+	return results
 }
 
-// AddStorage は AppData の LocalStorage スライスに新しい単語IDを追加します。
+// GetByWord は見出し語が一致する Datum へのポインタを返します。大文字・小文字は区別しません。
+// GetByID と同じく、ポインタは AddData・SetData の後は古い要素を指すことがあります。
+//
+// 引数:
+//   - word: 見出し語。
+//
+// 戻り値:
+//   - Datum へのポインタ。存在しない場合は nil。
+func (a *AppData) GetByWord(word string) *Datum {
+	i, exists := a.indexByWord[strings.ToLower(strings.TrimSpace(word))]
+	if !exists {
+		return nil
+	}
+	return &a.Data[i]
+}
+
+// IsHeadword は単語が見出し語として登録されているかどうかを判定します。大文字・小文字は区別しません。
+// lemma.Lemmatize や Datum.FindWordInExample の見出し語の判定に使用します。
+func (a *AppData) IsHeadword(word string) bool {
+	return a.GetByWord(word) != nil
+}
+
+// AddStorage は AppData の LocalStorage セットに新しい単語IDを追加します。
 // すでにIDが存在する場合は、何も行いません（重複を防ぐ）。
 //
 // 引数:
 //   - id: 追加する単語ID。
 func (a *AppData) AddStorage(id int) {
-	// 既存のIDをチェック
-	for _, existingID := range a.LocalStorage {
-		if existingID == id {
-			return // すでに存在するので何もしない
-		}
+	if a.LocalStorage == nil {
+		a.LocalStorage = make(map[int]struct{})
 	}
-	// 存在しない場合のみ追加
-	a.LocalStorage = append(a.LocalStorage, id)
+	a.LocalStorage[id] = struct{}{}
 }
 
-// RemoveStorage は AppData の LocalStorage スライスから指定されたIDを削除します。
-// 指定されたIDが存在しない場合、スライスは変更されません。
+// InStorage は指定された単語IDが LocalStorage (除外リスト) に含まれているかを判定します。
+func (a *AppData) InStorage(id int) bool {
+	_, exists := a.LocalStorage[id]
+	return exists
+}
+
+// RemoveStorage は AppData の LocalStorage セットから指定されたIDを削除します。
+// 指定されたIDが存在しない場合、セットは変更されません。
 //
 // 引数:
 //   - idToRemove: 削除する単語ID。
 func (a *AppData) RemoveStorage(idToRemove int) {
-	newStorage := make([]int, 0, len(a.LocalStorage))
-	for _, id := range a.LocalStorage {
-		if id != idToRemove {
-			newStorage = append(newStorage, id)
-		}
-	}
-	a.LocalStorage = newStorage
+	delete(a.LocalStorage, idToRemove)
 }
 
-// ClearStorage は AppData の LocalStorage スライスを空にします。
+// ClearStorage は AppData の LocalStorage セットを空にします。
 func (a *AppData) ClearStorage() {
-	a.LocalStorage = make([]int, 0)
+	a.LocalStorage = make(map[int]struct{})
+}
+
+// StorageIDs は LocalStorage に含まれる単語IDを昇順のスライスとして返します。
+// ブラウザの localStorage に保存する際に使用します。
+func (a *AppData) StorageIDs() []int {
+	ids := make([]int, 0, len(a.LocalStorage))
+	for id := range a.LocalStorage {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
 }
 
 // FilterNotInStorage は AppData の Data スライスから、
@@ -117,13 +294,9 @@ func (a *AppData) ClearStorage() {
 // 戻り値:
 //   - LocalStorage に含まれていない Datum のスライス。
 func (a *AppData) FilterNotInStorage() []Datum {
-	storageSet := make(map[int]bool, len(a.LocalStorage))
-	for _, id := range a.LocalStorage {
-		storageSet[id] = true
-	}
 	results := make([]Datum, 0)
 	for _, obj := range a.Data {
-		if !storageSet[obj.ID] {
+		if !a.InStorage(obj.ID) {
 			results = append(results, obj)
 		}
 	}
@@ -137,22 +310,26 @@ func (a *AppData) FilterNotInStorage() []Datum {
 // 戻り値:
 //   - LocalStorage に含まれている Datum のスライス。
 func (a *AppData) FilterInStorage() []Datum {
-	storageSet := make(map[int]bool, len(a.LocalStorage))
-	for _, id := range a.LocalStorage {
-		storageSet[id] = true
-	}
 	results := make([]Datum, 0)
 	for _, obj := range a.Data {
-		if storageSet[obj.ID] {
+		if a.InStorage(obj.ID) {
 			results = append(results, obj)
 		}
 	}
 	return results
 }
 
+// NewRand は指定されたシードから乱数生成器を作成します。
+// 同じシードから作成した乱数生成器は、同じ順序で同じ乱数を生成します。
+// 問題の順序や選択肢を再現したい場合 (不具合の報告、テスト、共有するチャレンジなど) に使用します。
+func NewRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
 // ShuffleCopy は与えられたスライスのコピーを作成し、そのコピーをシャッフルして返します。
 // 元のスライスは変更されません。ジェネリック関数です。
 // 要素数が1以下の場合は、単にコピーを返します。
+// 乱数にはグローバルな乱数生成器を使用します。再現性が必要な場合は ShuffleCopyRand を使用してください。
 //
 // 引数:
 //   - original: シャッフルしたい元のスライス。
@@ -160,6 +337,19 @@ func (a *AppData) FilterInStorage() []Datum {
 // 戻り値:
 //   - シャッフルされた新しいスライス。
 func ShuffleCopy[T any](original []T) []T {
+	return ShuffleCopyRand(nil, original)
+}
+
+// ShuffleCopyRand は ShuffleCopy と同じですが、指定された乱数生成器でシャッフルします。
+// r が nil の場合はグローバルな乱数生成器を使用します。
+//
+// 引数:
+//   - r: シャッフルに使う乱数生成器 (nil の場合はグローバルな乱数生成器)。
+//   - original: シャッフルしたい元のスライス。
+//
+// 戻り値:
+//   - シャッフルされた新しいスライス。
+func ShuffleCopyRand[T any](r *rand.Rand, original []T) []T {
 	n := len(original)
 	// 要素数が1以下の場合はシャッフルの必要がない（コピーだけ行う）
 	if n <= 1 {
@@ -174,17 +364,61 @@ func ShuffleCopy[T any](original []T) []T {
 
 	// 2. コピーしたスライス (shuffled) をシャッフル
 	// Go 1.22 以降 (math/rand/v2)
-	rand.Shuffle(n, func(i, j int) {
+	swap := func(i, j int) {
 		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
-	})
+	}
+	if r != nil {
+		r.Shuffle(n, swap)
+	} else {
+		rand.Shuffle(n, swap)
+	}
 
 	// 3. シャッフルされた新しいスライスを返す
 	return shuffled
 }
 
+// ShuffledRand は与えられたスライスの要素を、ShuffleCopyRand と同じくランダムな順に1つずつ返すイテレータを返します。
+// 取り出した分だけシャッフルする (部分的な Fisher-Yates シャッフル) ため、大きなスライスの先頭のいくつかだけを
+// 使う場合 (ダミー選択肢の候補など) にスライス全体をコピー・シャッフルせずに済みます。元のスライスは変更されません。
+// r が nil の場合はグローバルな乱数生成器を使用します。
+//
+// 引数:
+//   - r: シャッフルに使う乱数生成器 (nil の場合はグローバルな乱数生成器)。
+//   - original: 要素を取り出す元のスライス。
+//
+// 戻り値:
+//   - ランダムな順に要素を返すイテレータ。
+func ShuffledRand[T any](r *rand.Rand, original []T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		n := len(original)
+		// 入れ替えたインデックスだけを記録する (記録がないインデックスは自分自身を指す)
+		swapped := make(map[int]int)
+		at := func(i int) int {
+			if j, ok := swapped[i]; ok {
+				return j
+			}
+			return i
+		}
+		for i := range n {
+			var j int
+			if r != nil {
+				j = i + r.IntN(n-i)
+			} else {
+				j = i + rand.IntN(n-i)
+			}
+			picked := at(j)
+			swapped[j] = at(i)
+			if !yield(original[picked]) {
+				return
+			}
+		}
+	}
+}
+
 // GetRandomElement は与えられたスライスからランダムな要素を1つ取得して返します。
 // ジェネリック関数です。
 // スライスが空の場合、型に応じたゼロ値とエラーを返します。
+// 乱数にはグローバルな乱数生成器を使用します。再現性が必要な場合は GetRandomElementRand を使用してください。
 //
 // 引数:
 //   - slice: 要素を取得したいスライス。
@@ -193,6 +427,20 @@ func ShuffleCopy[T any](original []T) []T {
 //   - ランダムに選択された要素。
 //   - スライスが空だった場合のエラー。
 func GetRandomElement[T any](slice []T) (T, error) {
+	return GetRandomElementRand(nil, slice)
+}
+
+// GetRandomElementRand は GetRandomElement と同じですが、指定された乱数生成器で要素を選びます。
+// r が nil の場合はグローバルな乱数生成器を使用します。
+//
+// 引数:
+//   - r: 要素を選ぶのに使う乱数生成器 (nil の場合はグローバルな乱数生成器)。
+//   - slice: 要素を取得したいスライス。
+//
+// 戻り値:
+//   - ランダムに選択された要素。
+//   - スライスが空だった場合のエラー。
+func GetRandomElementRand[T any](r *rand.Rand, slice []T) (T, error) {
 	n := len(slice)
 	if n == 0 {
 		var zero T // 型に応じたゼロ値を返すため
@@ -201,7 +449,12 @@ func GetRandomElement[T any](slice []T) (T, error) {
 
 	// 0 から n-1 の範囲でランダムなインデックスを取得
 	// Go 1.22 以降 (math/rand/v2)
-	randomIndex := rand.IntN(n) // 0 <= randomIndex < n
+	var randomIndex int
+	if r != nil {
+		randomIndex = r.IntN(n)
+	} else {
+		randomIndex = rand.IntN(n) // 0 <= randomIndex < n
+	}
 
 	return slice[randomIndex], nil
 }
@@ -0,0 +1,136 @@
+package objects
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetByID(t *testing.T) {
+	var appData AppData
+	appData.SetData([]Datum{{ID: 10, Word: "Apple"}, {ID: 20, Word: "banana"}})
+	appData.AddData(Datum{ID: 30, Word: "cherry"})
+
+	if d := appData.GetByID(20); d == nil || d.Word != "banana" {
+		t.Errorf("GetByID(20) failed: got %v", d)
+	}
+	if d := appData.GetByID(99); d != nil {
+		t.Errorf("GetByID(99) failed: expected nil, got %v", d)
+	}
+	if d := appData.GetByWord("apple"); d == nil || d.ID != 10 {
+		t.Errorf("GetByWord(\"apple\") failed: got %v", d)
+	}
+	got := idsOf(appData.GetByIDs([]int{30, 99, 10}))
+	if len(got) != 2 || got[0] != 30 || got[1] != 10 {
+		t.Errorf("GetByIDs([30 99 10]) failed: expected [30 10], got %v", got)
+	}
+
+	// AddData で Data が再割り当てされても、引き直したポインタは現在の要素を指す
+	for id := 100; id < 200; id++ {
+		appData.AddData(Datum{ID: id, Word: "filler"})
+	}
+	if d := appData.GetByID(20); d != &appData.Data[1] {
+		t.Errorf("expected GetByID(20) to point at the current element after AddData, got %p", d)
+	}
+
+	// データを置き換えると索引は古いデータを指さない
+	generation := appData.Generation()
+	appData.SetData([]Datum{{ID: 40, Word: "dog"}, {ID: 50, Word: "egg"}, {ID: 60, Word: "fig"}})
+	if appData.Generation() == generation {
+		t.Error("expected SetData to advance the generation")
+	}
+	if d := appData.GetByID(10); d != nil {
+		t.Errorf("GetByID(10) after SetData: expected nil, got %v", d)
+	}
+	if d := appData.GetByWord("egg"); d == nil || d.ID != 50 {
+		t.Errorf("GetByWord(\"egg\") after SetData failed: got %v", d)
+	}
+	// 要素を直接書き換えた場合は Reindex で索引を作り直す
+	appData.Data[0] = Datum{ID: 70, Word: "goat"}
+	appData.Reindex()
+	if d := appData.GetByID(70); d == nil || d.Word != "goat" || appData.GetByID(40) != nil {
+		t.Errorf("GetByID after Reindex failed: got %v", d)
+	}
+}
+
+func TestShuffledRand(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	got := slices.Collect(ShuffledRand(NewRand(1), items))
+	slices.Sort(got)
+	if !slices.Equal(got, items) {
+		t.Errorf("expected every item exactly once, got %v", got)
+	}
+	// 同じシードでは同じ順序になり、途中で止めた場合は取り出した分だけ返す
+	var first []int
+	for v := range ShuffledRand(NewRand(1), items) {
+		if len(first) == 3 {
+			break
+		}
+		first = append(first, v)
+	}
+	if all := slices.Collect(ShuffledRand(NewRand(1), items)); !slices.Equal(first, all[:3]) {
+		t.Errorf("expected the same order with the same seed, got %v and %v", first, all)
+	}
+	if !slices.Equal(items, []int{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("expected the original slice to be unchanged, got %v", items)
+	}
+}
+
+func TestRound(t *testing.T) {
+	items := []Datum{{ID: 1}, {ID: 2}, {ID: 3}}
+	var r Round
+	if !r.Done() {
+		t.Fatal("expected a round that has not started to be done")
+	}
+	r.Start(items)
+	for i := range items {
+		d := r.Next()
+		if d == nil || d.ID != items[i].ID || r.Position() != i+1 || r.Total() != 3 {
+			t.Fatalf("unexpected item %v at %d/%d", d, r.Position(), r.Total())
+		}
+	}
+	if !r.Done() || r.Next() != nil {
+		t.Fatal("expected the round to be done after all items")
+	}
+
+	all := func() []Datum { return items }
+	r.MarkMissed(items[2])
+	r.MarkMissed(items[2])
+	if got := r.NextItems(nil, all); len(got) != 3 {
+		t.Errorf("expected all items without MissedOnly, got %v", got)
+	}
+	r.MissedOnly = true
+	if got := r.NextItems(nil, all); len(got) != 1 || got[0].ID != 3 {
+		t.Errorf("expected only the missed item, got %v", got)
+	}
+	r.Start(r.NextItems(nil, all))
+	if r.Number != 2 || len(r.Missed()) != 0 {
+		t.Errorf("expected round 2 without missed items, got number %d, missed %v", r.Number, r.Missed())
+	}
+	// 間違えた問題がない場合はすべての問題を出題する
+	if got := r.NextItems(nil, all); len(got) != 3 {
+		t.Errorf("expected all items when nothing was missed, got %v", got)
+	}
+
+	// Continue は最初の周回を first の順で始め、周回が完了するまでは新しい周回を始めない
+	var c Round
+	if c.Continue(nil, nil, all) {
+		t.Error("expected no round without first items")
+	}
+	first := []Datum{{ID: 3}, {ID: 1}}
+	if !c.Continue(nil, first, all) || c.Number != 1 || c.Next().ID != 3 {
+		t.Fatalf("expected round 1 in the first order, got round %d", c.Number)
+	}
+	if !c.Continue(nil, first, all) || c.Number != 1 || c.Next().ID != 1 {
+		t.Fatalf("expected round 1 to continue, got round %d", c.Number)
+	}
+	if !c.Continue(nil, first, all) || c.Number != 2 || c.Total() != 3 {
+		t.Errorf("expected round 2 with all items, got round %d with %d", c.Number, c.Total())
+	}
+	empty := func() []Datum { return nil }
+	for !c.Done() {
+		c.Next()
+	}
+	if c.Continue(nil, first, empty) {
+		t.Error("expected no new round when nothing is left")
+	}
+}
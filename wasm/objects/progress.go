@@ -0,0 +1,47 @@
+package objects
+
+// WordProgress は単語ごとの解答記録を保持する構造体です。
+type WordProgress struct {
+	Correct   int `json:"correct"`   // 正解した回数
+	Incorrect int `json:"incorrect"` // 不正解だった回数
+}
+
+// Mastery は解答記録から習熟度 (0.0〜1.0) を計算します。
+// 正解率をそのまま習熟度とし、一度も解答していない場合は 0 を返します。
+func (p WordProgress) Mastery() float64 {
+	total := p.Correct + p.Incorrect
+	if total == 0 {
+		return 0
+	}
+	return float64(p.Correct) / float64(total)
+}
+
+// RecordAnswer は指定された単語IDの解答結果を Progress に記録します。
+// 単語のレベルの直近の解答結果 (LevelHistory) にも記録します。
+//
+// 引数:
+//   - id: 解答した単語のID。
+//   - correct: 正解した場合は true。
+func (a *AppData) RecordAnswer(id int, correct bool) {
+	if a.Progress == nil {
+		a.Progress = make(map[int]WordProgress)
+	}
+	p := a.Progress[id]
+	if correct {
+		p.Correct++
+	} else {
+		p.Incorrect++
+	}
+	a.Progress[id] = p
+	if d := a.GetByID(id); d != nil {
+		a.recordLevel(d.Level, correct)
+	}
+}
+
+// Mastery は指定された単語IDの習熟度 (0.0〜1.0) を返します。
+// 解答記録がない場合は 0 を返します。
+// 習熟度はクイズやスペリングで RecordAnswer により記録した解答記録から計算するため、
+// Filter の MinMastery・MaxMastery と SortMastery・SortMasteryDesc は解答するたびに結果が変わります。
+func (a *AppData) Mastery(id int) float64 {
+	return a.Progress[id].Mastery()
+}
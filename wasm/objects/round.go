@@ -0,0 +1,146 @@
+package objects
+
+import "math/rand/v2"
+
+// Round は問題を周回 (ラウンド) 単位で出題するための状態です。
+// 1つの周回では各問題を1回ずつ出題し、すべて出題し終えると周回の完了 (Done) になります。
+// 周回中に間違えた問題は MarkMissed で記録し、次の周回を間違えた問題だけにすることができます。
+type Round struct {
+	Number     int          // 現在の周回の番号 (1 始まり、開始前は 0)
+	Items      []Datum      // 現在の周回の出題順に並んだ問題
+	MissedOnly bool         // true の場合、次の周回は前の周回で間違えた問題のみにする
+	position   int          // 現在の周回で出題済みの問題数
+	missed     []Datum      // 現在の周回で間違えた問題 (重複なし、間違えた順)
+	missedIDs  map[int]bool // missed に含まれる単語IDのセット
+}
+
+// Start は新しい周回を開始します。周回の番号を1つ進め、出題済みの数と間違えた問題の記録を初期化します。
+//
+// 引数:
+//   - items: 新しい周回で出題する問題 (出題順に並べたもの)。
+func (r *Round) Start(items []Datum) {
+	r.Number++
+	r.Items = items
+	r.position = 0
+	r.missed = nil
+	r.missedIDs = nil
+}
+
+// Next は現在の周回の次の問題を返します。周回の問題をすべて出題し終えた場合は nil を返します。
+func (r *Round) Next() *Datum {
+	if r.Done() {
+		return nil
+	}
+	d := &r.Items[r.position]
+	r.position++
+	return d
+}
+
+// NextWeighted は Next と同じですが、現在の周回でまだ出題していない問題から、weight に比例した確率で1つ選んで返します。
+// 選んだ問題は出題順の現在の位置に移します。重みは呼び出すたびに計算し直すため、直前までの解答結果が次の問題の選択に反映されます。
+//
+// 引数:
+//   - rng: 選択に使う乱数生成器 (nil の場合はグローバルな乱数生成器)。
+//   - weight: 問題の出題の重み (0 以上)。
+func (r *Round) NextWeighted(rng *rand.Rand, weight func(*Datum) float64) *Datum {
+	if r.Done() {
+		return nil
+	}
+	rest := r.Items[r.position:]
+	weights := make([]float64, len(rest))
+	total := 0.0
+	for i := range rest {
+		weights[i] = weight(&rest[i])
+		total += weights[i]
+	}
+	var u float64
+	if rng != nil {
+		u = rng.Float64()
+	} else {
+		u = rand.Float64()
+	}
+	u *= total
+	chosen := len(rest) - 1
+	for i, w := range weights {
+		if u < w {
+			chosen = i
+			break
+		}
+		u -= w
+	}
+	rest[0], rest[chosen] = rest[chosen], rest[0]
+	return r.Next()
+}
+
+// Done は現在の周回の問題をすべて出題し終えたかどうかを返します。開始前の場合も true を返します。
+func (r *Round) Done() bool {
+	return r.position >= len(r.Items)
+}
+
+// Position は現在の周回で出題済みの問題数 (現在の問題が何問目か) を返します。
+func (r *Round) Position() int {
+	return r.position
+}
+
+// Total は現在の周回の問題数を返します。
+func (r *Round) Total() int {
+	return len(r.Items)
+}
+
+// MarkMissed は現在の周回で間違えた問題を記録します。同じ問題を複数回記録しても1つとして扱います。
+func (r *Round) MarkMissed(d Datum) {
+	if r.missedIDs == nil {
+		r.missedIDs = make(map[int]bool)
+	}
+	if r.missedIDs[d.ID] {
+		return
+	}
+	r.missedIDs[d.ID] = true
+	r.missed = append(r.missed, d)
+}
+
+// Missed は現在の周回で間違えた問題を、間違えた順に返します。
+func (r *Round) Missed() []Datum {
+	return r.missed
+}
+
+// Continue は現在の周回が完了している場合に新しい周回を開始し、現在の周回に出題できる問題が残っているかどうかを返します。
+// 最初の周回は first の順に出題し、2周目以降は NextItems で決めた問題を出題します。
+// first が空の場合や、NextItems で出題できる問題がない場合は false を返します。
+//
+// 引数:
+//   - rng: 2周目以降のシャッフルに使う乱数生成器 (nil の場合はグローバルな乱数生成器)。
+//   - first: 最初の周回で出題する問題 (例: Init で並び替えた FilteredArray)。
+//   - all: すべての問題を並び替えて返す関数 (NextItems を参照)。
+func (r *Round) Continue(rng *rand.Rand, first []Datum, all func() []Datum) bool {
+	if !r.Done() {
+		return true
+	}
+	if r.Number == 0 {
+		if len(first) == 0 {
+			return false
+		}
+		r.Start(first)
+		return true
+	}
+	items := r.NextItems(rng, all)
+	if len(items) == 0 {
+		return false
+	}
+	r.Start(items)
+	return true
+}
+
+// NextItems は次の周回で出題する問題を決めます。
+// MissedOnly が true で、現在の周回で間違えた問題がある場合は、それらを rng でシャッフルしたものを返します。
+// それ以外の場合は all を呼び出して、すべての問題を並び替えた (既定ではシャッフルした) ものを返します。
+//
+// 引数:
+//   - rng: シャッフルに使う乱数生成器 (nil の場合はグローバルな乱数生成器)。
+//   - all: すべての問題を並び替えて返す関数 (例: AppData.Query の呼び出し)。
+func (r *Round) NextItems(rng *rand.Rand, all func() []Datum) []Datum {
+	if r.MissedOnly && len(r.missed) > 0 {
+		return ShuffleCopyRand(rng, r.missed)
+	}
+	return all()
+}
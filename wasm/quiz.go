@@ -3,7 +3,11 @@
 package main
 
 import (
+	"english_app_for_japanese/wasm/objects"
+	"english_app_for_japanese/wasm/quiz"
+	"fmt"
 	"syscall/js"
+	"time"
 )
 
 // CreateQuiz はJavaScriptから呼び出され、指定されたレベルと選択肢の数に基づいて
@@ -12,24 +16,34 @@ import (
 // 内部で quizData の初期化または更新、次の問題への遷移、選択肢の生成を行います。
 //
 // 引数:
-//   - args[0]: level (数値型) - クイズの難易度レベル。
+//   - args[0]: 絞り込み条件 (オブジェクト型、形式は parseFilter を参照)、level (数値型) または "auto" (文字列型)。
 //   - 0: レベル指定なし（ローカルストレージに含まれない全データから出題）
-//   - 1: レベル1のデータ（ローカルストレージに含まれないもの）から出題
-//   - 2: レベル2のデータ（ローカルストレージに含まれないもの）から出題
+//   - 1 以上: 指定レベルのデータ（ローカルストレージに含まれないもの）から出題
+//   - "auto": 正答率が約80%になるよう、レベルの配分と単語を自動で選んで出題
 //   - args[1]: choiceCount (数値型) - 生成する選択肢の数（正解を含む）。
+//   - args[2]: options (オブジェクト型、省略可能) - 出題のオプション。形式は parseQuizOptions を参照。
 //
 // 戻り値:
 //   - JavaScriptのPromiseオブジェクト。
-//   - 成功時: 正解データの情報を含むJavaScriptオブジェクト (`{id, en, jp, en2, jp2}`) で解決されます。
+//   - 成功時: 正解データの情報を含むJavaScriptオブジェクト (`{id, en, jp, en2, jp2, targets, spans, difficulty, direction, prompt, round, timeLimit, remaining}`) で解決されます。
+//     targets は例文 (en2) 中に現れる見出し語の実際の表記 (例: "went") の配列、
+//     spans はその出現範囲 (`{start, end}`、JavaScript の文字列インデックス) の配列、
+//     difficulty は選択肢の紛らわしさから推定した問題の難易度 (0.0〜1.0)、
+//     direction はこの問題の出題方向、prompt は出題方向に応じて問題文として表示するテキスト、
+//     round は周回の出題状況 (`{number, position, total, done}`、roundToJS を参照) です。
+//     done が true の場合、この問題で周回が完了し、次の呼び出しで新しい周回 (再シャッフル) が始まります。
+//     timeLimit は1問あたりの制限時間、remaining はスプリントの残り時間 (いずれもミリ秒、設定がない場合は null) です。
+//     スプリントの持ち時間を使い切った場合は null で解決されます。
 //   - 失敗時: エラーメッセージで拒否されます。
 //
 // 処理内容:
 //  1. appDataが初期化されているか確認します。
 //  2. 引数の数と型を検証します。
-//  3. 指定されたlevelとchoiceCountを取得します。
-//  4. quizDataが未初期化、または指定されたlevelが前回と異なる場合、quizDataを初期化します。
-//     (appDataから指定レベルの未学習データをフィルタリングし、シャッフルします)
+//  3. 指定された絞り込み条件とchoiceCountを取得します。
+//  4. quizDataが未初期化、または指定された絞り込み条件かシードが前回と異なる場合、quizDataを初期化します。
+//     (appDataから絞り込み条件に一致するデータを取り出し、シャッフルします)
 //  5. quizData.Next()を呼び出し、次の問題（正解データ）を設定し、内部で選択肢も生成します。
+//     前の問題が解答されないまま時間切れになっていた場合は、不正解として記録した解答記録を localStorage に保存します。
 //  6. 正解データが正常に取得できたか確認します。
 //  7. 正解データをJavaScriptで扱いやすい形式 (map[string]interface{}) に変換します。
 //  8. 変換された正解データをPromiseのresolve関数に渡して返します。
@@ -43,27 +57,55 @@ func CreateQuiz(this js.Value, args []js.Value) any {
 				reject.Invoke(js.ValueOf("Go関数(CreateQuiz)エラー: appDataが初期化されていません。CreateObjectを先に呼び出してください。"))
 				return
 			}
-			if len(args) != 2 {
-				reject.Invoke(js.ValueOf("Go関数(CreateQuiz)エラー: 引数は2つ必要です"))
+			if len(args) != 2 && len(args) != 3 {
+				reject.Invoke(js.ValueOf("Go関数(CreateQuiz)エラー: 引数は2つまたは3つ必要です"))
 				return
 			}
-			if args[0].Type() != js.TypeNumber {
-				reject.Invoke(js.ValueOf("Go関数(CreateQuiz)エラー: 引数0は数値である必要があります。"))
+			filter, err := parseFilter(args[0], objects.LevelFilter)
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CreateQuiz)エラー: 引数0が不正です: %v", err)))
 				return
 			}
 			if args[1].Type() != js.TypeNumber {
 				reject.Invoke(js.ValueOf("Go関数(CreateQuiz)エラー: 引数1は数値である必要があります。"))
 				return
 			}
-			level := args[0].Int()
 			choiceCount := args[1].Int()
-			consoleLog.Invoke(js.ValueOf("Go関数(CreateQuiz)で使用したレベル:"), js.ValueOf(level))
-			// もしもquizDataにQuizDataがない、またはレベルが変更されていたら
-			if quizData.FilteredArray == nil || quizData.Level != level {
-				quizData.Init(&appData, level, choiceCount)
+			var options quizOptions
+			if len(args) == 3 {
+				options, err = parseQuizOptions(args[2])
+				if err != nil {
+					reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CreateQuiz)エラー: 引数2が不正です: %v", err)))
+					return
+				}
+			}
+			consoleLog.Invoke(js.ValueOf("Go関数(CreateQuiz)で使用した絞り込み条件:"), js.ValueOf(fmt.Sprintf("%+v", filter)))
+			// もしもquizDataにQuizDataがない、または絞り込み条件かシードが変更されていたら
+			if quizData.FilteredArray == nil || !quizData.Filter.Equal(filter) || !sameSeed(quizData.Seed, options.seed) {
+				quizData.Seed = options.seed
+				quizData.Init(&appData, filter, choiceCount)
+			}
+			// ダミー選択肢の選び方は問題ごとに変更できる
+			quizData.Strategies = options.strategies
+			quizData.Direction = options.direction
+			quizData.Round.MissedOnly = options.missedOnly
+			// 時間制限が変更された場合は、新しい設定で成績を集計し直す
+			if quizData.Timing != options.timing {
+				quizData.Timing = options.timing
+				quizData.ResetSession()
+			}
+			// 次の問題へ(最初の問題含む)。解答されずに時間切れになった前の問題は不正解として記録される
+			if quizData.Next() {
+				if errMsg := saveProgress(); errMsg != "" {
+					reject.Invoke(js.ValueOf(errMsg))
+					return
+				}
+			}
+			if quizData.TimeUp() {
+				// スプリントの持ち時間を使い切った
+				resolve.Invoke(js.Null())
+				return
 			}
-			// 次の問題へ(最初の問題含む)
-			quizData.Next()
 			if quizData.CorrectAnswer == nil {
 				reject.Invoke(js.ValueOf("Go関数(CreateQuiz)エラー: 次の問題の取得に失敗しました。データがない可能性があります。"))
 				return
@@ -75,6 +117,20 @@ func CreateQuiz(this js.Value, args []js.Value) any {
 				"jp":  quizData.CorrectAnswer.DefinitionJa,
 				"en2": quizData.CorrectAnswer.ExampleEn,
 				"jp2": quizData.CorrectAnswer.ExampleJa,
+				// 例文中に現れる見出し語の実際の表記 (変化形を含む)
+				"targets": toJSStringArray(quizData.CorrectAnswer.TargetsInExample(appData.IsHeadword)),
+				// 例文中の見出し語の出現範囲 (JavaScript の文字列インデックス)
+				"spans": toJSSpanArray(quizData.CorrectAnswer.ExampleSpans(appData.IsHeadword)),
+				// 選択肢の紛らわしさから推定した難易度 (0.0〜1.0)
+				"difficulty": quizData.Difficulty,
+				// 出題方向と、出題方向に応じた問題文
+				"direction": string(quizData.CurrentDirection),
+				"prompt":    quizData.PromptText(),
+				// 周回の出題状況 (n/total と完了)
+				"round": roundToJS(&quizData.Round),
+				// 時間制限 (ミリ秒、制限がない場合は null)
+				"timeLimit": durationToJS(quizData.Timing.QuestionLimit),
+				"remaining": remainingToJS(&quizData),
 			}
 			resolve.Invoke(jsResult)
 		}()
@@ -84,8 +140,81 @@ func CreateQuiz(this js.Value, args []js.Value) any {
 	return promiseConstructor.New(handler)
 }
 
+// quizOptions は CreateQuiz の3つ目の引数で指定する出題のオプションです。
+type quizOptions struct {
+	strategies []quiz.Strategy // ダミー選択肢の選び方の優先順 (nil の場合は quiz.DefaultStrategies)
+	direction  quiz.Direction  // 出題方向 (空の場合は quiz.DirectionEnToJp)
+	missedOnly bool            // 次の周回を前の周回で間違えた問題のみにするかどうか
+	seed       *uint64         // 乱数のシード (nil の場合は毎回異なる出題順)
+	timing     quiz.Timing     // 時間制限の設定 (ゼロ値の場合は時間制限なし)
+}
+
+// parseQuizOptions はJavaScriptから渡された出題のオプションを quizOptions に変換します。
+//
+// JavaScriptのオブジェクトのプロパティ (すべて省略可能):
+//   - distractors: ダミー選択肢の選び方の優先順の配列。
+//     "random", "same_level", "similar", "same_pos", "semantic" のいずれか。
+//   - direction: 出題方向。"en_jp" (英単語→日本語訳、既定値), "jp_en" (日本語訳→英単語),
+//     "def_en" (英語の定義文→英単語), "mixed" (問題ごとにランダム) のいずれか。
+//   - missedOnly: true の場合、次の周回は前の周回で間違えた問題のみにします (間違えた問題がない場合はすべての問題)。
+//   - seed: 乱数のシード (0 以上の整数)。指定すると出題順と選択肢が毎回同じになります。
+//     シードを変更すると、問題を最初から出題し直します。
+//   - timeLimit: 1問あたりの制限時間 (ミリ秒)。制限時間を過ぎてからの解答は時間切れ (不正解) として記録します。
+//   - sprint: スプリントの持ち時間 (ミリ秒)。最初の問題から数えて持ち時間を使い切るまで出題します。
+//     timeLimit または sprint を変更すると、セッションの成績を初期化します。
+//
+// 引数:
+//   - value: JavaScriptのオブジェクト。undefined または null の場合はすべて既定値になります。
+//
+// 戻り値:
+//   - 変換された quizOptions。
+//   - 値の型や内容が不正な場合のエラー。
+func parseQuizOptions(value js.Value) (quizOptions, error) {
+	var options quizOptions
+	if value.IsUndefined() || value.IsNull() {
+		return options, nil
+	}
+	if value.Type() != js.TypeObject {
+		return options, fmt.Errorf("オプションはオブジェクトである必要があります")
+	}
+
+	distractors, err := getStringArray(value, "distractors")
+	if err != nil {
+		return options, err
+	}
+	for _, d := range distractors {
+		if !quiz.IsValidStrategy(d) {
+			return options, fmt.Errorf("distractors の値が不正です: %s", d)
+		}
+		options.strategies = append(options.strategies, quiz.Strategy(d))
+	}
+
+	direction, err := getString(value, "direction")
+	if err != nil {
+		return options, err
+	}
+	if direction != "" && !quiz.IsValidDirection(direction) {
+		return options, fmt.Errorf("direction の値が不正です: %s", direction)
+	}
+	options.direction = quiz.Direction(direction)
+
+	if options.missedOnly, err = getBool(value, "missedOnly"); err != nil {
+		return options, err
+	}
+	if options.seed, err = getSeed(value, "seed"); err != nil {
+		return options, err
+	}
+	if options.timing.QuestionLimit, err = getDuration(value, "timeLimit"); err != nil {
+		return options, err
+	}
+	if options.timing.Budget, err = getDuration(value, "sprint"); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
 // CreateQuizChoices はJavaScriptから呼び出され、現在設定されているクイズ問題に対する
-// 選択肢の配列（IDと日本語訳のみ）を返します。
+// 選択肢の配列（ID、日本語訳、英単語、出題方向に応じた表示テキスト）を返します。
 //
 // この関数は CreateQuiz が呼び出された後に使用されることを想定しています。
 //
@@ -94,12 +223,13 @@ func CreateQuiz(this js.Value, args []js.Value) any {
 //
 // 戻り値:
 //   - JavaScriptのPromiseオブジェクト。
-//   - 成功時: 選択肢の配列（各要素は `{id, jp}` のJavaScriptオブジェクト）で解決されます。
+//   - 成功時: 選択肢の配列（各要素は `{id, jp, en, text}` のJavaScriptオブジェクト）で解決されます。
+//     text は出題方向に応じて選択肢として表示するテキスト (EN→JP では日本語訳、それ以外では英単語) です。
 //   - 失敗時: エラーメッセージで拒否されます。
 //
 // 処理内容:
 //  1. quizData.OptionsArray (CreateQuiz内で生成された選択肢配列) が存在するか確認します。
-//  2. 存在する場合、各選択肢データ (objects.Datum) からIDと日本語訳 (Jp) を抽出し、
+//  2. 存在する場合、各選択肢データ (objects.Datum) からID、日本語訳、英単語、表示テキストを抽出し、
 //     JavaScriptで扱いやすい形式 (map[string]interface{}) の配列に変換します。
 //  3. 変換された選択肢の配列をPromiseのresolve関数に渡して返します。
 //  4. quizData.OptionsArrayが存在しない場合（CreateQuizが未呼び出しなど）、
@@ -118,8 +248,10 @@ func CreateQuizChoices(this js.Value, args []js.Value) any {
 			for i, choice := range quizData.OptionsArray {
 				// 各選択肢を map[string]interface{} (JavaScriptオブジェクトに対応) に変換
 				choiceObj := map[string]interface{}{
-					"id": choice.ID,           // IDは数値のまま渡す
-					"jp": choice.DefinitionJa, // 日本語訳
+					"id":   choice.ID,                    // IDは数値のまま渡す
+					"jp":   choice.DefinitionJa,          // 日本語訳
+					"en":   choice.Word,                  // 英単語
+					"text": quizData.OptionText(&choice), // 出題方向に応じた表示テキスト
 				}
 				jsResult[i] = choiceObj // map を interface{} としてスライスに追加
 			}
@@ -130,3 +262,187 @@ func CreateQuizChoices(this js.Value, args []js.Value) any {
 	promiseConstructor := js.Global().Get("Promise")
 	return promiseConstructor.New(handler)
 }
+
+// AnswerQuiz はJavaScriptから呼び出され、現在のクイズ問題に対する解答を正解 (quizData.CorrectAnswer) と照合します。
+// セッションの正解数・連続正解数・解答時間を更新し、単語の解答記録をブラウザの localStorage に保存します。
+// 1つの問題に解答できるのは1回のみです。
+//
+// 引数:
+//   - args[0]: 選択された選択肢の単語ID (数値型)。CreateQuizChoices の id です。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: 解答の結果 (形式は answerResultToJS を参照) で解決されます。
+//     1問あたりの制限時間を過ぎてからの解答は、選択肢に関係なく時間切れ (timedOut が true) になります。
+//   - 失敗時: エラーメッセージで拒否されます。
+func AnswerQuiz(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(AnswerQuiz)エラー: 引数は1つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeNumber {
+				reject.Invoke(js.ValueOf("Go関数(AnswerQuiz)エラー: 引数は数値型が必要です"))
+				return
+			}
+			result, err := quizData.Answer(args[0].Int())
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(AnswerQuiz)エラー: %v", err)))
+				return
+			}
+			if errMsg := saveProgress(); errMsg != "" {
+				reject.Invoke(js.ValueOf(errMsg))
+				return
+			}
+			resolve.Invoke(answerResultToJS(result))
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// TimeoutQuiz はJavaScriptから呼び出され、現在のクイズ問題を時間切れ (不正解) として記録します。
+// 1問あたりの制限時間を過ぎても解答がない場合に呼び出します。
+// 間違えた問題と同じく単語の解答記録に不正解として記録し、ブラウザの localStorage に保存します。
+//
+// 引数:
+//   - なし (args は使用されません)
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: 解答の結果 (形式は answerResultToJS を参照) で解決されます。
+//   - 失敗時: エラーメッセージで拒否されます。
+func TimeoutQuiz(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			result, err := quizData.Timeout()
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(TimeoutQuiz)エラー: %v", err)))
+				return
+			}
+			if errMsg := saveProgress(); errMsg != "" {
+				reject.Invoke(js.ValueOf(errMsg))
+				return
+			}
+			resolve.Invoke(answerResultToJS(result))
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// answerResultToJS はクイズの解答の結果をJavaScriptのオブジェクト
+// (`{correct, timedOut, answer, elapsed, points, totalPoints, score, answered, streak, bestStreak, roundComplete, remaining, timeUp}`) に変換します。
+// answer は正解の単語 (`{id, en, jp}`)、elapsed は問題の表示 (CreateQuiz) から解答までの時間 (ミリ秒)、
+// points はこの解答の得点 (解答時間で重み付けした値、不正解は 0)、totalPoints はセッションの得点の合計、
+// score と answered はセッションの正解数と解答数、streak と bestStreak は現在と最長の連続正解数、
+// roundComplete はこの解答で周回の問題をすべて解答し終えた場合に true、
+// remaining はスプリントの残り時間 (ミリ秒、スプリントでない場合は null)、timeUp は持ち時間を使い切った場合に true です。
+func answerResultToJS(result quiz.AnswerResult) map[string]interface{} {
+	return map[string]interface{}{
+		"correct":  result.Correct,
+		"timedOut": result.TimedOut,
+		"answer": map[string]interface{}{
+			"id": result.CorrectAnswer.ID,
+			"en": result.CorrectAnswer.Word,
+			"jp": result.CorrectAnswer.DefinitionJa,
+		},
+		"elapsed":       result.Elapsed.Milliseconds(),
+		"points":        result.Points,
+		"totalPoints":   result.TotalPoints,
+		"score":         result.Score,
+		"answered":      result.Answered,
+		"streak":        result.Streak,
+		"bestStreak":    result.BestStreak,
+		"roundComplete": quizData.Round.Done(),
+		"remaining":     remainingToJS(&quizData),
+		"timeUp":        quizData.TimeUp(),
+	}
+}
+
+// durationToJS は時間をミリ秒の数値に変換します。0 以下の場合 (設定なし) は nil を返します。
+func durationToJS(d time.Duration) interface{} {
+	if d <= 0 {
+		return nil
+	}
+	return d.Milliseconds()
+}
+
+// remainingToJS はスプリントの残り時間をミリ秒の数値に変換します。スプリントでない場合は nil を返します。
+func remainingToJS(q *quiz.Quiz) interface{} {
+	if q.Timing.Budget <= 0 {
+		return nil
+	}
+	return q.Remaining().Milliseconds()
+}
+
+// GetQuizSummary はJavaScriptから呼び出され、現在のクイズのセッションの成績と、間違えた単語の一覧を返します。
+// 間違えた単語はすぐに復習できるよう、検索結果と同じ形式で返します。
+//
+// 引数:
+//   - args[0]: reset (真偽値型、省略可能) - true の場合、集計後にセッションの成績を初期化します。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{answered, score, accuracy, bestStreak, points, timeouts, totalTime, averageTime, missed}` で解決されます。
+//     points は解答時間で重み付けした得点の合計、timeouts は時間切れの問題の数、totalTime と averageTime はミリ秒、missed は間違えた単語 (`{id, en, ee, jp, en2, jp2, level, targets, spans}`) の配列です。
+//   - 失敗時: エラーメッセージで拒否されます。
+func GetQuizSummary(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if len(args) > 1 {
+				reject.Invoke(js.ValueOf("Go関数(GetQuizSummary)エラー: 引数は0個または1つです"))
+				return
+			}
+			reset := false
+			if len(args) == 1 && !args[0].IsUndefined() {
+				if args[0].Type() != js.TypeBoolean {
+					reject.Invoke(js.ValueOf("Go関数(GetQuizSummary)エラー: 引数は真偽値型が必要です"))
+					return
+				}
+				reset = args[0].Bool()
+			}
+			summary := quizData.Summary()
+			if reset {
+				quizData.ResetSession()
+			}
+			missed := make([]interface{}, len(summary.Missed))
+			for i, v := range summary.Missed {
+				missed[i] = map[string]interface{}{
+					"id":      v.ID,
+					"en":      v.Word,
+					"ee":      v.DefinitionEn,
+					"jp":      v.DefinitionJa,
+					"en2":     v.ExampleEn,
+					"jp2":     v.ExampleJa,
+					"level":   v.Level,
+					"targets": toJSStringArray(v.TargetsInExample(appData.IsHeadword)),
+					"spans":   toJSSpanArray(v.ExampleSpans(appData.IsHeadword)),
+				}
+			}
+			resolve.Invoke(map[string]interface{}{
+				"answered":    summary.Answered,
+				"score":       summary.Score,
+				"accuracy":    summary.Accuracy,
+				"bestStreak":  summary.BestStreak,
+				"points":      summary.Points,
+				"timeouts":    summary.Timeouts,
+				"totalTime":   summary.TotalTime.Milliseconds(),
+				"averageTime": summary.AverageTime.Milliseconds(),
+				"missed":      missed,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
@@ -0,0 +1,205 @@
+package quiz
+
+import (
+	"english_app_for_japanese/wasm/lemma"
+	"english_app_for_japanese/wasm/objects"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Blank は穴埋め問題で見出し語を隠した部分に表示する文字列です。
+const Blank = "_____"
+
+// ClozeOption は穴埋め問題の選択肢です。
+type ClozeOption struct {
+	ID   int    // 選択肢の単語データのID
+	Text string // 空欄に入れる形に変化させた英単語 (例: "took")
+}
+
+// Cloze は英語例文 (ExampleEn) の見出し語を空欄にした穴埋め問題です。
+type Cloze struct {
+	Datum    *objects.Datum // 正解の単語データ
+	Sentence string         // 見出し語 (変化形を含む) を Blank に置き換えた例文
+	Answer   string         // 最初の空欄に入る例文中の実際の表記 (例: "Took")
+	Form     lemma.Form     // 空欄に入る語の語形
+	Hint     string         // ヒントとして表示する日本語例文 (ExampleJa)
+	Options  []ClozeOption  // 選択肢 (正解を含む、シャッフル済み)
+}
+
+// ClozeResult は穴埋め問題の記述式の解答を採点した結果です。
+type ClozeResult struct {
+	Correct bool   // 空欄に入る語と一致した場合 true (大文字・小文字と前後の空白は区別しない)
+	Close   bool   // 見出し語の別の変化形 (例: took に対する take) を解答した場合 true
+	Answer  string // 正解 (例文中の実際の表記)
+}
+
+// ClozeQuiz は穴埋めクイズモードのデータと状態を管理する構造体です。
+// 問題の並び順やダミー選択肢の選び方は Quiz と共通です。
+type ClozeQuiz struct {
+	Quiz
+	Current *Cloze // 現在の問題 (問題がない場合は nil)
+}
+
+// Init は ClozeQuiz 構造体を初期化します。
+// Quiz.Init と同じく絞り込み条件に一致する問題を並び替えたうえで、
+// 英語例文の中に見出し語 (変化形を含む) が見つからない単語を除きます。
+//
+// 引数:
+//   - appData: アプリケーション全体のデータ (objects.AppData) へのポインタ。
+//   - filter: 問題の絞り込み条件。
+//   - choiceCount: 各問題で生成する選択肢の数（正解を含む）。
+func (c *ClozeQuiz) Init(appData *objects.AppData, filter objects.Filter, choiceCount int) {
+	c.keep = func(d objects.Datum) bool {
+		return len(d.FindWordInExample(appData.IsHeadword)) > 0
+	}
+	c.Quiz.Init(appData, filter, choiceCount)
+	c.Current = nil
+}
+
+// Next は次の穴埋め問題に進み、Current に設定します。問題がない場合は Current を nil にします。
+// 問題は Quiz.Next と同じく周回単位で出題します。
+// 選択肢は正解の語形 (過去形、複数形など) に合わせて変化させ、文頭の場合は大文字で始めます。
+func (c *ClozeQuiz) Next() {
+	c.answered = false
+	c.questionStart = now()
+	c.CorrectAnswer = c.nextInRound()
+	if c.CorrectAnswer == nil {
+		c.OptionsArray = nil
+		c.Current = nil
+		return
+	}
+	// 選択肢は英単語で、日本語訳が同じ単語 (どちらも正解になりうる) は選択肢に使わない
+	c.CurrentDirection = DirectionJpToEn
+	c.Current = newCloze(c.CorrectAnswer, c.appData.IsHeadword)
+	c.createClozeOptions()
+}
+
+// newCloze は単語データから穴埋め問題を作成します。例文中の見出し語はすべて空欄にします。
+// isHeadword は Datum.FindWordInExample と同じです。例文中に見出し語が見つからない場合は nil を返します。
+func newCloze(d *objects.Datum, isHeadword func(string) bool) *Cloze {
+	matches := d.FindWordInExample(isHeadword)
+	if len(matches) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		sb.WriteString(d.ExampleEn[last:m.Start])
+		sb.WriteString(Blank)
+		last = m.End
+	}
+	sb.WriteString(d.ExampleEn[last:])
+
+	return &Cloze{
+		Datum:    d,
+		Sentence: sb.String(),
+		Answer:   matches[0].Text,
+		Form:     lemma.FormOf(matches[0].Text, d.Word),
+		Hint:     d.ExampleJa,
+	}
+}
+
+// createClozeOptions は現在の穴埋め問題の選択肢を生成します。
+// ダミー選択肢は Strategies の優先順に選びますが、文法的に空欄に入りうるよう、
+// まず正解と同じ品詞 (推定) の単語だけを使い、足りない場合にそれ以外の単語で補います。
+// 選択肢のテキストは重複しません。
+func (c *ClozeQuiz) createClozeOptions() {
+	c.OptionsArray = nil
+	c.Difficulty = 0
+	if c.Current == nil {
+		return
+	}
+	correct := c.CorrectAnswer
+	c.OptionsArray = []objects.Datum{*correct}
+	selectedIDs := map[int]bool{correct.ID: true}
+	selectedTexts := map[string]bool{strings.ToLower(c.Current.Answer): true}
+	texts := map[int]string{correct.ID: c.Current.Answer}
+	prompt := c.promptTextOf(correct)
+	pos := partOfSpeech(correct)
+
+	strategies := c.Strategies
+	if len(strategies) == 0 {
+		strategies = DefaultStrategies
+	}
+	if strategies[len(strategies)-1] != StrategyRandom {
+		strategies = append(slices.Clone(strategies), StrategyRandom)
+	}
+
+	for _, samePOS := range []bool{true, false} {
+		for _, strategy := range strategies {
+			for candidate := range c.strategyCandidates(strategy) {
+				if len(c.OptionsArray) >= c.numberOfOptions {
+					break
+				}
+				if selectedIDs[candidate.ID] || c.promptTextOf(&candidate) == prompt {
+					continue
+				}
+				if samePOS && partOfSpeech(&candidate) != pos {
+					continue
+				}
+				text := c.inflectOption(&candidate)
+				if text == "" || selectedTexts[strings.ToLower(text)] {
+					continue
+				}
+				c.OptionsArray = append(c.OptionsArray, candidate)
+				selectedIDs[candidate.ID] = true
+				selectedTexts[strings.ToLower(text)] = true
+				texts[candidate.ID] = text
+			}
+		}
+	}
+
+	c.OptionsArray = objects.ShuffleCopyRand(c.rng, c.OptionsArray)
+	c.Current.Options = make([]ClozeOption, len(c.OptionsArray))
+	for i, d := range c.OptionsArray {
+		c.Current.Options[i] = ClozeOption{ID: d.ID, Text: texts[d.ID]}
+	}
+	c.Difficulty = c.estimateDifficulty()
+}
+
+// inflectOption はダミー選択肢の見出し語を、正解の語形と大文字・小文字に合わせて変化させます。
+func (c *ClozeQuiz) inflectOption(d *objects.Datum) string {
+	text := lemma.Inflect(d.Word, c.Current.Form)
+	if r, _ := utf8.DecodeRuneInString(c.Current.Answer); unicode.IsUpper(r) {
+		first, size := utf8.DecodeRuneInString(text)
+		text = string(unicode.ToUpper(first)) + text[size:]
+	}
+	return text
+}
+
+// Check は記述式の解答を現在の穴埋め問題の正解と比べて採点します。
+// 大文字・小文字と前後の空白、連続する空白は区別しません。
+// 見出し語の別の変化形を解答した場合は Close を true にします。
+// 問題が設定されていない場合は、すべて false の結果を返します。
+//
+// 引数:
+//   - text: ユーザーが入力した解答。
+//
+// 戻り値:
+//   - 採点結果。
+func (c *ClozeQuiz) Check(text string) ClozeResult {
+	if c.Current == nil {
+		return ClozeResult{}
+	}
+	answer := strings.Join(strings.Fields(text), " ")
+	result := ClozeResult{Answer: c.Current.Answer}
+	if strings.EqualFold(answer, c.Current.Answer) {
+		result.Correct = true
+		return result
+	}
+	// 複数語の見出し語は語ごとに原形の一致を確認する
+	words := strings.Fields(answer)
+	headwords := strings.Fields(c.Current.Datum.Word)
+	if len(words) == len(headwords) && len(words) > 0 {
+		result.Close = true
+		for i := range words {
+			if !lemma.Matches(words[i], headwords[i], c.appData.IsHeadword) {
+				result.Close = false
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package quiz
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"hash/fnv"
+	"time"
+)
+
+// デイリーチャレンジの設定です。
+const (
+	DailyCount       = 10               // 既定の問題数
+	DailyDateLayout  = "2006-01-02"     // 日付の書式 (YYYY-MM-DD)
+	dailyPoints      = 100              // 1問正解するごとの得点
+	dailyBonusPoints = 50               // 即答した場合の時間ボーナスの上限
+	dailyBonusTime   = 10 * time.Second // 時間ボーナスがなくなるまでの解答時間
+)
+
+// DailySeed は日付の文字列 (DailyDateLayout の書式) から乱数のシードを作成します。
+// 同じ日付からは常に同じシードになるため、すべての学習者に同じ問題が出題されます。
+func DailySeed(date string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte("daily:" + date))
+	return h.Sum64()
+}
+
+// DailyChallenge はデイリーチャレンジ (日付ごとに全員共通の問題を解くモード) のデータと状態を管理する構造体です。
+// 問題の選び方は Quiz と同じですが、除外リストや出題のオプションに関係なく、
+// 日付から決まるシードでアプリケーションデータ全体から Count 問を選び、1周だけ出題します。
+type DailyChallenge struct {
+	Quiz
+	Date  string // 出題する日付 (DailyDateLayout の書式)
+	Count int    // 問題数
+}
+
+// DailyResult はデイリーチャレンジの1日分の結果です。ローカルの履歴として JSON で保存します。
+type DailyResult struct {
+	Date      string `json:"date"`      // 日付 (DailyDateLayout の書式)
+	Score     int    `json:"score"`     // 正解数と解答時間から計算した得点
+	Correct   int    `json:"correct"`   // 正解数
+	Total     int    `json:"total"`     // 問題数
+	TotalTime int64  `json:"totalTime"` // 解答時間の合計 (ミリ秒)
+	Attempts  int    `json:"attempts"`  // 挑戦した回数 (2回目以降の結果は得点に反映しない)
+}
+
+// DailyHistory は日付からデイリーチャレンジの結果への対応表です。
+type DailyHistory map[string]DailyResult
+
+// Init は DailyChallenge 構造体を指定された日付の問題で初期化します。
+// 出題の設定 (Strategies, Direction, Round.MissedOnly, Timing) は既定値に戻します。
+//
+// 引数:
+//   - appData: アプリケーション全体のデータ (objects.AppData) へのポインタ。
+//   - date: 出題する日付 (DailyDateLayout の書式)。
+//   - count: 問題数。0 以下の場合は DailyCount。
+//   - choiceCount: 各問題で生成する選択肢の数（正解を含む）。
+func (d *DailyChallenge) Init(appData *objects.AppData, date string, count, choiceCount int) {
+	if count <= 0 {
+		count = DailyCount
+	}
+	d.Date = date
+	d.Count = count
+	seed := DailySeed(date)
+	d.Seed = &seed
+	d.Strategies = nil
+	d.Direction = ""
+	d.Timing = Timing{}
+	d.keep = nil
+	// 除外リストに関係なくアプリケーションデータ全体から選ぶ
+	d.Quiz.Init(appData, objects.Filter{Excluded: objects.ExcludedAll, Sort: objects.SortShuffle}, choiceCount)
+	if len(d.FilteredArray) > count {
+		d.FilteredArray = d.FilteredArray[:count]
+	}
+}
+
+// Next は次の問題に進みます。すべての問題を出題し終えた場合は CorrectAnswer を nil にします。
+func (d *DailyChallenge) Next() {
+	if d.Round.Number > 0 && d.Round.Done() {
+		d.answered = false
+		d.CorrectAnswer = nil
+		d.OptionsArray = nil
+		return
+	}
+	d.Quiz.Next()
+}
+
+// Done はすべての問題に解答したかどうかを返します。
+func (d *DailyChallenge) Done() bool {
+	return len(d.FilteredArray) > 0 && len(d.Session.Answers) >= len(d.FilteredArray)
+}
+
+// Result は現在のセッションの解答からデイリーチャレンジの結果を計算します。
+// 正解1問ごとに dailyPoints 点と、解答時間が短いほど多い (dailyBonusTime で 0 になる) 時間ボーナスを加えます。
+func (d *DailyChallenge) Result() DailyResult {
+	result := DailyResult{Date: d.Date, Total: len(d.FilteredArray), Attempts: 1}
+	var total time.Duration
+	for _, a := range d.Session.Answers {
+		total += a.Elapsed
+		if !a.Correct {
+			continue
+		}
+		result.Correct++
+		result.Score += dailyPoints
+		if a.Elapsed < dailyBonusTime {
+			result.Score += int(dailyBonusPoints * (dailyBonusTime - a.Elapsed) / dailyBonusTime)
+		}
+	}
+	result.TotalTime = total.Milliseconds()
+	return result
+}
+
+// Record は結果を履歴に追加します。同じ日付の結果がすでにある場合は、
+// 最初の挑戦の結果を残したまま挑戦した回数のみを増やします (友達との比較を公平にするため)。
+//
+// 戻り値:
+//   - 履歴に残った結果。
+func (h DailyHistory) Record(result DailyResult) DailyResult {
+	if prev, ok := h[result.Date]; ok {
+		prev.Attempts++
+		h[result.Date] = prev
+		return prev
+	}
+	h[result.Date] = result
+	return result
+}
@@ -0,0 +1,89 @@
+package quiz
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"strings"
+)
+
+// Direction はクイズの出題方向 (問題文に何を表示し、選択肢に何を表示するか) です。
+type Direction string
+
+const (
+	DirectionEnToJp  Direction = "en_jp"  // 英単語を見て日本語訳を選ぶ (既定値、認識)
+	DirectionJpToEn  Direction = "jp_en"  // 日本語訳を見て英単語を選ぶ (想起)
+	DirectionDefToEn Direction = "def_en" // 英語の定義文 (DefinitionEn) を見て英単語を選ぶ
+	DirectionMixed   Direction = "mixed"  // 問題ごとに上記のいずれかをランダムに選ぶ
+)
+
+// IsValidDirection は文字列が有効な Direction かどうかを判定します。
+func IsValidDirection(s string) bool {
+	switch Direction(s) {
+	case DirectionEnToJp, DirectionJpToEn, DirectionDefToEn, DirectionMixed:
+		return true
+	}
+	return false
+}
+
+// resolveDirection は設定された出題方向 (Direction) から、現在の問題で使用する出題方向を決めます。
+// DirectionMixed の場合はランダムに選び、英語の定義文がない単語では DirectionDefToEn を選びません。
+// DirectionDefToEn が指定されていても英語の定義文がない場合は DirectionJpToEn にします。
+func (q *Quiz) resolveDirection() Direction {
+	hasDefinition := strings.TrimSpace(q.CorrectAnswer.DefinitionEn) != ""
+	switch q.Direction {
+	case DirectionJpToEn:
+		return DirectionJpToEn
+	case DirectionDefToEn:
+		if hasDefinition {
+			return DirectionDefToEn
+		}
+		return DirectionJpToEn
+	case DirectionMixed:
+		candidates := []Direction{DirectionEnToJp, DirectionJpToEn}
+		if hasDefinition {
+			candidates = append(candidates, DirectionDefToEn)
+		}
+		direction, err := objects.GetRandomElementRand(q.rng, candidates)
+		if err != nil {
+			return DirectionEnToJp
+		}
+		return direction
+	default:
+		return DirectionEnToJp
+	}
+}
+
+// PromptText は現在の問題の問題文として表示するテキストを、出題方向に応じて返します。
+//   - DirectionEnToJp: 英単語 (Word)
+//   - DirectionJpToEn: 日本語訳 (DefinitionJa)
+//   - DirectionDefToEn: 英語の定義文 (DefinitionEn)
+//
+// 問題が設定されていない場合は空文字列を返します。
+func (q *Quiz) PromptText() string {
+	if q.CorrectAnswer == nil {
+		return ""
+	}
+	return q.promptTextOf(q.CorrectAnswer)
+}
+
+// promptTextOf は単語データを問題として出題した場合の問題文を、現在の出題方向に応じて返します。
+func (q *Quiz) promptTextOf(d *objects.Datum) string {
+	switch q.CurrentDirection {
+	case DirectionJpToEn:
+		return strings.TrimSpace(d.DefinitionJa)
+	case DirectionDefToEn:
+		return strings.TrimSpace(d.DefinitionEn)
+	default:
+		return strings.TrimSpace(d.Word)
+	}
+}
+
+// OptionText は選択肢として画面に表示するテキストを、現在の問題の出題方向に応じて返します。
+// DirectionEnToJp では日本語訳 (DefinitionJa)、それ以外では英単語 (Word) です。
+func (q *Quiz) OptionText(d *objects.Datum) string {
+	switch q.CurrentDirection {
+	case DirectionJpToEn, DirectionDefToEn:
+		return strings.TrimSpace(d.Word)
+	default:
+		return strings.TrimSpace(d.DefinitionJa)
+	}
+}
@@ -0,0 +1,253 @@
+package quiz
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"iter"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Strategy は不正解の選択肢 (ダミー選択肢) の選び方です。
+type Strategy string
+
+const (
+	StrategyRandom    Strategy = "random"     // すべての単語からランダムに選ぶ
+	StrategySameLevel Strategy = "same_level" // 正解と同じレベルの単語から選ぶ
+	StrategySimilar   Strategy = "similar"    // 正解の SimilarIDs (紛らわしい単語) から選ぶ
+	StrategySamePOS   Strategy = "same_pos"   // 正解と同じ品詞 (推定) の単語から選ぶ
+	StrategySemantic  Strategy = "semantic"   // 意味 (定義文) が近い単語から選ぶ
+)
+
+// DefaultStrategies は選択肢の選び方が指定されていない場合に使用する既定の優先順です。
+// 紛らわしい単語、意味の近い単語、同じ品詞の単語の順に選び、足りない分は同じレベル、ランダムの順に補います。
+var DefaultStrategies = []Strategy{StrategySimilar, StrategySemantic, StrategySamePOS, StrategySameLevel, StrategyRandom}
+
+// IsValidStrategy は文字列が有効な Strategy かどうかを判定します。
+func IsValidStrategy(s string) bool {
+	switch Strategy(s) {
+	case StrategyRandom, StrategySameLevel, StrategySimilar, StrategySamePOS, StrategySemantic:
+		return true
+	}
+	return false
+}
+
+// 品詞 (推定) を表す定数です。
+const (
+	posNoun      = "noun"
+	posVerb      = "verb"
+	posAdjective = "adjective"
+	posAdverb    = "adverb"
+)
+
+// partOfSpeech は単語データの品詞を推定します。
+// データに品詞の列がないため、英語の定義文 ("to ..." は動詞)、日本語訳の語尾
+// (「する」は動詞、「な」「い」は形容詞、「に」は副詞)、見出し語の接尾辞の順に手がかりにします。
+// 「く」で終わる訳語は副詞 (素早く) と動詞 (書く、歩く) のどちらもあるため手がかりにしません。
+// どれにも当てはまらない場合は名詞とみなします。
+func partOfSpeech(d *objects.Datum) string {
+	definitionEn := strings.ToLower(strings.TrimSpace(d.DefinitionEn))
+	if strings.HasPrefix(definitionEn, "to ") {
+		return posVerb
+	}
+
+	// 日本語訳は「、」などで区切られた最初の訳語で判定する
+	definitionJa := strings.TrimSpace(d.DefinitionJa)
+	if i := strings.IndexAny(definitionJa, "、,;；/／"); i >= 0 {
+		definitionJa = strings.TrimSpace(definitionJa[:i])
+	}
+	switch {
+	case strings.HasSuffix(definitionJa, "する"), strings.HasSuffix(definitionJa, "させる"):
+		return posVerb
+	case strings.HasSuffix(definitionJa, "的に"), strings.HasSuffix(definitionJa, "に"):
+		return posAdverb
+	case strings.HasSuffix(definitionJa, "な"), strings.HasSuffix(definitionJa, "的"), strings.HasSuffix(definitionJa, "しい"):
+		return posAdjective
+	}
+
+	word := strings.ToLower(d.Word)
+	switch {
+	case strings.HasSuffix(word, "ly"):
+		return posAdverb
+	case strings.HasSuffix(word, "ous"), strings.HasSuffix(word, "ful"), strings.HasSuffix(word, "ive"),
+		strings.HasSuffix(word, "able"), strings.HasSuffix(word, "ible"), strings.HasSuffix(word, "al"),
+		strings.HasSuffix(word, "ic"), strings.HasSuffix(word, "less"):
+		return posAdjective
+	case strings.HasSuffix(word, "ize"), strings.HasSuffix(word, "ise"), strings.HasSuffix(word, "ate"),
+		strings.HasSuffix(word, "ify"):
+		return posVerb
+	}
+	return posNoun
+}
+
+// stopWords は意味の近さを計算する際に無視する英単語です。
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "to": true, "of": true, "and": true, "or": true,
+	"in": true, "on": true, "at": true, "for": true, "with": true, "by": true, "from": true,
+	"is": true, "are": true, "be": true, "as": true, "that": true, "which": true, "who": true,
+	"something": true, "someone": true, "one": true, "it": true, "its": true, "not": true,
+}
+
+// features は意味の近さを計算するための単語データの特徴です。
+type features struct {
+	enWords   map[string]bool // 英語の定義文に含まれる内容語 (小文字)
+	jaBigrams map[string]bool // 日本語訳の文字 bigram
+}
+
+// extractFeatures は単語データから意味の近さを計算するための特徴を取り出します。
+func extractFeatures(d *objects.Datum) features {
+	f := features{enWords: make(map[string]bool), jaBigrams: make(map[string]bool)}
+	for _, w := range strings.FieldsFunc(strings.ToLower(d.DefinitionEn), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		if len(w) > 2 && !stopWords[w] {
+			f.enWords[w] = true
+		}
+	}
+	runes := []rune(d.DefinitionJa)
+	for i := 0; i+1 < len(runes); i++ {
+		if unicode.IsPunct(runes[i]) || unicode.IsPunct(runes[i+1]) || unicode.IsSpace(runes[i]) || unicode.IsSpace(runes[i+1]) {
+			continue
+		}
+		f.jaBigrams[string(runes[i:i+2])] = true
+	}
+	return f
+}
+
+// jaccard は2つの集合の Jaccard 係数 (0.0〜1.0) を計算します。
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for k := range a {
+		if b[k] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// semanticScore は2つの単語データの意味の近さ (0.0〜1.0) を、
+// 英語の定義文の内容語と日本語訳の文字 bigram の重なりから計算します。
+func (q *Quiz) semanticScore(a, b *objects.Datum) float64 {
+	fa, fb := q.featuresOf(a), q.featuresOf(b)
+	return max(jaccard(fa.enWords, fb.enWords), jaccard(fa.jaBigrams, fb.jaBigrams))
+}
+
+// featuresOf は単語データの特徴を返します。計算済みの特徴はキャッシュされます。
+// 同じIDの単語が書き換えられることがあるため、appData の世代番号が変わった場合はキャッシュを作り直します。
+func (q *Quiz) featuresOf(d *objects.Datum) features {
+	if q.featureCache == nil || q.featureGen != q.appData.Generation() {
+		q.featureCache = make(map[int]features)
+		q.featureGen = q.appData.Generation()
+	}
+	f, exists := q.featureCache[d.ID]
+	if !exists {
+		f = extractFeatures(d)
+		q.featureCache[d.ID] = f
+	}
+	return f
+}
+
+// strategyCandidates は指定された選び方で、ダミー選択肢の候補を優先度順に返します。
+// 候補には正解自身が含まれることがあるため、呼び出し側で除外します。
+// ランダム・同じレベル・同じ品詞の候補は、呼び出し側が必要な数だけ取り出す間にデータ全体からランダムに選ぶため、
+// 問題ごとにデータ全体をシャッフルしません。
+func (q *Quiz) strategyCandidates(s Strategy) iter.Seq[objects.Datum] {
+	correct := q.CorrectAnswer
+	switch s {
+	case StrategySameLevel:
+		return q.sampleData(func(d *objects.Datum) bool {
+			return d.Level == correct.Level
+		})
+	case StrategySimilar:
+		return slices.Values(objects.ShuffleCopyRand(q.rng, q.appData.GetByIDs(correct.SimilarIDs)))
+	case StrategySamePOS:
+		pos := partOfSpeech(correct)
+		return q.sampleData(func(d *objects.Datum) bool {
+			return partOfSpeech(d) == pos
+		})
+	case StrategySemantic:
+		type scored struct {
+			datum objects.Datum
+			score float64
+		}
+		var candidates []scored
+		for i := range q.appData.Data {
+			d := &q.appData.Data[i]
+			if d.ID == correct.ID {
+				continue
+			}
+			if score := q.semanticScore(correct, d); score > 0 {
+				candidates = append(candidates, scored{*d, score})
+			}
+		}
+		slices.SortStableFunc(candidates, func(a, b scored) int {
+			switch {
+			case a.score > b.score:
+				return -1
+			case a.score < b.score:
+				return 1
+			}
+			return 0
+		})
+		// 毎回同じダミー選択肢にならないよう、上位の候補の中でシャッフルする
+		limit := min(len(candidates), q.numberOfOptions*3)
+		results := make([]objects.Datum, limit)
+		for i := range results {
+			results[i] = candidates[i].datum
+		}
+		return slices.Values(objects.ShuffleCopyRand(q.rng, results))
+	default:
+		return q.sampleData(nil)
+	}
+}
+
+// sampleData はアプリケーションデータ全体からランダムな順に、keep が true を返す単語を返すイテレータを返します。
+// keep が nil の場合はすべての単語を返します。
+func (q *Quiz) sampleData(keep func(*objects.Datum) bool) iter.Seq[objects.Datum] {
+	return func(yield func(objects.Datum) bool) {
+		for d := range objects.ShuffledRand(q.rng, q.appData.Data) {
+			if keep != nil && !keep(&d) {
+				continue
+			}
+			if !yield(d) {
+				return
+			}
+		}
+	}
+}
+
+// closeness はダミー選択肢が正解とどれだけ紛らわしいか (0.0〜1.0) を推定します。
+// 紛らわしい単語 (SimilarIDs) は 1.0、それ以外は意味の近さに、同じ品詞・同じレベルであることを加味します。
+func (q *Quiz) closeness(d *objects.Datum) float64 {
+	correct := q.CorrectAnswer
+	if slices.Contains(correct.SimilarIDs, d.ID) || slices.Contains(d.SimilarIDs, correct.ID) {
+		return 1
+	}
+	score := q.semanticScore(correct, d)
+	if partOfSpeech(correct) == partOfSpeech(d) {
+		score += 0.2
+	}
+	if correct.Level == d.Level {
+		score += 0.1
+	}
+	return min(score, 1)
+}
+
+// estimateDifficulty は現在の選択肢から問題の難易度 (0.0〜1.0) を推定します。
+// ダミー選択肢の紛らわしさの平均で、値が大きいほど難しい問題です。
+func (q *Quiz) estimateDifficulty() float64 {
+	if q.CorrectAnswer == nil || len(q.OptionsArray) <= 1 {
+		return 0
+	}
+	total := 0.0
+	for i := range q.OptionsArray {
+		if q.OptionsArray[i].ID == q.CorrectAnswer.ID {
+			continue
+		}
+		total += q.closeness(&q.OptionsArray[i])
+	}
+	return total / float64(len(q.OptionsArray)-1)
+}
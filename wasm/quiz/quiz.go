@@ -2,107 +2,183 @@ package quiz
 
 import (
 	"english_app_for_japanese/wasm/objects"
+	"math/rand/v2"
+	"slices"
+	"time"
 )
 
 // Quiz はクイズモードのデータと状態を管理する構造体です。
 type Quiz struct {
-	appData         *objects.AppData // アプリケーション全体のデータへのポインタ
-	FilteredArray   []objects.Datum  // フィルタリングおよびシャッフルされた問題データのスライス
-	index           int              // FilteredArray 内の現在の問題インデックス
-	Level           int              // 現在選択されている問題のレベル (0 は全レベル)
-	numberOfOptions int              // 各問題で表示する選択肢の数
-	CorrectAnswer   *objects.Datum   // 現在の問題の正解データへのポインタ
-	OptionsArray    []objects.Datum  // 現在の問題の選択肢（正解を含む）のスライス
+	appData          *objects.AppData         // アプリケーション全体のデータへのポインタ
+	FilteredArray    []objects.Datum          // フィルタリングおよびシャッフルされた問題データのスライス
+	Filter           objects.Filter           // 現在選択されている問題の絞り込み条件
+	numberOfOptions  int                      // 各問題で表示する選択肢の数
+	CorrectAnswer    *objects.Datum           // 現在の問題の正解データへのポインタ (Round.Items の要素で、appData.Data のコピー)
+	OptionsArray     []objects.Datum          // 現在の問題の選択肢（正解を含む）のスライス
+	Strategies       []Strategy               // ダミー選択肢の選び方の優先順 (空の場合は DefaultStrategies)
+	Difficulty       float64                  // 現在の問題の推定難易度 (0.0〜1.0、大きいほど難しい)
+	Direction        Direction                // 出題方向の設定 (空の場合は DirectionEnToJp)
+	CurrentDirection Direction                // 現在の問題の出題方向 (DirectionMixed の場合に問題ごとに決まる)
+	featureCache     map[int]features         // 意味の近さの計算に使う単語IDごとの特徴のキャッシュ
+	featureGen       uint64                   // featureCache を作成したときの appData の世代番号 (objects.AppData.Generation を参照)
+	Session          Session                  // 現在のセッションの成績 (正解数、連続正解数、解答の記録)
+	Timing           Timing                   // 時間制限の設定 (ゼロ値の場合は時間制限なし)
+	questionStart    time.Time                // 現在の問題を出題した時刻
+	answered         bool                     // 現在の問題に解答済みかどうか
+	Round            objects.Round            // 現在の周回の出題状況
+	Seed             *uint64                  // 乱数のシード (nil の場合はグローバルな乱数生成器を使用)
+	rng              *rand.Rand               // Seed から作成した乱数生成器 (Init で作り直す)
+	keep             func(objects.Datum) bool // 出題する問題を絞り込み条件の結果からさらに選ぶ関数 (nil の場合はすべて)
 }
 
 // Init は Quiz 構造体を初期化します。
-// 指定されたレベルに基づいて、アプリケーションデータから未学習の問題をフィルタリングし、
-// シャッフルして内部の FilteredArray に格納します。また、選択肢の数を設定します。
+// Seed が設定されている場合は、乱数生成器をそのシードで作り直します。
+// 指定された絞り込み条件に基づいて、アプリケーションデータから問題をフィルタリングし、
+// 並び替えて (既定ではシャッフルして) 内部の FilteredArray に格納します。また、選択肢の数を設定します。
 //
 // 引数:
 //   - appData: アプリケーション全体のデータ (objects.AppData) へのポインタ。
-//   - level: フィルタリングする問題のレベル。0 を指定するとレベルに関係なくフィルタリングします。
+//   - filter: 問題の絞り込み条件。objects.LevelFilter(0) でレベルに関係なく未学習の問題を対象にします。
 //   - choiceCount: 各問題で生成する選択肢の数（正解を含む）。
-func (q *Quiz) Init(appData *objects.AppData, level int, choiceCount int) {
+func (q *Quiz) Init(appData *objects.AppData, filter objects.Filter, choiceCount int) {
 	q.appData = appData
-	q.Level = level
+	q.Filter = filter
 	q.numberOfOptions = choiceCount
-	q.index = 0 // インデックスを初期化
-	// LocalStorageに含まれていない（未学習の）データを取得
-	tmp := q.appData.FilterNotInStorage()
-	// level が 0 以外の場合、指定されたレベルでさらにフィルタリング
-	if q.Level != 0 {
-		tmp = objects.FilterByLevel(tmp, q.Level)
+	// 意味の近さの計算に使う特徴は appData ごとに計算し直す
+	q.featureCache = nil
+	// シードが指定されている場合は、同じシードから同じ問題の順序・選択肢になるよう乱数生成器を作り直す
+	q.rng = nil
+	if q.Seed != nil {
+		q.rng = objects.NewRand(*q.Seed)
 	}
-	// フィルタリングされたデータをシャッフルして格納
-	q.FilteredArray = objects.ShuffleCopy(tmp)
+	// 周回と表示中の問題を初期化 (前の絞り込み条件の問題を新しい成績に記録しない)
+	q.Round = objects.Round{}
+	q.CorrectAnswer = nil
+	q.OptionsArray = nil
+	// 絞り込み条件に一致するデータを並び替えて格納
+	q.queryAll()
+	// 成績は絞り込み条件ごとに集計する
+	q.ResetSession()
 }
 
 // Next は次のクイズ問題に進みます。
-// FilteredArray から現在のインデックスに対応する問題データを CorrectAnswer に設定し、
-// インデックスを次に進めます。配列の末尾に達した場合は、インデックスを 0 に戻してループさせます。
-// 最後に、新しい正解に対応する選択肢を生成するために CreateOptionsArray を呼び出します。
-func (q *Quiz) Next() {
-	// FilteredArray が空でないことを確認（Init が呼ばれている前提）
-	if len(q.FilteredArray) == 0 {
+// 問題は周回 (Round) 単位で出題し、現在の周回の次の問題を CorrectAnswer に設定します。
+// 周回の問題をすべて出題し終えている場合は、新しい周回を開始します。新しい周回の問題は
+// 絞り込み条件で取り出し直して並び替え (既定ではシャッフルし) ますが、Round.MissedOnly が true の場合は
+// 前の周回で間違えた問題のみにします。絞り込み条件の並び順が objects.SortAdaptive の場合は、
+// 周回の残りの問題からその時点の推定正答率に合わせて1問ずつ選びます (objects.AppData.NextAdaptive を参照)。
+// 最後に、出題方向 (CurrentDirection) を決め、新しい正解に対応する選択肢を生成するために CreateOptionsArray を呼び出します。
+// 解答時間 (Answer を参照) は Next を呼び出した時刻から計測します。
+// スプリント (Timing.Budget) の持ち時間はセッションの最初の Next から計測し、使い切った場合は CorrectAnswer を nil にします。
+// 前の問題が解答されないまま制限時間か持ち時間を過ぎている場合は、次に進む前に時間切れ (不正解) として記録します。
+//
+// 戻り値:
+//   - 前の問題を時間切れとして記録した場合 true (単語の解答記録を保存し直す必要があります)。
+func (q *Quiz) Next() bool {
+	expired := q.recordExpired()
+	q.answered = false
+	q.questionStart = now()
+	if q.Session.Started.IsZero() {
+		q.Session.Started = q.questionStart
+	}
+	if q.TimeUp() {
 		q.CorrectAnswer = nil
 		q.OptionsArray = nil
-		return
+		return expired
 	}
-	// 現在のインデックスのデータを CorrectAnswer に設定
-	q.CorrectAnswer = &q.FilteredArray[q.index]
-	// インデックスを次に進める
-	q.index++
-	// インデックスが配列の範囲を超えたら 0 に戻す
-	if q.index >= len(q.FilteredArray) {
-		q.index = 0
+	q.CorrectAnswer = q.nextInRound()
+	if q.CorrectAnswer == nil {
+		q.OptionsArray = nil
+		return expired
 	}
+	// 出題方向を決める
+	q.CurrentDirection = q.resolveDirection()
 	// 新しい正解に対する選択肢を生成する
 	q.CreateOptionsArray()
+	return expired
+}
+
+// nextInRound は現在の周回の次の問題を返します。周回が完了している場合は新しい周回を開始します。
+// 出題できる問題がない場合は nil を返します。
+func (q *Quiz) nextInRound() *objects.Datum {
+	// 最初の周回は Init で並び替えた順に出題し、以降は取り出し直した問題で新しい周回を始める
+	if !q.Round.Continue(q.rng, q.FilteredArray, q.queryAll) {
+		return nil
+	}
+	if q.Filter.Sort == objects.SortAdaptive {
+		// おまかせの場合は直前までの解答結果に合わせて、周回の残りの問題から1問ずつ選ぶ
+		return q.appData.NextAdaptive(q.rng, &q.Round)
+	}
+	return q.Round.Next()
+}
+
+// queryAll は絞り込み条件に一致する問題を取り出し直して並び替え、FilteredArray に格納して返します。
+// 周回の途中で除外された単語は、次の周回から出題されなくなります。
+func (q *Quiz) queryAll() []objects.Datum {
+	q.FilteredArray = q.appData.QueryRand(q.Filter, q.rng)
+	if q.keep != nil {
+		q.FilteredArray = slices.DeleteFunc(q.FilteredArray, func(d objects.Datum) bool {
+			return !q.keep(d)
+		})
+	}
+	return q.FilteredArray
 }
 
 // CreateOptionsArray は現在の正解 (CorrectAnswer) に対する選択肢の配列 (OptionsArray) を生成します。
-// 正解データを含め、指定された numberOfOptions の数だけ、重複しないようにランダムな選択肢を
-// アプリケーションデータ全体 (appData.Data) から選び出します。
-// 生成された選択肢の配列は最後にシャッフルされます。
+// 正解データを含め、指定された numberOfOptions の数だけ、ダミー選択肢を Strategies の優先順に
+// 選び出します。どの選び方でも足りない場合は、アプリケーションデータ全体からランダムに補います。
+// 表示されるテキスト (出題方向に応じた日本語訳または英単語、OptionText を参照) が同じ選択肢や空の選択肢は追加されないため、
+// 画面上で見分けのつかない選択肢が並ぶことはありません。
+// また、問題文が正解と同じになる単語 (例: JP→EN で日本語訳が同じ単語) は正解が複数になるため使いません。
+// 生成された選択肢の配列は最後にシャッフルされ、問題の推定難易度 (Difficulty) が計算されます。
 //
-// 注意: appData.Data の要素数が numberOfOptions より少ない場合、
+// 注意: 表示テキストの異なる単語が numberOfOptions より少ない場合、
 //
-//	またはランダム選択の試行回数が上限に達した場合、
 //	生成される選択肢の数が numberOfOptions より少なくなる可能性があります。
 func (q *Quiz) CreateOptionsArray() {
 	// 正解データが設定されていない場合は何もしない
 	if q.CorrectAnswer == nil {
 		q.OptionsArray = nil
+		q.Difficulty = 0
 		return
 	}
 
 	// 選択肢配列を正解データで初期化
 	q.OptionsArray = []objects.Datum{*q.CorrectAnswer}
-	// 選択済みのIDを記録するマップ
-	selectedIDs := make(map[int]bool)
-	selectedIDs[q.CorrectAnswer.ID] = true
+	// 選択済みのIDと表示テキストを記録するマップ
+	selectedIDs := map[int]bool{q.CorrectAnswer.ID: true}
+	selectedTexts := map[string]bool{q.OptionText(q.CorrectAnswer): true}
+	// 問題文が正解と同じになる単語 (例: JP→EN で日本語訳が同じ単語) は、それも正解になってしまうため使わない
+	prompt := q.promptTextOf(q.CorrectAnswer)
 
-	// 無限ループを防ぐための最大試行回数
-	maxAttempts := len(q.appData.Data) * 2 // データ数の2倍を試行回数上限とする
-	attempts := 0
+	strategies := q.Strategies
+	if len(strategies) == 0 {
+		strategies = DefaultStrategies
+	}
+	// 最後は必ずランダムで補う
+	if strategies[len(strategies)-1] != StrategyRandom {
+		strategies = append(slices.Clone(strategies), StrategyRandom)
+	}
 
-	// 必要な選択肢の数に達するまで、または最大試行回数に達するまでループ
-	for len(q.OptionsArray) < q.numberOfOptions && attempts < maxAttempts {
-		attempts++
-		// アプリケーションデータ全体からランダムに候補を選択
-		candidate, err := objects.GetRandomElement(q.appData.Data)
-		// エラーが発生した場合（データが空など）はループを抜ける
-		if err != nil {
-			break // もしくはエラーハンドリング
+	for _, strategy := range strategies {
+		if len(q.OptionsArray) >= q.numberOfOptions {
+			break
 		}
-		// 候補がまだ選択されていない場合
-		if !selectedIDs[candidate.ID] {
-			// 選択肢配列に追加し、選択済みIDマップにも記録
+		for candidate := range q.strategyCandidates(strategy) {
+			if len(q.OptionsArray) >= q.numberOfOptions {
+				break
+			}
+			text := q.OptionText(&candidate)
+			// 選択済みの単語、表示テキストが重複する単語、表示テキストが空の単語は使わない
+			if selectedIDs[candidate.ID] || selectedTexts[text] || text == "" || q.promptTextOf(&candidate) == prompt {
+				continue
+			}
 			q.OptionsArray = append(q.OptionsArray, candidate)
 			selectedIDs[candidate.ID] = true
+			selectedTexts[text] = true
 		}
 	}
 	// 最終的な選択肢配列をシャッフル
-	q.OptionsArray = objects.ShuffleCopy(q.OptionsArray)
+	q.OptionsArray = objects.ShuffleCopyRand(q.rng, q.OptionsArray)
+	q.Difficulty = q.estimateDifficulty()
 }
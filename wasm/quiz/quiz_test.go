@@ -0,0 +1,550 @@
+package quiz
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestAppData はテスト用の単語データを作成します。
+// ID 2 と 3 は ID 1 と同じ日本語訳を持ち、ID 4 と 5 は ID 1 の紛らわしい単語です。
+func newTestAppData() *objects.AppData {
+	appData := &objects.AppData{}
+	for _, d := range []objects.Datum{
+		{ID: 1, Word: "affect", DefinitionEn: "to influence something", DefinitionJa: "影響する", Level: 1, SimilarIDs: []int{4, 5}},
+		{ID: 2, Word: "influence", DefinitionEn: "to have an effect on", DefinitionJa: "影響する", Level: 1},
+		{ID: 3, Word: "impact", DefinitionEn: "to have a strong effect", DefinitionJa: "影響する", Level: 2},
+		{ID: 4, Word: "effect", DefinitionEn: "a result or change", DefinitionJa: "効果", Level: 1, SimilarIDs: []int{1}},
+		{ID: 5, Word: "infect", DefinitionEn: "to pass a disease", DefinitionJa: "感染させる", Level: 2, SimilarIDs: []int{1}},
+		{ID: 6, Word: "apple", DefinitionEn: "a round fruit", DefinitionJa: "りんご", Level: 1},
+		{ID: 7, Word: "quickly", DefinitionEn: "at a fast speed", DefinitionJa: "素早く", Level: 2},
+		{ID: 8, Word: "happy", DefinitionEn: "feeling pleasure", DefinitionJa: "幸せな", Level: 1},
+	} {
+		appData.AddData(d)
+	}
+	return appData
+}
+
+func TestCreateOptionsArrayUniqueText(t *testing.T) {
+	appData := newTestAppData()
+	for _, strategies := range [][]Strategy{
+		{StrategyRandom},
+		{StrategySameLevel},
+		{StrategySimilar},
+		{StrategySamePOS},
+		{StrategySemantic},
+		nil,
+	} {
+		for range 20 {
+			q := Quiz{appData: appData, numberOfOptions: 6, Strategies: strategies}
+			q.CorrectAnswer = appData.GetByID(1)
+			q.CreateOptionsArray()
+
+			// 表示テキストの異なる単語は 6 つあるため、選択肢は必ず 6 つ揃う
+			if len(q.OptionsArray) != 6 {
+				t.Fatalf("strategies %v: expected 6 options, got %d", strategies, len(q.OptionsArray))
+			}
+			texts := make(map[string]bool)
+			foundCorrect := false
+			for _, option := range q.OptionsArray {
+				if texts[option.DefinitionJa] {
+					t.Fatalf("strategies %v: duplicate option text %q in %v", strategies, option.DefinitionJa, q.OptionsArray)
+				}
+				texts[option.DefinitionJa] = true
+				if option.ID == 1 {
+					foundCorrect = true
+				}
+			}
+			if !foundCorrect {
+				t.Fatalf("strategies %v: correct answer is missing from %v", strategies, q.OptionsArray)
+			}
+		}
+	}
+}
+
+// TestFeatureCacheFollowsData は同じIDの単語が書き換えられた場合に、意味の近さを計算し直すことを確認します。
+func TestFeatureCacheFollowsData(t *testing.T) {
+	appData := newTestAppData()
+	var q Quiz
+	q.Init(appData, objects.Filter{}, 3)
+	if score := q.semanticScore(appData.GetByID(1), appData.GetByID(6)); score != 0 {
+		t.Fatalf("expected affect and apple to be unrelated, got %v", score)
+	}
+	data := slices.Clone(appData.Data)
+	data[5] = objects.Datum{ID: 6, Word: "sway", DefinitionEn: "to influence something", DefinitionJa: "左右する", Level: 1}
+	appData.SetData(data)
+	if score := q.semanticScore(appData.GetByID(1), appData.GetByID(6)); score != 1 {
+		t.Errorf("expected the rewritten word to share the definition, got %v", score)
+	}
+}
+
+func TestCreateOptionsArraySimilarFirst(t *testing.T) {
+	appData := newTestAppData()
+	q := Quiz{appData: appData, numberOfOptions: 3, Strategies: []Strategy{StrategySimilar}}
+	q.CorrectAnswer = appData.GetByID(1)
+	q.CreateOptionsArray()
+
+	ids := make(map[int]bool)
+	for _, option := range q.OptionsArray {
+		ids[option.ID] = true
+	}
+	if len(ids) != 3 || !ids[1] || !ids[4] || !ids[5] {
+		t.Errorf("expected options {1, 4, 5}, got %v", q.OptionsArray)
+	}
+	if q.Difficulty != 1 {
+		t.Errorf("expected difficulty 1 for similar-word distractors, got %v", q.Difficulty)
+	}
+}
+
+func TestPartOfSpeech(t *testing.T) {
+	testCases := []struct {
+		datum    objects.Datum
+		expected string
+	}{
+		{objects.Datum{Word: "affect", DefinitionEn: "to influence"}, posVerb},
+		{objects.Datum{Word: "decide", DefinitionJa: "決定する、決める"}, posVerb},
+		{objects.Datum{Word: "happy", DefinitionJa: "幸せな"}, posAdjective},
+		{objects.Datum{Word: "quickly", DefinitionJa: "素早く"}, posAdverb},
+		{objects.Datum{Word: "write", DefinitionJa: "書く"}, posNoun}, // 「く」で終わるだけでは副詞とみなさない
+		{objects.Datum{Word: "carefully", DefinitionJa: "注意深く"}, posAdverb},
+		{objects.Datum{Word: "dangerous", DefinitionJa: "危険"}, posAdjective},
+		{objects.Datum{Word: "apple", DefinitionJa: "りんご"}, posNoun},
+	}
+	for _, tc := range testCases {
+		if got := partOfSpeech(&tc.datum); got != tc.expected {
+			t.Errorf("partOfSpeech(%q) failed: expected %q, got %q", tc.datum.Word, tc.expected, got)
+		}
+	}
+}
+
+func TestDirection(t *testing.T) {
+	appData := newTestAppData()
+	testCases := []struct {
+		direction      Direction
+		expectedPrompt string
+		expectedOption string
+	}{
+		{DirectionEnToJp, "affect", "影響する"},
+		{DirectionJpToEn, "影響する", "affect"},
+		{DirectionDefToEn, "to influence something", "affect"},
+	}
+	for _, tc := range testCases {
+		q := Quiz{appData: appData, numberOfOptions: 4, Direction: tc.direction}
+		q.FilteredArray = appData.GetByIDs([]int{1})
+		q.Next()
+		if q.CurrentDirection != tc.direction {
+			t.Errorf("%s: expected current direction %s, got %s", tc.direction, tc.direction, q.CurrentDirection)
+		}
+		if got := q.PromptText(); got != tc.expectedPrompt {
+			t.Errorf("%s: expected prompt %q, got %q", tc.direction, tc.expectedPrompt, got)
+		}
+		if got := q.OptionText(q.CorrectAnswer); got != tc.expectedOption {
+			t.Errorf("%s: expected option text %q, got %q", tc.direction, tc.expectedOption, got)
+		}
+		// JP→EN では日本語訳が同じ単語 (influence, impact) も正解になってしまうため選択肢に使わない
+		for _, option := range q.OptionsArray {
+			if tc.direction == DirectionJpToEn && (option.ID == 2 || option.ID == 3) {
+				t.Errorf("%s: option %q has the same prompt as the correct answer", tc.direction, option.Word)
+			}
+		}
+		if len(q.OptionsArray) != 4 {
+			t.Errorf("%s: expected 4 options, got %d", tc.direction, len(q.OptionsArray))
+		}
+	}
+}
+
+func TestClozeQuiz(t *testing.T) {
+	appData := &objects.AppData{}
+	for _, d := range []objects.Datum{
+		{ID: 1, Word: "take", DefinitionEn: "to get hold of", DefinitionJa: "取る", ExampleEn: "She took the bus.", ExampleJa: "彼女はバスに乗った。", SimilarIDs: []int{2}},
+		{ID: 2, Word: "make", DefinitionEn: "to create", DefinitionJa: "作る", ExampleEn: "He made it."},
+		{ID: 3, Word: "stop", DefinitionEn: "to end a movement", DefinitionJa: "止める", ExampleEn: "No example here."},
+		{ID: 4, Word: "apple", DefinitionEn: "a round fruit", DefinitionJa: "りんご"},
+		{ID: 5, Word: "study", DefinitionJa: "勉強する"},
+	} {
+		appData.AddData(d)
+	}
+
+	var c ClozeQuiz
+	c.Init(appData, objects.Filter{Sort: objects.SortID}, 4)
+	// 例文に見出し語を含まない単語 (ID 3, 4, 5) は出題しない
+	if len(c.FilteredArray) != 2 || c.FilteredArray[0].ID != 1 || c.FilteredArray[1].ID != 2 {
+		t.Fatalf("expected questions [1 2], got %v", c.FilteredArray)
+	}
+
+	c.Next()
+	if c.Current == nil {
+		t.Fatal("expected a cloze question")
+	}
+	if c.Current.Sentence != "She _____ the bus." || c.Current.Answer != "took" || c.Current.Hint != "彼女はバスに乗った。" {
+		t.Errorf("unexpected cloze: %+v", c.Current)
+	}
+	// 選択肢は正解と同じ品詞 (動詞) の単語を、正解と同じ過去形に変化させる
+	texts := make(map[int]string)
+	for _, option := range c.Current.Options {
+		texts[option.ID] = option.Text
+	}
+	expected := map[int]string{1: "took", 2: "made", 3: "stopped", 5: "studied"}
+	for id, text := range expected {
+		if texts[id] != text {
+			t.Errorf("expected option %d to be %q, got %q (options %v)", id, text, texts[id], c.Current.Options)
+		}
+	}
+	if _, exists := texts[4]; exists {
+		t.Errorf("noun %q should not be used as an option for a verb: %v", "apple", c.Current.Options)
+	}
+
+	for _, tc := range []struct {
+		input   string
+		correct bool
+		close   bool
+	}{
+		{" Took ", true, false},
+		{"take", false, true},
+		{"made", false, false},
+	} {
+		result := c.Check(tc.input)
+		if result.Correct != tc.correct || result.Close != tc.close || result.Answer != "took" {
+			t.Errorf("Check(%q) failed: got %+v", tc.input, result)
+		}
+	}
+}
+
+func TestAnswerAndSummary(t *testing.T) {
+	current := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	appData := newTestAppData()
+	var q Quiz
+	q.Init(appData, objects.Filter{Levels: []int{1}, Sort: objects.SortID}, 4)
+
+	if _, err := q.Answer(1); err != ErrNoQuestion {
+		t.Fatalf("expected ErrNoQuestion, got %v", err)
+	}
+
+	// 1問目 (affect) に 2 秒で正解、2問目 (influence) に 4 秒で不正解、3問目 (effect) に正解
+	for i, tc := range []struct {
+		correct bool
+		elapsed time.Duration
+	}{
+		{true, 2 * time.Second},
+		{false, 4 * time.Second},
+		{true, 3 * time.Second},
+	} {
+		q.Next()
+		current = current.Add(tc.elapsed)
+		choiceID := q.CorrectAnswer.ID
+		if !tc.correct {
+			for _, option := range q.OptionsArray {
+				if option.ID != q.CorrectAnswer.ID {
+					choiceID = option.ID
+					break
+				}
+			}
+		}
+		result, err := q.Answer(choiceID)
+		if err != nil {
+			t.Fatalf("question %d: unexpected error %v", i, err)
+		}
+		if result.Correct != tc.correct || result.Elapsed != tc.elapsed || result.CorrectAnswer.ID != q.CorrectAnswer.ID {
+			t.Errorf("question %d: unexpected result %+v", i, result)
+		}
+		if _, err := q.Answer(choiceID); err != ErrAlreadyAnswered {
+			t.Errorf("question %d: expected ErrAlreadyAnswered, got %v", i, err)
+		}
+	}
+
+	if q.Session.Score != 2 || q.Session.Streak != 1 || q.Session.BestStreak != 1 {
+		t.Errorf("unexpected session %+v", q.Session)
+	}
+	summary := q.Summary()
+	if summary.Answered != 3 || summary.AverageTime != 3*time.Second || len(summary.Missed) != 1 || summary.Missed[0].ID != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if p := appData.Progress[2]; p.Incorrect != 1 {
+		t.Errorf("expected the miss to be recorded in progress, got %+v", p)
+	}
+}
+
+func TestQuizRounds(t *testing.T) {
+	appData := newTestAppData()
+	var q Quiz
+	q.Init(appData, objects.Filter{Levels: []int{2}}, 2)
+	q.Round.MissedOnly = true
+
+	// 1周目: レベル2の3問 (3, 5, 7) をすべて1回ずつ出題する
+	seen := make(map[int]bool)
+	var missedID int
+	for i := 1; i <= 3; i++ {
+		q.Next()
+		if q.Round.Number != 1 || q.Round.Position() != i || q.Round.Total() != 3 {
+			t.Fatalf("question %d: unexpected round %d, %d/%d", i, q.Round.Number, q.Round.Position(), q.Round.Total())
+		}
+		seen[q.CorrectAnswer.ID] = true
+		choiceID := q.CorrectAnswer.ID
+		if i == 2 {
+			missedID = q.CorrectAnswer.ID
+			for _, option := range q.OptionsArray {
+				if option.ID != q.CorrectAnswer.ID {
+					choiceID = option.ID
+				}
+			}
+		}
+		if _, err := q.Answer(choiceID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(seen) != 3 || !q.Round.Done() {
+		t.Fatalf("expected every question once and the round to be done, got %v", seen)
+	}
+
+	// 2周目: 1周目で間違えた問題のみ
+	q.Next()
+	if q.Round.Number != 2 || q.Round.Total() != 1 || q.CorrectAnswer.ID != missedID {
+		t.Errorf("expected round 2 with only question %d, got round %d with %d questions (%d)", missedID, q.Round.Number, q.Round.Total(), q.CorrectAnswer.ID)
+	}
+}
+
+// TestAdaptiveQuiz は「おまかせ」で間違いが続いたレベルが、同じ周回の残りの問題で出題されにくくなることを確認します。
+func TestAdaptiveQuiz(t *testing.T) {
+	// run は同じシードで20問に解答し、出題されたレベルの順を返します。
+	// missLevel2 が true の場合はレベル2の問題をすべて間違え、それ以外はすべて正解します。
+	run := func(missLevel2 bool) []int {
+		appData := &objects.AppData{}
+		for id := 1; id <= 40; id++ {
+			appData.AddData(objects.Datum{ID: id, Word: fmt.Sprintf("word%d", id), DefinitionJa: fmt.Sprintf("訳%d", id), Level: (id-1)/20 + 1})
+		}
+		seed := uint64(7)
+		q := Quiz{Seed: &seed}
+		q.Init(appData, objects.AutoFilter(), 2)
+		var levels []int
+		for range 20 {
+			q.Next()
+			levels = append(levels, q.CorrectAnswer.Level)
+			choiceID := q.CorrectAnswer.ID
+			if missLevel2 && q.CorrectAnswer.Level == 2 {
+				for _, option := range q.OptionsArray {
+					if option.ID != q.CorrectAnswer.ID {
+						choiceID = option.ID
+					}
+				}
+			}
+			if _, err := q.Answer(choiceID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if q.Round.Number != 1 {
+			t.Fatalf("expected every question to come from the first round, got round %d", q.Round.Number)
+		}
+		return levels
+	}
+
+	control := run(false)
+	levels := run(true)
+	if levels[0] != control[0] {
+		t.Fatalf("expected the same first question with the same seed, got %v and %v", levels, control)
+	}
+	if count(levels, 2) < 1 || count(levels[1:], 2)*2 >= count(control[1:], 2) {
+		t.Errorf("expected level 2 to be served less often after misses: got %v, without misses %v", levels, control)
+	}
+}
+
+// count は values に含まれる v の数を返します。
+func count(values []int, v int) int {
+	n := 0
+	for _, x := range values {
+		if x == v {
+			n++
+		}
+	}
+	return n
+}
+
+// TestSeededQuiz は同じシードで同じ出題順、出題方向、選択肢になることを固定値で確認します。
+func TestSeededQuiz(t *testing.T) {
+	want := []struct {
+		id        int
+		direction Direction
+		options   []int
+	}{
+		{2, DirectionDefToEn, []int{3, 2, 1}},
+		{5, DirectionEnToJp, []int{1, 7, 5}},
+		{1, DirectionJpToEn, []int{4, 5, 1}},
+		{6, DirectionJpToEn, []int{4, 6, 2}},
+		{7, DirectionJpToEn, []int{7, 5, 3}},
+		{4, DirectionEnToJp, []int{4, 6, 1}},
+		{8, DirectionEnToJp, []int{6, 8, 1}},
+		{3, DirectionEnToJp, []int{5, 7, 3}},
+		// 2周目 (再シャッフル)
+		{2, DirectionJpToEn, []int{5, 8, 2}},
+		{7, DirectionJpToEn, []int{5, 3, 7}},
+	}
+	for run := range 2 {
+		seed := uint64(42)
+		q := Quiz{Seed: &seed}
+		q.Init(newTestAppData(), objects.Filter{}, 3)
+		q.Direction = DirectionMixed
+		for i, w := range want {
+			q.Next()
+			var options []int
+			for _, option := range q.OptionsArray {
+				options = append(options, option.ID)
+			}
+			if q.CorrectAnswer.ID != w.id || q.CurrentDirection != w.direction || !slices.Equal(options, w.options) {
+				t.Fatalf("run %d, question %d: got %d %s %v, want %d %s %v",
+					run, i, q.CorrectAnswer.ID, q.CurrentDirection, options, w.id, w.direction, w.options)
+			}
+		}
+	}
+}
+
+func TestDailyChallenge(t *testing.T) {
+	current := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	// 同じ日付では、除外リストに関係なく同じ問題が出題される
+	questions := func(appData *objects.AppData, date string) []int {
+		var d DailyChallenge
+		d.Init(appData, date, 5, 3)
+		var ids []int
+		for d.Next(); d.CorrectAnswer != nil; d.Next() {
+			ids = append(ids, d.CorrectAnswer.ID)
+		}
+		return ids
+	}
+	excluded := newTestAppData()
+	excluded.AddStorage(1)
+	excluded.AddStorage(2)
+	a, b := questions(newTestAppData(), "2025-01-01"), questions(excluded, "2025-01-01")
+	if len(a) != 5 || !slices.Equal(a, b) {
+		t.Errorf("expected the same 5 questions for the same date, got %v and %v", a, b)
+	}
+	if c := questions(newTestAppData(), "2025-01-02"); slices.Equal(a, c) {
+		t.Errorf("expected different questions for another date, got %v", c)
+	}
+
+	// 1問目に即答で正解、2問目に 5 秒で正解、残りは 20 秒で不正解
+	var d DailyChallenge
+	d.Init(newTestAppData(), "2025-01-01", 5, 3)
+	for i := 0; !d.Done(); i++ {
+		d.Next()
+		current = current.Add([]time.Duration{0, 5 * time.Second, 20 * time.Second, 20 * time.Second, 20 * time.Second}[i])
+		choiceID := d.CorrectAnswer.ID
+		if i >= 2 {
+			for _, option := range d.OptionsArray {
+				if option.ID != d.CorrectAnswer.ID {
+					choiceID = option.ID
+				}
+			}
+		}
+		if _, err := d.Answer(choiceID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result := d.Result()
+	want := DailyResult{Date: "2025-01-01", Score: 150 + 125, Correct: 2, Total: 5, TotalTime: 65000, Attempts: 1}
+	if result != want {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+
+	// 同じ日付の2回目の挑戦は、最初の結果を残したまま挑戦した回数のみを増やす
+	history := make(DailyHistory)
+	history.Record(result)
+	if record := history.Record(DailyResult{Date: "2025-01-01", Score: 750, Attempts: 1}); record.Score != result.Score || record.Attempts != 2 {
+		t.Errorf("expected the first result to be kept, got %+v", record)
+	}
+}
+
+func TestTimedQuiz(t *testing.T) {
+	current := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	appData := newTestAppData()
+	var q Quiz
+	q.Init(appData, objects.Filter{Sort: objects.SortID}, 3)
+	q.Timing = Timing{QuestionLimit: 10 * time.Second, Budget: 45 * time.Second}
+
+	// 即答は 100 点、制限時間の半分で 75 点、制限時間を過ぎた解答は正解を選んでも時間切れ
+	for i, tc := range []struct {
+		elapsed  time.Duration
+		timedOut bool
+		points   int
+	}{
+		{0, false, 100},
+		{5 * time.Second, false, 75},
+		{11 * time.Second, true, 0},
+	} {
+		q.Next()
+		current = current.Add(tc.elapsed)
+		result, err := q.Answer(q.CorrectAnswer.ID)
+		if err != nil {
+			t.Fatalf("question %d: unexpected error %v", i, err)
+		}
+		if result.TimedOut != tc.timedOut || result.Correct == tc.timedOut || result.Points != tc.points {
+			t.Errorf("question %d: unexpected result %+v", i, result)
+		}
+	}
+	if p := appData.Progress[3]; p.Incorrect != 1 {
+		t.Errorf("expected the timeout to be recorded as a miss, got %+v", p)
+	}
+
+	// 4問目は解答せずに時間切れにする
+	q.Next()
+	current = current.Add(10 * time.Second)
+	if result, err := q.Timeout(); err != nil || !result.TimedOut || result.TotalPoints != 175 {
+		t.Errorf("unexpected timeout result %+v, %v", result, err)
+	}
+	summary := q.Summary()
+	if summary.Points != 175 || summary.Timeouts != 2 || len(summary.Missed) != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	// 5問目は解答せずに次に進むと、制限時間を過ぎていれば時間切れとして記録される
+	q.Next()
+	missedID := q.CorrectAnswer.ID
+	current = current.Add(11 * time.Second)
+	if !q.Next() {
+		t.Error("expected Next to record the expired question")
+	}
+	if last := q.Session.Answers[len(q.Session.Answers)-1]; last.Datum.ID != missedID || !last.TimedOut || last.ChoiceID != 0 {
+		t.Errorf("expected question %d to be recorded as a timeout, got %+v", missedID, last)
+	}
+	if q.Next() {
+		t.Error("expected Next not to record a question shown just now")
+	}
+
+	// スプリントの持ち時間 (45 秒) を過ぎた解答は、選んだ単語IDのまま時間切れとして記録する
+	if q.Remaining() != 8*time.Second || q.TimeUp() {
+		t.Errorf("expected 8s remaining, got %v", q.Remaining())
+	}
+	current = current.Add(9 * time.Second)
+	choiceID := q.CorrectAnswer.ID
+	result, err := q.Answer(choiceID)
+	if err != nil || !result.TimedOut || result.Correct {
+		t.Errorf("expected the answer after the sprint to time out, got %+v, %v", result, err)
+	}
+	if last := q.Session.Answers[len(q.Session.Answers)-1]; last.ChoiceID != choiceID {
+		t.Errorf("expected the chosen ID %d to be kept, got %d", choiceID, last.ChoiceID)
+	}
+	q.Next()
+	if !q.TimeUp() || q.CorrectAnswer != nil {
+		t.Errorf("expected no question after the sprint, got %v", q.CorrectAnswer)
+	}
+
+	// 持ち時間を使い切ったときに表示中だった問題は、次の Next で時間切れとして記録される
+	q.ResetSession()
+	q.Next()
+	shown := q.CorrectAnswer.ID
+	current = current.Add(46 * time.Second)
+	if !q.Next() || q.CorrectAnswer != nil {
+		t.Fatalf("expected the sprint to end with the shown question recorded")
+	}
+	if s := q.Summary(); s.Answered != 1 || s.Timeouts != 1 || s.Missed[0].ID != shown {
+		t.Errorf("unexpected summary after the sprint %+v", s)
+	}
+}
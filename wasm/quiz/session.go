@@ -0,0 +1,210 @@
+package quiz
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"errors"
+	"time"
+)
+
+// AnswerRecord は1問分の解答の記録です。
+type AnswerRecord struct {
+	Datum    objects.Datum // 出題された単語データ (正解)
+	ChoiceID int           // 選択された選択肢の単語ID
+	Correct  bool          // 正解した場合 true
+	TimedOut bool          // 制限時間内に解答しなかった場合 true (不正解として扱う)
+	Elapsed  time.Duration // 問題の表示から解答までの時間
+	Points   int           // 解答時間で重み付けした得点 (TimedPoints を参照)
+}
+
+// Session はクイズの1セッション (Init から次の Init または ResetSession まで) の成績を保持します。
+type Session struct {
+	Score      int            // 正解数
+	Streak     int            // 現在の連続正解数
+	BestStreak int            // セッション中の最長連続正解数
+	Points     int            // 解答時間で重み付けした得点の合計
+	Timeouts   int            // 制限時間内に解答しなかった問題の数
+	Started    time.Time      // セッションの最初の問題を出題した時刻 (スプリントの残り時間の計算に使用)
+	Answers    []AnswerRecord // 解答の記録 (解答順)
+}
+
+// AnswerResult は AnswerQuiz の結果です。
+type AnswerResult struct {
+	Correct       bool           // 正解した場合 true
+	TimedOut      bool           // 制限時間内に解答しなかった場合 true
+	CorrectAnswer *objects.Datum // 正解の単語データ
+	Elapsed       time.Duration  // 問題の表示から解答までの時間
+	Points        int            // この解答の得点 (TimedPoints を参照)
+	TotalPoints   int            // 解答後のセッションの得点の合計
+	Score         int            // 解答後のセッションの正解数
+	Answered      int            // 解答後のセッションの解答数
+	Streak        int            // 解答後の連続正解数
+	BestStreak    int            // 解答後の最長連続正解数
+}
+
+// Summary はクイズのセッションの集計結果です。
+type Summary struct {
+	Answered    int             // 解答数
+	Score       int             // 正解数
+	Accuracy    float64         // 正答率 (0.0〜1.0、解答がない場合は 0)
+	BestStreak  int             // 最長連続正解数
+	Points      int             // 解答時間で重み付けした得点の合計
+	Timeouts    int             // 制限時間内に解答しなかった問題の数
+	TotalTime   time.Duration   // 解答時間の合計
+	AverageTime time.Duration   // 1問あたりの平均解答時間
+	Missed      []objects.Datum // 間違えた単語 (重複なし、最初に間違えた順)
+}
+
+// 解答時のエラーです。
+var (
+	ErrNoQuestion      = errors.New("問題がありません")
+	ErrAlreadyAnswered = errors.New("この問題はすでに解答済みです")
+	ErrInvalidChoice   = errors.New("選択肢に含まれない単語IDです")
+)
+
+// now は現在時刻を返します。テストで置き換えられるように変数にしています。
+var now = time.Now
+
+// ResetSession はセッションの成績 (正解数、連続正解数、解答の記録) を初期化します。
+func (q *Quiz) ResetSession() {
+	q.Session = Session{}
+}
+
+// Answer は現在の問題に対する解答を CorrectAnswer と照合し、セッションの成績と単語の解答記録を更新します。
+// 1つの問題に解答できるのは1回のみです。
+// Timing.QuestionLimit またはスプリントの持ち時間を過ぎてからの解答は、選択肢に関係なく時間切れ (不正解) として記録します
+// (選択した単語IDはそのまま記録します)。
+//
+// 引数:
+//   - choiceID: 選択された選択肢の単語ID。
+//
+// 戻り値:
+//   - 解答の結果。
+//   - 問題がない場合は ErrNoQuestion、解答済みの場合は ErrAlreadyAnswered、
+//     選択肢に含まれないIDの場合は ErrInvalidChoice。
+func (q *Quiz) Answer(choiceID int) (AnswerResult, error) {
+	if err := q.checkAnswerable(); err != nil {
+		return AnswerResult{}, err
+	}
+	valid := false
+	for _, option := range q.OptionsArray {
+		if option.ID == choiceID {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return AnswerResult{}, ErrInvalidChoice
+	}
+	elapsed := now().Sub(q.questionStart)
+	if q.Timing.expired(elapsed) || q.TimeUp() {
+		return q.record(choiceID, false, true, elapsed), nil
+	}
+	return q.record(choiceID, choiceID == q.CorrectAnswer.ID, false, elapsed), nil
+}
+
+// Timeout は現在の問題を時間切れ (不正解) として記録します。
+// 制限時間を過ぎても解答がない場合に呼び出します。スプリントの持ち時間を使い切った後も、表示中の問題を記録できます。
+//
+// 戻り値:
+//   - 解答の結果。
+//   - 問題がない場合は ErrNoQuestion、解答済みの場合は ErrAlreadyAnswered。
+func (q *Quiz) Timeout() (AnswerResult, error) {
+	if err := q.checkAnswerable(); err != nil {
+		return AnswerResult{}, err
+	}
+	return q.record(0, false, true, now().Sub(q.questionStart)), nil
+}
+
+// checkAnswerable は現在の問題に解答できるかを確認します。
+func (q *Quiz) checkAnswerable() error {
+	if q.CorrectAnswer == nil {
+		return ErrNoQuestion
+	}
+	if q.answered {
+		return ErrAlreadyAnswered
+	}
+	return nil
+}
+
+// recordExpired は現在の問題が解答されないまま1問あたりの制限時間かスプリントの持ち時間を過ぎている場合に、
+// 時間切れ (不正解) として記録します。記録した場合は true を返します。
+func (q *Quiz) recordExpired() bool {
+	if q.CorrectAnswer == nil || q.answered {
+		return false
+	}
+	elapsed := now().Sub(q.questionStart)
+	if !q.Timing.expired(elapsed) && !q.TimeUp() {
+		return false
+	}
+	q.record(0, false, true, elapsed)
+	return true
+}
+
+// record は解答結果をセッションの成績と単語の解答記録に記録します。
+func (q *Quiz) record(choiceID int, correct, timedOut bool, elapsed time.Duration) AnswerResult {
+	q.answered = true
+	s := &q.Session
+	points := 0
+	if correct {
+		points = q.Timing.Points(elapsed)
+		s.Score++
+		s.Streak++
+		s.BestStreak = max(s.BestStreak, s.Streak)
+		s.Points += points
+	} else {
+		s.Streak = 0
+		// 間違えた (時間切れを含む) 問題は次の周回で復習できるように記録する
+		q.Round.MarkMissed(*q.CorrectAnswer)
+	}
+	if timedOut {
+		s.Timeouts++
+	}
+	s.Answers = append(s.Answers, AnswerRecord{
+		Datum:    *q.CorrectAnswer,
+		ChoiceID: choiceID,
+		Correct:  correct,
+		TimedOut: timedOut,
+		Elapsed:  elapsed,
+		Points:   points,
+	})
+	q.appData.RecordAnswer(q.CorrectAnswer.ID, correct)
+
+	return AnswerResult{
+		Correct:       correct,
+		TimedOut:      timedOut,
+		CorrectAnswer: q.CorrectAnswer,
+		Elapsed:       elapsed,
+		Points:        points,
+		TotalPoints:   s.Points,
+		Score:         s.Score,
+		Answered:      len(s.Answers),
+		Streak:        s.Streak,
+		BestStreak:    s.BestStreak,
+	}
+}
+
+// Summary は現在のセッションの成績を集計します。
+func (q *Quiz) Summary() Summary {
+	s := q.Session
+	summary := Summary{
+		Answered:   len(s.Answers),
+		Score:      s.Score,
+		BestStreak: s.BestStreak,
+		Points:     s.Points,
+		Timeouts:   s.Timeouts,
+		Missed:     make([]objects.Datum, 0),
+	}
+	missed := make(map[int]bool)
+	for _, a := range s.Answers {
+		summary.TotalTime += a.Elapsed
+		if !a.Correct && !missed[a.Datum.ID] {
+			missed[a.Datum.ID] = true
+			summary.Missed = append(summary.Missed, a.Datum)
+		}
+	}
+	if summary.Answered > 0 {
+		summary.Accuracy = float64(summary.Score) / float64(summary.Answered)
+		summary.AverageTime = summary.TotalTime / time.Duration(summary.Answered)
+	}
+	return summary
+}
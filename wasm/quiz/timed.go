@@ -0,0 +1,47 @@
+package quiz
+
+import "time"
+
+// 解答時間による得点の重み付けの設定です。
+const (
+	timedMaxPoints = 100              // 即答した場合の得点
+	timedMinPoints = 50               // 基準の時間以上かかった場合の得点
+	timedReference = 10 * time.Second // 制限時間がない場合に得点の計算の基準にする時間
+)
+
+// Timing は時間制限付きのクイズの設定です。ゼロ値は時間制限なしを意味します。
+type Timing struct {
+	QuestionLimit time.Duration // 1問あたりの制限時間 (0 の場合は制限なし)
+	Budget        time.Duration // スプリントの持ち時間 (0 の場合は制限なし)。持ち時間内にできるだけ多くの問題に解答します
+}
+
+// Points は正解した場合の得点を、解答時間 elapsed で重み付けして計算します。
+// 即答で timedMaxPoints 点とし、QuestionLimit (制限がない場合は timedReference) に近づくほど
+// timedMinPoints 点まで直線的に減らします。
+func (t Timing) Points(elapsed time.Duration) int {
+	reference := t.QuestionLimit
+	if reference <= 0 {
+		reference = timedReference
+	}
+	elapsed = min(max(elapsed, 0), reference)
+	return timedMaxPoints - int((timedMaxPoints-timedMinPoints)*elapsed/reference)
+}
+
+// expired は解答時間 elapsed が1問あたりの制限時間を過ぎているかどうかを返します。
+func (t Timing) expired(elapsed time.Duration) bool {
+	return t.QuestionLimit > 0 && elapsed > t.QuestionLimit
+}
+
+// Remaining はスプリントの残り時間を返します。
+// スプリントでない場合、またはまだ最初の問題を出題していない場合は Budget をそのまま返します。
+func (q *Quiz) Remaining() time.Duration {
+	if q.Timing.Budget <= 0 || q.Session.Started.IsZero() {
+		return q.Timing.Budget
+	}
+	return max(0, q.Timing.Budget-now().Sub(q.Session.Started))
+}
+
+// TimeUp はスプリントの持ち時間を使い切ったかどうかを返します。スプリントでない場合は常に false です。
+func (q *Quiz) TimeUp() bool {
+	return q.Timing.Budget > 0 && !q.Session.Started.IsZero() && q.Remaining() <= 0
+}
@@ -0,0 +1,281 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"english_app_for_japanese/wasm/lemma"
+	"english_app_for_japanese/wasm/objects"
+	"english_app_for_japanese/wasm/similar"
+	"fmt"
+	"slices"
+	"syscall/js"
+)
+
+// findDatumByWord は見出し語から単語データを探します。
+// 大文字・小文字を区別しない一致、変化形からの原形 (lemma.Lemmatize を参照) の一致の順に探します。
+// 見つからない場合は nil を返します。
+func findDatumByWord(word string) *objects.Datum {
+	return appData.GetByWord(lemma.Lemmatize(word, appData.IsHeadword))
+}
+
+// similarGraph は appData.Data から作成した類似語グラフのキャッシュです。
+// similarGraphGeneration は作成したときの appData の世代番号 (objects.AppData.Generation を参照) です。
+var similarGraph *similar.Graph
+var similarGraphGeneration uint64
+
+// currentSimilarGraph は類似語グラフを返します。appData.Data が変更されていない間は、
+// 前回作成したグラフを再利用し、変更された場合だけ作り直します。
+func currentSimilarGraph() *similar.Graph {
+	if similarGraph == nil || similarGraphGeneration != appData.Generation() {
+		similarGraph = similar.NewGraph(appData.Data)
+		similarGraphGeneration = appData.Generation()
+	}
+	return similarGraph
+}
+
+// similarNodeToJS は類似語グラフの単語をJavaScriptで扱いやすい形式に変換します。
+func similarNodeToJS(d *objects.Datum) map[string]interface{} {
+	return map[string]interface{}{
+		"id":    d.ID,
+		"en":    d.Word,
+		"jp":    d.DefinitionJa,
+		"level": d.Level,
+	}
+}
+
+// linksToJS は類似語のリンクのスライスを、JavaScript の `{from, to}` オブジェクトの配列に変換します。
+func linksToJS(links []similar.Link) []interface{} {
+	jsArray := make([]interface{}, len(links))
+	for i, link := range links {
+		jsArray[i] = map[string]interface{}{
+			"from": link.From,
+			"to":   link.To,
+		}
+	}
+	return jsArray
+}
+
+// SearchSimilarGraph はJavaScriptから呼び出され、指定された単語から depth 段階以内でたどれる
+// 類似語をグラフ (ノードとリンク) として返します。単語マップの表示に使用します。
+//
+// 引数:
+//   - args[0]: 起点の見出し語 (文字列型)。
+//   - args[1]: 探索する深さ (数値型)。1 で直接の類似語のみ。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{nodes, edges}` で解決されます。
+//     nodes は `{id, en, jp, level, depth}` の配列 (depth は起点からの距離、起点自身は 0)、
+//     edges は `{from, to}` の配列です。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func SearchSimilarGraph(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if appData.Data == nil {
+				reject.Invoke(js.ValueOf("Go関数(SearchSimilarGraph)エラー: appDataが初期化されていません。InitializeAppDataが正常に完了したか確認してください。"))
+				return
+			}
+			if len(args) != 2 {
+				reject.Invoke(js.ValueOf("Go関数(SearchSimilarGraph)エラー: 引数は2つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeString {
+				reject.Invoke(js.ValueOf("Go関数(SearchSimilarGraph)エラー: 引数0は文字列型である必要があります"))
+				return
+			}
+			if args[1].Type() != js.TypeNumber {
+				reject.Invoke(js.ValueOf("Go関数(SearchSimilarGraph)エラー: 引数1は数値型である必要があります"))
+				return
+			}
+			origin := findDatumByWord(args[0].String())
+			if origin == nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(SearchSimilarGraph)エラー: 単語が見つかりません: %s", args[0].String())))
+				return
+			}
+			depth := args[1].Int()
+
+			graph := currentSimilarGraph()
+			distances := graph.Neighbors(origin.ID, depth)
+			distances[origin.ID] = 0
+			ids := make([]int, 0, len(distances))
+			for id := range distances {
+				ids = append(ids, id)
+			}
+			// 起点からの距離、IDの順に並べる
+			slices.SortFunc(ids, func(a, b int) int {
+				if distances[a] != distances[b] {
+					return distances[a] - distances[b]
+				}
+				return a - b
+			})
+
+			nodes := make([]interface{}, 0, len(ids))
+			for _, id := range ids {
+				d := appData.GetByID(id)
+				if d == nil {
+					continue
+				}
+				node := similarNodeToJS(d)
+				node["depth"] = distances[id]
+				nodes = append(nodes, node)
+			}
+			consoleLog.Invoke(js.ValueOf("Go関数(SearchSimilarGraph)で検索したデータの長さ:"), js.ValueOf(len(nodes)))
+			resolve.Invoke(map[string]interface{}{
+				"nodes": nodes,
+				"edges": linksToJS(graph.Edges(ids)),
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// FindSimilarPath はJavaScriptから呼び出され、2つの単語を結ぶ類似語の最短経路を返します。
+//
+// 引数:
+//   - args[0]: 起点の見出し語 (文字列型)。
+//   - args[1]: 終点の見出し語 (文字列型)。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: 経路上の単語 (`{id, en, jp, level}`) の配列で解決されます。経路がない場合は空の配列です。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func FindSimilarPath(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if appData.Data == nil {
+				reject.Invoke(js.ValueOf("Go関数(FindSimilarPath)エラー: appDataが初期化されていません。InitializeAppDataが正常に完了したか確認してください。"))
+				return
+			}
+			if len(args) != 2 {
+				reject.Invoke(js.ValueOf("Go関数(FindSimilarPath)エラー: 引数は2つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeString || args[1].Type() != js.TypeString {
+				reject.Invoke(js.ValueOf("Go関数(FindSimilarPath)エラー: 引数は文字列型である必要があります"))
+				return
+			}
+			from := findDatumByWord(args[0].String())
+			to := findDatumByWord(args[1].String())
+			if from == nil || to == nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(FindSimilarPath)エラー: 単語が見つかりません: %s, %s", args[0].String(), args[1].String())))
+				return
+			}
+
+			path := currentSimilarGraph().ShortestPath(from.ID, to.ID)
+			jsResult := make([]interface{}, 0, len(path))
+			for _, id := range path {
+				if d := appData.GetByID(id); d != nil {
+					jsResult = append(jsResult, similarNodeToJS(d))
+				}
+			}
+			resolve.Invoke(jsResult)
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// GetSimilarClusters はJavaScriptから呼び出され、類似語でつながった単語のまとまりを返します。
+// 紛らわしい単語をまとめて学習するセットの作成に使用します。
+//
+// 引数:
+//   - args[0]: まとまりの最小の単語数 (数値型)。2 未満の場合は 2 として扱います。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: 各まとまりの単語 (`{id, en, jp, level}`) の配列の配列で解決されます。単語数の多い順に並びます。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func GetSimilarClusters(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if appData.Data == nil {
+				reject.Invoke(js.ValueOf("Go関数(GetSimilarClusters)エラー: appDataが初期化されていません。InitializeAppDataが正常に完了したか確認してください。"))
+				return
+			}
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(GetSimilarClusters)エラー: 引数は1つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeNumber {
+				reject.Invoke(js.ValueOf("Go関数(GetSimilarClusters)エラー: 引数は数値型である必要があります"))
+				return
+			}
+
+			clusters := currentSimilarGraph().Clusters(args[0].Int())
+			jsResult := make([]interface{}, len(clusters))
+			for i, cluster := range clusters {
+				jsCluster := make([]interface{}, 0, len(cluster))
+				for _, id := range cluster {
+					if d := appData.GetByID(id); d != nil {
+						jsCluster = append(jsCluster, similarNodeToJS(d))
+					}
+				}
+				jsResult[i] = jsCluster
+			}
+			consoleLog.Invoke(js.ValueOf("Go関数(GetSimilarClusters)で検索したまとまりの数:"), js.ValueOf(len(clusters)))
+			resolve.Invoke(jsResult)
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// CheckSimilarLinks はJavaScriptから呼び出され、一方向にしか記載されていない類似語のリンク
+// (A の SimilarIDs に B があるが、B の SimilarIDs に A がない) を検出し、必要に応じて修正します。
+// 修正はアプリケーション内部のデータ (appData.Data) にのみ反映され、CSVファイルは変更されません。
+//
+// 引数:
+//   - args[0]: 修正するかどうか (真偽値型)。true の場合は逆向きのリンクを追加します。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{asymmetric, fixed}` で解決されます。
+//     asymmetric は検出された一方向のリンク (`{from, to}`) の配列、
+//     fixed は追加したリンクの配列です (修正しない場合は空の配列)。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func CheckSimilarLinks(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if appData.Data == nil {
+				reject.Invoke(js.ValueOf("Go関数(CheckSimilarLinks)エラー: appDataが初期化されていません。InitializeAppDataが正常に完了したか確認してください。"))
+				return
+			}
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(CheckSimilarLinks)エラー: 引数は1つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeBoolean {
+				reject.Invoke(js.ValueOf("Go関数(CheckSimilarLinks)エラー: 引数は真偽値型である必要があります"))
+				return
+			}
+
+			asymmetric := currentSimilarGraph().AsymmetricLinks()
+			var fixed []similar.Link
+			if args[0].Bool() {
+				fixed = similar.FixAsymmetricLinks(appData.Data)
+				// 類似語のリンクを直接書き換えたため、索引を作り直して世代番号を進める
+				appData.Reindex()
+			}
+			consoleLog.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CheckSimilarLinks): 一方向のリンクを %d 件検出し、%d 件修正しました。", len(asymmetric), len(fixed))))
+			resolve.Invoke(map[string]interface{}{
+				"asymmetric": linksToJS(asymmetric),
+				"fixed":      linksToJS(fixed),
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
@@ -0,0 +1,268 @@
+package similar
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"slices"
+)
+
+// Link は類似語の一方向のリンク (From の SimilarIDs に To が含まれていること) を表します。
+type Link struct {
+	From int
+	To   int
+}
+
+// Graph は単語データの SimilarIDs をもとにした類似語のグラフです。
+// 類似関係は本来双方向のものとして扱い、探索ではリンクの向きを区別しません。
+type Graph struct {
+	links map[int][]int        // 単語IDごとの SimilarIDs (データに記載されたままの向き)
+	adj   map[int]map[int]bool // 向きを区別しない隣接関係
+}
+
+// NewGraph は単語データのスライスから類似語のグラフを作成します。
+// データに存在しないIDへのリンク、自分自身へのリンク、重複したリンクは無視されます。
+//
+// 引数:
+//   - data: グラフの元になる単語データのスライス。
+//
+// 戻り値:
+//   - 作成された Graph へのポインタ。
+func NewGraph(data []objects.Datum) *Graph {
+	g := &Graph{
+		links: make(map[int][]int, len(data)),
+		adj:   make(map[int]map[int]bool, len(data)),
+	}
+	for _, d := range data {
+		g.adj[d.ID] = make(map[int]bool)
+	}
+	for _, d := range data {
+		for _, id := range d.SimilarIDs {
+			if id == d.ID {
+				continue
+			}
+			if _, exists := g.adj[id]; !exists || slices.Contains(g.links[d.ID], id) {
+				continue
+			}
+			g.links[d.ID] = append(g.links[d.ID], id)
+			g.adj[d.ID][id] = true
+			g.adj[id][d.ID] = true
+		}
+	}
+	return g
+}
+
+// Has は指定されたIDの単語がグラフに含まれているかを判定します。
+func (g *Graph) Has(id int) bool {
+	_, exists := g.adj[id]
+	return exists
+}
+
+// sortedNeighbors は指定されたIDに隣接する単語IDを昇順で返します。
+func (g *Graph) sortedNeighbors(id int) []int {
+	neighbors := make([]int, 0, len(g.adj[id]))
+	for n := range g.adj[id] {
+		neighbors = append(neighbors, n)
+	}
+	slices.Sort(neighbors)
+	return neighbors
+}
+
+// Neighbors は指定されたIDの単語から depth 段階以内でたどれる類似語を、幅優先探索で求めます。
+// 起点の単語自身は結果に含まれません。
+//
+// 引数:
+//   - id: 起点の単語ID。
+//   - depth: 探索する深さ (1 で直接の類似語のみ)。
+//
+// 戻り値:
+//   - 見つかった単語IDから起点までの距離 (1〜depth) へのマップ。
+func (g *Graph) Neighbors(id, depth int) map[int]int {
+	distances := make(map[int]int)
+	if !g.Has(id) || depth <= 0 {
+		return distances
+	}
+	visited := map[int]bool{id: true}
+	frontier := []int{id}
+	for d := 1; d <= depth && len(frontier) > 0; d++ {
+		var next []int
+		for _, current := range frontier {
+			for _, n := range g.sortedNeighbors(current) {
+				if visited[n] {
+					continue
+				}
+				visited[n] = true
+				distances[n] = d
+				next = append(next, n)
+			}
+		}
+		frontier = next
+	}
+	return distances
+}
+
+// Edges は指定された単語IDの集合の内部にある類似関係を、向きを区別せずに返します。
+// 各リンクは From < To となるように正規化され、From、To の昇順に並びます。
+//
+// 引数:
+//   - ids: 対象の単語IDのスライス。
+//
+// 戻り値:
+//   - 類似関係のリンクのスライス。
+func (g *Graph) Edges(ids []int) []Link {
+	inSet := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		inSet[id] = true
+	}
+	var results []Link
+	for id := range inSet {
+		for n := range g.adj[id] {
+			if id < n && inSet[n] {
+				results = append(results, Link{From: id, To: n})
+			}
+		}
+	}
+	slices.SortFunc(results, func(a, b Link) int {
+		if a.From != b.From {
+			return a.From - b.From
+		}
+		return a.To - b.To
+	})
+	return results
+}
+
+// ShortestPath は2つの単語を結ぶ類似語の最短経路を、幅優先探索で求めます。
+//
+// 引数:
+//   - from: 起点の単語ID。
+//   - to: 終点の単語ID。
+//
+// 戻り値:
+//   - 起点から終点までの単語IDのスライス (両端を含む)。経路がない場合は nil。
+func (g *Graph) ShortestPath(from, to int) []int {
+	if !g.Has(from) || !g.Has(to) {
+		return nil
+	}
+	if from == to {
+		return []int{from}
+	}
+	prev := map[int]int{from: from}
+	queue := []int{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, n := range g.sortedNeighbors(current) {
+			if _, seen := prev[n]; seen {
+				continue
+			}
+			prev[n] = current
+			if n == to {
+				// 終点から起点へさかのぼって経路を組み立てる
+				path := []int{to}
+				for path[len(path)-1] != from {
+					path = append(path, prev[path[len(path)-1]])
+				}
+				slices.Reverse(path)
+				return path
+			}
+			queue = append(queue, n)
+		}
+	}
+	return nil
+}
+
+// Clusters は類似語でつながった単語のまとまり (連結成分) を求めます。
+// 紛らわしい単語をまとめて学習するセットの作成に使用できます。
+//
+// 引数:
+//   - minSize: 結果に含めるまとまりの最小の単語数。2 未満を指定した場合は 2 として扱います。
+//
+// 戻り値:
+//   - 各まとまりの単語IDのスライス (昇順) のスライス。単語数の多い順 (同数の場合は最小IDの昇順) に並びます。
+func (g *Graph) Clusters(minSize int) [][]int {
+	if minSize < 2 {
+		minSize = 2
+	}
+	ids := make([]int, 0, len(g.adj))
+	for id := range g.adj {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	visited := make(map[int]bool, len(ids))
+	var clusters [][]int
+	for _, id := range ids {
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		cluster := []int{id}
+		stack := []int{id}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for n := range g.adj[current] {
+				if !visited[n] {
+					visited[n] = true
+					cluster = append(cluster, n)
+					stack = append(stack, n)
+				}
+			}
+		}
+		if len(cluster) >= minSize {
+			slices.Sort(cluster)
+			clusters = append(clusters, cluster)
+		}
+	}
+	slices.SortStableFunc(clusters, func(a, b []int) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return a[0] - b[0]
+	})
+	return clusters
+}
+
+// AsymmetricLinks は一方向にしか記載されていないリンク
+// (A の SimilarIDs に B があるが、B の SimilarIDs に A がない) を求めます。
+//
+// 戻り値:
+//   - 一方向のリンクのスライス。From、To の昇順に並びます。
+func (g *Graph) AsymmetricLinks() []Link {
+	var results []Link
+	for from, tos := range g.links {
+		for _, to := range tos {
+			if !slices.Contains(g.links[to], from) {
+				results = append(results, Link{From: from, To: to})
+			}
+		}
+	}
+	slices.SortFunc(results, func(a, b Link) int {
+		if a.From != b.From {
+			return a.From - b.From
+		}
+		return a.To - b.To
+	})
+	return results
+}
+
+// FixAsymmetricLinks は単語データの一方向のリンクに逆向きのリンクを追加し、類似関係を双方向にします。
+// data の各 Datum の SimilarIDs が直接更新されます。
+//
+// 引数:
+//   - data: 修正する単語データのスライス。
+//
+// 戻り値:
+//   - 追加したリンクのスライス。
+func FixAsymmetricLinks(data []objects.Datum) []Link {
+	links := NewGraph(data).AsymmetricLinks()
+	indexByID := make(map[int]int, len(data))
+	for i, d := range data {
+		indexByID[d.ID] = i
+	}
+	added := make([]Link, 0, len(links))
+	for _, link := range links {
+		i := indexByID[link.To]
+		data[i].SimilarIDs = append(data[i].SimilarIDs, link.From)
+		added = append(added, Link{From: link.To, To: link.From})
+	}
+	return added
+}
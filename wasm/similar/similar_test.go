@@ -0,0 +1,67 @@
+package similar
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"slices"
+	"testing"
+)
+
+// testData は 1-2-3 (2→3 は一方向)、4-5 のまとまりと、孤立した 6 からなる単語データです。
+func testData() []objects.Datum {
+	return []objects.Datum{
+		{ID: 1, Word: "affect", SimilarIDs: []int{2}},
+		{ID: 2, Word: "effect", SimilarIDs: []int{1, 3}},
+		{ID: 3, Word: "efficient", SimilarIDs: []int{}},
+		{ID: 4, Word: "adapt", SimilarIDs: []int{5, 99}},
+		{ID: 5, Word: "adopt", SimilarIDs: []int{4, 4}},
+		{ID: 6, Word: "alone", SimilarIDs: []int{6}},
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	g := NewGraph(testData())
+	got := g.Neighbors(1, 2)
+	expected := map[int]int{2: 1, 3: 2}
+	if len(got) != len(expected) || got[2] != 1 || got[3] != 2 {
+		t.Errorf("Neighbors(1, 2) failed: expected %v, got %v", expected, got)
+	}
+	if got := g.Neighbors(1, 1); len(got) != 1 {
+		t.Errorf("Neighbors(1, 1) failed: expected 1 neighbor, got %v", got)
+	}
+	if got := g.Neighbors(6, 3); len(got) != 0 {
+		t.Errorf("Neighbors(6, 3) failed: expected no neighbors, got %v", got)
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	g := NewGraph(testData())
+	if got := g.ShortestPath(3, 1); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf("ShortestPath(3, 1) failed: expected [3 2 1], got %v", got)
+	}
+	if got := g.ShortestPath(1, 4); got != nil {
+		t.Errorf("ShortestPath(1, 4) failed: expected nil, got %v", got)
+	}
+}
+
+func TestClusters(t *testing.T) {
+	g := NewGraph(testData())
+	got := g.Clusters(2)
+	if len(got) != 2 || !slices.Equal(got[0], []int{1, 2, 3}) || !slices.Equal(got[1], []int{4, 5}) {
+		t.Errorf("Clusters(2) failed: expected [[1 2 3] [4 5]], got %v", got)
+	}
+}
+
+func TestAsymmetricLinks(t *testing.T) {
+	data := testData()
+	got := NewGraph(data).AsymmetricLinks()
+	if !slices.Equal(got, []Link{{From: 2, To: 3}}) {
+		t.Fatalf("AsymmetricLinks() failed: expected [{2 3}], got %v", got)
+	}
+	added := FixAsymmetricLinks(data)
+	if !slices.Equal(added, []Link{{From: 3, To: 2}}) {
+		t.Errorf("FixAsymmetricLinks() failed: expected [{3 2}], got %v", added)
+	}
+	if got := NewGraph(data).AsymmetricLinks(); len(got) != 0 {
+		t.Errorf("AsymmetricLinks() after fix failed: expected none, got %v", got)
+	}
+}
@@ -0,0 +1,122 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"english_app_for_japanese/wasm/spelling"
+	"fmt"
+	"syscall/js"
+)
+
+// CreateSpelling はJavaScriptから呼び出され、スペリング (綴りの記述式) の次の問題を準備して返します。
+// 画面には日本語訳を表示し、必要に応じて en を音声で再生します。
+//
+// 引数:
+//   - args[0]: 絞り込み条件 (オブジェクト型、形式は parseFilter を参照) または level (数値型)。
+//     数値の場合の扱いは CreateQuiz と同じです。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{id, en, jp, ee, length, alternates, round}` で解決されます。
+//     en は音声の再生用の見出し語、length は見出し語の文字数、
+//     alternates は正解として扱う別の綴り (イギリス式・アメリカ式) の配列、
+//     round は周回の出題状況 (roundToJS を参照) です。
+//   - 失敗時: エラーメッセージで拒否されます。
+func CreateSpelling(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if appData.Data == nil {
+				reject.Invoke(js.ValueOf("Go関数(CreateSpelling)エラー: appDataが初期化されていません。InitializeAppDataが正常に完了したか確認してください。"))
+				return
+			}
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(CreateSpelling)エラー: 引数は1つ必要です"))
+				return
+			}
+			filter, err := parseFilter(args[0], objects.LevelFilter)
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CreateSpelling)エラー: %v", err)))
+				return
+			}
+			if spellingData.FilteredArray == nil || !spellingData.Filter.Equal(filter) {
+				spellingData.Init(&appData, filter)
+			}
+			spellingData.Next()
+			d := spellingData.CurrentData
+			if d == nil {
+				reject.Invoke(js.ValueOf("Go関数(CreateSpelling)エラー: 次の問題の取得に失敗しました。データがない可能性があります。"))
+				return
+			}
+			resolve.Invoke(map[string]interface{}{
+				"id":         d.ID,
+				"en":         d.Word,
+				"jp":         d.DefinitionJa,
+				"ee":         d.DefinitionEn,
+				"length":     len([]rune(d.Word)),
+				"alternates": toJSStringArray(spelling.Alternates(d.Word)),
+				// 周回の出題状況 (n/total と完了)
+				"round": roundToJS(&spellingData.Round),
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// CheckSpelling はJavaScriptから呼び出され、現在のスペリングの問題に対する解答を採点します。
+// 大文字・小文字と空白の違いは区別せず、イギリス式・アメリカ式の別の綴りも正解として扱います。
+// 問題ごとの最初の解答の正誤は単語の解答記録に記録され、ブラウザの localStorage に保存されます。
+//
+// 引数:
+//   - args[0]: ユーザーが入力した英単語 (文字列型)。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{correct, close, answer, matched, distance, wrong, missing, score}` で解決されます。
+//     close は正解に近い (数文字の違い) 場合に true、matched は解答に最も近かった正解の綴り、
+//     distance はその綴りとの編集距離、wrong は解答の中で誤っている文字の位置の配列、
+//     missing は matched の中で解答に抜けている文字の位置の配列 (いずれも文字単位、0 始まり)、
+//     score は部分点 (0.0〜1.0) です。
+//   - 失敗時: エラーメッセージで拒否されます。
+func CheckSpelling(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(CheckSpelling)エラー: 引数は1つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeString {
+				reject.Invoke(js.ValueOf("Go関数(CheckSpelling)エラー: 引数は文字列型である必要があります"))
+				return
+			}
+			result, ok := spellingData.Check(args[0].String())
+			if !ok {
+				reject.Invoke(js.ValueOf("Go関数(CheckSpelling)エラー: 問題がありません。CreateSpellingを先に呼び出してください。"))
+				return
+			}
+			if errMsg := saveProgress(); errMsg != "" {
+				reject.Invoke(js.ValueOf(errMsg))
+				return
+			}
+			resolve.Invoke(map[string]interface{}{
+				"correct":  result.Correct,
+				"close":    result.Close,
+				"answer":   result.Answer,
+				"matched":  result.Matched,
+				"distance": result.Distance,
+				"wrong":    toJSIntArray(result.Wrong),
+				"missing":  toJSIntArray(result.Missing),
+				"score":    result.Score,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
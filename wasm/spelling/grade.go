@@ -0,0 +1,182 @@
+package spelling
+
+import (
+	"slices"
+	"strings"
+)
+
+// alternatePairs はイギリス英語とアメリカ英語で綴りが異なる単語の {イギリス式, アメリカ式} の一覧です。
+// どちらの綴りで解答しても正解として扱います。
+var alternatePairs = [][2]string{
+	{"colour", "color"}, {"favour", "favor"}, {"favourite", "favorite"}, {"flavour", "flavor"},
+	{"harbour", "harbor"}, {"honour", "honor"}, {"humour", "humor"}, {"labour", "labor"},
+	{"neighbour", "neighbor"}, {"rumour", "rumor"}, {"behaviour", "behavior"}, {"vapour", "vapor"},
+	{"centre", "center"}, {"fibre", "fiber"}, {"litre", "liter"}, {"metre", "meter"},
+	{"theatre", "theater"}, {"kilometre", "kilometer"},
+	{"analyse", "analyze"}, {"paralyse", "paralyze"},
+	{"apologise", "apologize"}, {"realise", "realize"}, {"recognise", "recognize"},
+	{"organise", "organize"}, {"organisation", "organization"}, {"criticise", "criticize"},
+	{"emphasise", "emphasize"}, {"summarise", "summarize"}, {"specialise", "specialize"},
+	{"memorise", "memorize"}, {"minimise", "minimize"}, {"maximise", "maximize"},
+	{"civilisation", "civilization"}, {"globalisation", "globalization"},
+	{"catalogue", "catalog"}, {"dialogue", "dialog"}, {"analogue", "analog"},
+	{"defence", "defense"}, {"licence", "license"}, {"offence", "offense"}, {"pretence", "pretense"},
+	{"travelled", "traveled"}, {"travelling", "traveling"}, {"traveller", "traveler"},
+	{"cancelled", "canceled"}, {"cancelling", "canceling"}, {"labelled", "labeled"},
+	{"modelling", "modeling"}, {"jewellery", "jewelry"}, {"counsellor", "counselor"},
+	{"enrol", "enroll"}, {"fulfil", "fulfill"}, {"skilful", "skillful"}, {"instalment", "installment"},
+	{"ageing", "aging"}, {"judgement", "judgment"}, {"acknowledgement", "acknowledgment"},
+	{"grey", "gray"}, {"programme", "program"},
+	{"aluminium", "aluminum"}, {"plough", "plow"}, {"mould", "mold"}, {"sceptical", "skeptical"},
+	{"pyjamas", "pajamas"}, {"cosy", "cozy"},
+	{"oestrogen", "estrogen"}, {"manoeuvre", "maneuver"}, {"paediatric", "pediatric"},
+	{"encyclopaedia", "encyclopedia"},
+}
+
+// alternates は正規化した綴りから、同じ単語として扱う別の綴りへの対応表です。
+var alternates = make(map[string][]string)
+
+// init は alternatePairs から双方向の alternates を作成します。
+func init() {
+	for _, pair := range alternatePairs {
+		alternates[pair[0]] = append(alternates[pair[0]], pair[1])
+		alternates[pair[1]] = append(alternates[pair[1]], pair[0])
+	}
+}
+
+// Alternates は単語の別の綴り (イギリス式・アメリカ式) を返します。別の綴りがない場合は nil を返します。
+func Alternates(word string) []string {
+	return alternates[Normalize(word)]
+}
+
+// Result は綴りの採点結果です。
+type Result struct {
+	Correct  bool    // 正解の綴り (別の綴りを含む) と一致した場合 true
+	Close    bool    // 不正解だが正解に近い (数文字の違い) 場合 true
+	Input    string  // 正規化した解答
+	Answer   string  // 正解の見出し語
+	Matched  string  // 解答に最も近かった正解の綴り (見出し語または別の綴り、正規化したもの)
+	Distance int     // Matched との編集距離 (挿入・削除・置換・隣接文字の入れ替えを1回と数える)
+	Wrong    []int   // 解答の中で誤っている文字の位置 (rune 単位、0 始まり)
+	Missing  []int   // Matched の中で解答に抜けている文字の位置 (rune 単位、0 始まり)
+	Score    float64 // 部分点 (0.0〜1.0)。正解は 1.0、不正解は Matched の文字数に対する正しい文字の割合
+}
+
+// Normalize は解答を比較用に正規化します。
+// 前後の空白を取り除き、連続する空白を1つにまとめ、小文字化し、
+// タイポグラフィ用のアポストロフィとハイフンを ASCII に揃えます。
+func Normalize(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.ToLower(s)
+	return strings.NewReplacer("’", "'", "‘", "'", "‐", "-", "‑", "-", "–", "-").Replace(s)
+}
+
+// closeThreshold は不正解を Close とみなす編集距離の上限を、正解の文字数から決めます。
+// 4文字以下の単語は1文字、それより長い単語はおよそ4文字に1文字の違いまでを Close とします。
+func closeThreshold(length int) int {
+	return max(1, length/4)
+}
+
+// Grade は解答 input を見出し語 word と比べて採点します。
+// 大文字・小文字と空白の違いは区別せず、word の別の綴り (Alternates) も正解として扱います。
+// 不正解の場合は、最も近い正解の綴りとの編集距離と、誤っている文字の位置を報告します。
+//
+// 引数:
+//   - input: ユーザーが入力した解答。
+//   - word: 正解の見出し語 (例: Datum.Word)。
+//
+// 戻り値:
+//   - 採点結果。
+func Grade(input, word string) Result {
+	in := Normalize(input)
+	answers := append([]string{Normalize(word)}, Alternates(word)...)
+
+	result := Result{Input: in, Answer: word, Distance: -1}
+	for _, answer := range answers {
+		if answer == "" {
+			continue
+		}
+		d := Distance(in, answer)
+		if result.Distance < 0 || d < result.Distance {
+			result.Distance = d
+			result.Matched = answer
+		}
+	}
+	if result.Distance < 0 {
+		// 正解の綴りが空の場合
+		result.Distance = len([]rune(in))
+		return result
+	}
+	if result.Distance == 0 {
+		result.Correct = true
+		result.Score = 1
+		return result
+	}
+	length := len([]rune(result.Matched))
+	result.Wrong, result.Missing = diffPositions(in, result.Matched)
+	result.Close = in != "" && result.Distance <= closeThreshold(length)
+	result.Score = max(0, 1-float64(result.Distance)/float64(length))
+	return result
+}
+
+// Distance は2つの文字列の編集距離 (制限付き Damerau-Levenshtein 距離) を rune 単位で計算します。
+// 挿入・削除・置換・隣接する2文字の入れ替えをそれぞれ1回と数えます。
+func Distance(a, b string) int {
+	return editTable(a, b)[len([]rune(a))][len([]rune(b))]
+}
+
+// editTable は a の先頭 i 文字と b の先頭 j 文字の編集距離を table[i][j] とする表を作成します。
+func editTable(a, b string) [][]int {
+	ra, rb := []rune(a), []rune(b)
+	table := make([][]int, len(ra)+1)
+	for i := range table {
+		table[i] = make([]int, len(rb)+1)
+		table[i][0] = i
+	}
+	for j := range table[0] {
+		table[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			table[i][j] = min(table[i-1][j]+1, table[i][j-1]+1, table[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				table[i][j] = min(table[i][j], table[i-2][j-2]+1)
+			}
+		}
+	}
+	return table
+}
+
+// diffPositions は解答 input を正解 answer に直すための最小の編集をたどり、
+// input の中で誤っている (置換・余分・入れ替えの) 文字の位置と、answer の中で input に抜けている文字の位置を返します。
+// 位置は rune 単位で、昇順に並びます。
+func diffPositions(input, answer string) (wrong, missing []int) {
+	ri, ra := []rune(input), []rune(answer)
+	table := editTable(input, answer)
+	i, j := len(ri), len(ra)
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && ri[i-1] == ra[j-1] && table[i][j] == table[i-1][j-1]:
+			i, j = i-1, j-1
+		case i > 1 && j > 1 && ri[i-1] == ra[j-2] && ri[i-2] == ra[j-1] && table[i][j] == table[i-2][j-2]+1:
+			wrong = append(wrong, i-1, i-2)
+			i, j = i-2, j-2
+		case i > 0 && j > 0 && table[i][j] == table[i-1][j-1]+1:
+			wrong = append(wrong, i-1)
+			i, j = i-1, j-1
+		case i > 0 && table[i][j] == table[i-1][j]+1:
+			wrong = append(wrong, i-1)
+			i--
+		default:
+			missing = append(missing, j-1)
+			j--
+		}
+	}
+	slices.Reverse(wrong)
+	slices.Reverse(missing)
+	return wrong, missing
+}
@@ -0,0 +1,72 @@
+package spelling
+
+import "english_app_for_japanese/wasm/objects"
+
+// Spelling はスペリング (綴りの記述式) 学習モードのデータと状態を管理する構造体です。
+// 日本語訳 (または単語の音声) を出題し、ユーザーが入力した英単語を Grade で採点します。
+type Spelling struct {
+	appData       *objects.AppData // アプリケーション全体のデータへのポインタ
+	FilteredArray []objects.Datum  // フィルタリングおよびシャッフルされた問題データのスライス
+	Filter        objects.Filter   // 現在選択されている問題の絞り込み条件
+	Round         objects.Round    // 現在の周回の出題状況
+	CurrentData   *objects.Datum   // 現在出題中の問題データへのポインタ
+	answered      bool             // 現在の問題の解答を解答記録に記録済みかどうか
+}
+
+// Init は Spelling 構造体を初期化します。
+// 指定された絞り込み条件に基づいて、アプリケーションデータから問題をフィルタリングし、
+// 並び替えて (既定ではシャッフルして) 内部の FilteredArray に格納します。
+//
+// 引数:
+//   - appData: アプリケーション全体のデータ (objects.AppData) へのポインタ。
+//   - filter: 問題の絞り込み条件。
+func (s *Spelling) Init(appData *objects.AppData, filter objects.Filter) {
+	s.appData = appData
+	s.Filter = filter
+	s.FilteredArray = s.appData.Query(s.Filter)
+	s.CurrentData = nil
+	// 周回を初期化
+	s.Round = objects.Round{}
+}
+
+// Next は次の問題に進みます。
+// 問題は周回 (Round) 単位で出題し、現在の周回の次の問題を CurrentData に設定します。
+// 周回の問題をすべて出題し終えている場合は、絞り込み条件で取り出し直して新しい周回を開始しますが、
+// Round.MissedOnly が true の場合は前の周回で間違えた問題のみにします。
+func (s *Spelling) Next() {
+	s.answered = false
+	// 最初の周回は Init で並び替えた順に出題し、以降は取り出し直した問題で新しい周回を始める
+	if !s.Round.Continue(nil, s.FilteredArray, func() []objects.Datum {
+		s.FilteredArray = s.appData.Query(s.Filter)
+		return s.FilteredArray
+	}) {
+		s.CurrentData = nil
+		return
+	}
+	s.CurrentData = s.Round.Next()
+}
+
+// Check は現在の問題に対する解答を採点します。
+// 問題ごとの最初の解答の正誤を単語の解答記録 (AppData.Progress) に記録し、
+// 間違えた問題は次の周回で復習できるように周回 (Round) にも記録します。
+// 同じ問題で解答し直した場合、採点はしますが記録はしません。
+//
+// 引数:
+//   - input: ユーザーが入力した英単語。
+//
+// 戻り値:
+//   - 採点結果。問題が設定されていない場合は false。
+func (s *Spelling) Check(input string) (Result, bool) {
+	if s.CurrentData == nil {
+		return Result{}, false
+	}
+	result := Grade(input, s.CurrentData.Word)
+	if !s.answered {
+		s.appData.RecordAnswer(s.CurrentData.ID, result.Correct)
+		if !result.Correct {
+			s.Round.MarkMissed(*s.CurrentData)
+		}
+		s.answered = true
+	}
+	return result, true
+}
@@ -0,0 +1,90 @@
+package spelling
+
+import (
+	"english_app_for_japanese/wasm/objects"
+	"slices"
+	"testing"
+)
+
+func TestGrade(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		word     string
+		correct  bool
+		close    bool
+		distance int
+		wrong    []int
+		missing  []int
+	}{
+		{"Exact", "apple", "apple", true, false, 0, nil, nil},
+		{"Case and whitespace", "  Ice   Cream ", "ice cream", true, false, 0, nil, nil},
+		{"British spelling", "colour", "color", true, false, 0, nil, nil},
+		{"American spelling", "center", "centre", true, false, 0, nil, nil},
+		{"Substitution", "aplpe", "apple", false, true, 1, []int{2, 3}, nil},
+		{"Missing letter", "appe", "apple", false, true, 1, nil, []int{3}},
+		{"Extra letter", "apxple", "apple", false, true, 1, []int{2}, nil},
+		{"Wrong letter", "bpple", "apple", false, true, 1, []int{0}, nil},
+		{"Far", "banana", "apple", false, false, 5, nil, nil},
+		{"Empty", "", "apple", false, false, 5, nil, []int{0, 1, 2, 3, 4}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Grade(tc.input, tc.word)
+			if got.Correct != tc.correct || got.Close != tc.close || got.Distance != tc.distance {
+				t.Fatalf("Grade(%q, %q) failed: got %+v", tc.input, tc.word, got)
+			}
+			if tc.wrong != nil && !slices.Equal(got.Wrong, tc.wrong) {
+				t.Errorf("Grade(%q, %q) wrong positions: expected %v, got %v", tc.input, tc.word, tc.wrong, got.Wrong)
+			}
+			if tc.missing != nil && !slices.Equal(got.Missing, tc.missing) {
+				t.Errorf("Grade(%q, %q) missing positions: expected %v, got %v", tc.input, tc.word, tc.missing, got.Missing)
+			}
+			if tc.correct && got.Score != 1 {
+				t.Errorf("Grade(%q, %q) score: expected 1, got %v", tc.input, tc.word, got.Score)
+			}
+		})
+	}
+}
+
+func TestSpellingCheckRecordsProgress(t *testing.T) {
+	appData := &objects.AppData{}
+	appData.AddData(objects.Datum{ID: 1, Word: "apple", DefinitionJa: "りんご"})
+
+	var s Spelling
+	s.Init(appData, objects.Filter{Sort: objects.SortID})
+	s.Next()
+	if result, ok := s.Check("aple"); !ok || result.Correct {
+		t.Fatalf("expected an incorrect answer, got %+v", result)
+	}
+	// 解答し直しは記録しない
+	if result, ok := s.Check("apple"); !ok || !result.Correct {
+		t.Fatalf("expected a correct answer, got %+v", result)
+	}
+	if p := appData.Progress[1]; p.Correct != 0 || p.Incorrect != 1 {
+		t.Errorf("expected progress {0 1}, got %+v", p)
+	}
+}
+
+func TestSpellingRounds(t *testing.T) {
+	appData := &objects.AppData{}
+	appData.AddData(objects.Datum{ID: 1, Word: "apple", DefinitionJa: "りんご"})
+	appData.AddData(objects.Datum{ID: 2, Word: "banana", DefinitionJa: "バナナ"})
+
+	var s Spelling
+	s.Init(appData, objects.Filter{Sort: objects.SortID})
+	s.Round.MissedOnly = true
+	// 1周目: 2問を1回ずつ出題し、banana だけ間違える
+	for i, answer := range []string{"apple", "banan"} {
+		s.Next()
+		if s.CurrentData == nil || s.CurrentData.ID != i+1 || s.Round.Number != 1 {
+			t.Fatalf("question %d: unexpected %v in round %d", i+1, s.CurrentData, s.Round.Number)
+		}
+		s.Check(answer)
+	}
+	// 2周目: 間違えた banana のみ
+	s.Next()
+	if s.Round.Number != 2 || s.Round.Total() != 1 || s.CurrentData.ID != 2 {
+		t.Errorf("expected round 2 with only banana, got round %d with %d questions (%v)", s.Round.Number, s.Round.Total(), s.CurrentData)
+	}
+}
@@ -4,6 +4,9 @@ package main
 
 import (
 	"encoding/json"
+	"english_app_for_japanese/wasm/objects"
+	"english_app_for_japanese/wasm/quiz"
+	"english_app_for_japanese/wasm/typing"
 	"fmt"
 	"syscall/js"
 )
@@ -12,7 +15,7 @@ import (
 // エラーが発生した場合はエラーメッセージを返します。
 func saveLocalStorage() string {
 	localStorage := js.Global().Get("localStorage")
-	jsonData, err := json.Marshal(appData.LocalStorage)
+	jsonData, err := json.Marshal(appData.StorageIDs())
 	if err != nil {
 		errMsg := fmt.Sprintf("Go関数(saveLocalStorage)エラー: ローカルストレージデータのJSONエンコード失敗: %v", err)
 		consoleLog.Invoke(errMsg)
@@ -23,6 +26,184 @@ func saveLocalStorage() string {
 	return "" // エラーなし
 }
 
+// saveProgress は appData.Progress (単語ごとの解答記録) と appData.LevelHistory (レベルごとの直近の解答結果) を
+// ブラウザの localStorage に保存します。
+// エラーが発生した場合はエラーメッセージを返します。
+func saveProgress() string {
+	localStorage := js.Global().Get("localStorage")
+	for key, value := range map[string]any{
+		progressStorageKey:     appData.Progress,
+		levelHistoryStorageKey: appData.LevelHistory,
+	} {
+		jsonData, err := json.Marshal(value)
+		if err != nil {
+			errMsg := fmt.Sprintf("Go関数(saveProgress)エラー: 解答記録のJSONエンコード失敗: %v", err)
+			consoleLog.Invoke(errMsg)
+			return errMsg
+		}
+		localStorage.Call("setItem", key, string(jsonData))
+	}
+	return ""
+}
+
+// loadProgress はブラウザの localStorage から単語ごとの解答記録とレベルごとの直近の解答結果を読み込み、
+// appData.Progress と appData.LevelHistory に設定します。
+// 現在のデータセットに存在しないIDの記録は読み込みません。
+// エラーが発生した場合はエラーメッセージを返します。
+func loadProgress() string {
+	localStorage := js.Global().Get("localStorage")
+	if storedValueJS := localStorage.Call("getItem", progressStorageKey); !storedValueJS.IsNull() && !storedValueJS.IsUndefined() {
+		var loaded map[int]objects.WordProgress
+		if err := json.Unmarshal([]byte(storedValueJS.String()), &loaded); err != nil {
+			errMsg := fmt.Sprintf("Go関数(loadProgress)エラー: 解答記録のJSONデコード失敗: %v", err)
+			consoleLog.Invoke(errMsg)
+			return errMsg
+		}
+		appData.Progress = make(map[int]objects.WordProgress, len(loaded))
+		for id, p := range loaded {
+			if appData.HasID(id) {
+				appData.Progress[id] = p
+			}
+		}
+		consoleLog.Invoke(js.ValueOf(fmt.Sprintf("Go関数(loadProgress): %d 件の単語の解答記録をロードしました。", len(appData.Progress))))
+	}
+
+	if historyJS := localStorage.Call("getItem", levelHistoryStorageKey); !historyJS.IsNull() && !historyJS.IsUndefined() {
+		if err := json.Unmarshal([]byte(historyJS.String()), &appData.LevelHistory); err != nil {
+			errMsg := fmt.Sprintf("Go関数(loadProgress)エラー: レベルごとの解答結果のJSONデコード失敗: %v", err)
+			consoleLog.Invoke(errMsg)
+			return errMsg
+		}
+	}
+	return ""
+}
+
+// saveDailyHistory は dailyHistory (デイリーチャレンジの結果の履歴) をブラウザの localStorage に保存します。
+// エラーが発生した場合はエラーメッセージを返します。
+func saveDailyHistory() string {
+	jsonData, err := json.Marshal(dailyHistory)
+	if err != nil {
+		errMsg := fmt.Sprintf("Go関数(saveDailyHistory)エラー: デイリーチャレンジの履歴のJSONエンコード失敗: %v", err)
+		consoleLog.Invoke(errMsg)
+		return errMsg
+	}
+	js.Global().Get("localStorage").Call("setItem", dailyHistoryStorageKey, string(jsonData))
+	return ""
+}
+
+// loadDailyHistory はブラウザの localStorage からデイリーチャレンジの結果の履歴を読み込み、dailyHistory に設定します。
+// エラーが発生した場合はエラーメッセージを返します。
+func loadDailyHistory() string {
+	dailyHistory = make(quiz.DailyHistory)
+	storedValueJS := js.Global().Get("localStorage").Call("getItem", dailyHistoryStorageKey)
+	if storedValueJS.IsNull() || storedValueJS.IsUndefined() {
+		return ""
+	}
+	if err := json.Unmarshal([]byte(storedValueJS.String()), &dailyHistory); err != nil {
+		errMsg := fmt.Sprintf("Go関数(loadDailyHistory)エラー: デイリーチャレンジの履歴のJSONデコード失敗: %v", err)
+		consoleLog.Invoke(errMsg)
+		return errMsg
+	}
+	return ""
+}
+
+// saveTypingSettings は typingConfig (タイピングの設定) をブラウザの localStorage に保存します。
+// エラーが発生した場合はエラーメッセージを返します。
+func saveTypingSettings() string {
+	jsonData, err := json.Marshal(typingConfig)
+	if err != nil {
+		errMsg := fmt.Sprintf("Go関数(saveTypingSettings)エラー: タイピングの設定のJSONエンコード失敗: %v", err)
+		consoleLog.Invoke(errMsg)
+		return errMsg
+	}
+	js.Global().Get("localStorage").Call("setItem", typingSettingsStorageKey, string(jsonData))
+	return ""
+}
+
+// loadTypingSettings はブラウザの localStorage からタイピングの設定を読み込み、typingData に適用します。
+// 保存されている対応表が読み込めない場合 (プリセットの名前が無効な場合など) は、ログを出力して標準の対応表を使用します。
+// JSONのデコードに失敗した場合はエラーメッセージを返します。
+func loadTypingSettings() string {
+	typingConfig = typingSettings{Table: typing.PresetStandard}
+	typingData.Table = nil
+	storedValueJS := js.Global().Get("localStorage").Call("getItem", typingSettingsStorageKey)
+	if storedValueJS.IsNull() || storedValueJS.IsUndefined() {
+		return ""
+	}
+	var settings typingSettings
+	if err := json.Unmarshal([]byte(storedValueJS.String()), &settings); err != nil {
+		errMsg := fmt.Sprintf("Go関数(loadTypingSettings)エラー: タイピングの設定のJSONデコード失敗: %v", err)
+		consoleLog.Invoke(errMsg)
+		return errMsg
+	}
+	if _, err := applyTypingSettings(settings); err != nil {
+		consoleLog.Invoke(fmt.Sprintf("Go関数(loadTypingSettings): 保存されている対応表を読み込めないため標準の対応表を使用します: %v", err))
+	}
+	return ""
+}
+
+// saveTypingHistory は typingHistory (タイピングのセッションの結果の履歴と自己ベスト) をブラウザの localStorage に保存します。
+// エラーが発生した場合はエラーメッセージを返します。
+func saveTypingHistory() string {
+	jsonData, err := json.Marshal(typingHistory)
+	if err != nil {
+		errMsg := fmt.Sprintf("Go関数(saveTypingHistory)エラー: タイピングの履歴のJSONエンコード失敗: %v", err)
+		consoleLog.Invoke(errMsg)
+		return errMsg
+	}
+	js.Global().Get("localStorage").Call("setItem", typingHistoryStorageKey, string(jsonData))
+	return ""
+}
+
+// loadTypingHistory はブラウザの localStorage からタイピングのセッションの結果の履歴を読み込み、typingHistory に設定します。
+// 履歴が読み込めない場合は、アプリの起動を止めないよう、ログを出力して空の履歴から始めます。
+func loadTypingHistory() {
+	typingHistory = typing.History{}
+	storedValueJS := js.Global().Get("localStorage").Call("getItem", typingHistoryStorageKey)
+	if storedValueJS.IsNull() || storedValueJS.IsUndefined() {
+		return
+	}
+	if err := json.Unmarshal([]byte(storedValueJS.String()), &typingHistory); err != nil {
+		consoleLog.Invoke(fmt.Sprintf("Go関数(loadTypingHistory): タイピングの履歴を読み込めないため空の履歴から始めます: %v", err))
+		typingHistory = typing.History{}
+	}
+}
+
+// saveTypingGhosts は typingGhosts (問題文ごとの自己ベストのキー入力の記録) をブラウザの localStorage に保存します。
+// エラーが発生した場合はエラーメッセージを返します。
+func saveTypingGhosts() string {
+	jsonData, err := json.Marshal(typingGhosts)
+	if err != nil {
+		errMsg := fmt.Sprintf("Go関数(saveTypingGhosts)エラー: ゴーストのJSONエンコード失敗: %v", err)
+		consoleLog.Invoke(errMsg)
+		return errMsg
+	}
+	js.Global().Get("localStorage").Call("setItem", typingGhostsStorageKey, string(jsonData))
+	return ""
+}
+
+// loadTypingGhosts はブラウザの localStorage から問題文ごとの自己ベストのキー入力の記録を読み込み、
+// typingGhosts と typingData.Ghosts に設定します。記録は typing.ParseGhosts で検証し、不正な記録は取り除きます。
+// 読み込めない場合は、アプリの起動を止めないよう、ログを出力してゴーストなしで始めます。
+func loadTypingGhosts() {
+	typingGhosts = make(typing.Ghosts)
+	typingData.Ghosts = typingGhosts
+	storedValueJS := js.Global().Get("localStorage").Call("getItem", typingGhostsStorageKey)
+	if storedValueJS.IsNull() || storedValueJS.IsUndefined() {
+		return
+	}
+	ghosts, dropped, err := typing.ParseGhosts(storedValueJS.String())
+	if err != nil {
+		consoleLog.Invoke(fmt.Sprintf("Go関数(loadTypingGhosts): ゴーストを読み込めないためゴーストなしで始めます: %v", err))
+		return
+	}
+	if dropped > 0 {
+		consoleLog.Invoke(fmt.Sprintf("Go関数(loadTypingGhosts): 不正なゴーストの記録を %d 件取り除きました。", dropped))
+	}
+	typingGhosts = ghosts
+	typingData.Ghosts = typingGhosts
+}
+
 // SetStorage はブラウザの localStorage データをappData.LocalStorageに保存します。
 // ブラウザの localStorageにインポートした後に使用する想定。
 func SetStorage(this js.Value, args []js.Value) any {
@@ -50,19 +231,13 @@ func SetStorage(this js.Value, args []js.Value) any {
 					return
 				}
 
-				// appData.Data に存在するIDを効率的に検索するためのセットを作成
-				validDataIDs := make(map[int]struct{}, len(appData.Data))
-				for _, datum := range appData.Data {
-					validDataIDs[datum.ID] = struct{}{}
-				}
-
 				// 既存のLocalStorageをクリアし、有効なIDのみを追加する
-				appData.LocalStorage = make([]int, 0)
+				appData.ClearStorage()
 				addedCount := 0
 				skippedCount := 0
 				for _, id := range loadedStorage {
-					// appData.Data に ID が存在するか確認
-					if _, exists := validDataIDs[id]; exists {
+					// appData.Data に ID が存在するか確認 (IDの索引を使用)
+					if appData.HasID(id) {
 						// 存在する場合のみ追加 (AddStorageは重複チェックを行う)
 						appData.AddStorage(id)
 						addedCount++
@@ -79,7 +254,7 @@ func SetStorage(this js.Value, args []js.Value) any {
 				consoleLog.Invoke(js.ValueOf(logMsg))
 
 				// 重複のないデータをローカルストレージに代入
-				jsonData, err := json.Marshal(appData.LocalStorage)
+				jsonData, err := json.Marshal(appData.StorageIDs())
 				if err != nil {
 					errMsg := fmt.Sprintf("Go関数(SetStorage)エラー: ローカルストレージデータのJSONエンコード失敗: %v", err)
 					consoleLog.Invoke(errMsg)
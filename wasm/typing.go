@@ -3,23 +3,76 @@
 package main
 
 import (
+	"encoding/json"
+	"english_app_for_japanese/wasm/objects"
+	"english_app_for_japanese/wasm/typing"
+	"errors"
+	"fmt"
 	"syscall/js"
+	"time"
 )
 
+// customRomajiTable は TSV から読み込んだ対応表を表す typingSettings.Table の値です。
+const customRomajiTable = "custom"
+
+// typingSettings は localStorage に保存するタイピングの設定です。
+type typingSettings struct {
+	Table string `json:"table"`         // 対応表のプリセットの名前 (typing.PresetStandard など) または customRomajiTable
+	TSV   string `json:"tsv,omitempty"` // Table が customRomajiTable の場合の TSV 形式のローマ字テーブル
+}
+
+// applyTypingSettings はタイピングの設定の対応表を読み込み、typingData と typingConfig に設定します。
+// 読み込みに失敗した場合は typingData と typingConfig を変更しません。
+//
+// 戻り値:
+//   - TSV から読み込んだ場合に読み飛ばしたエントリの数。
+//   - プリセットの名前が無効な場合や TSV の形式が正しくない場合はエラー。
+func applyTypingSettings(settings typingSettings) (int, error) {
+	var table typing.RomajiTable
+	skipped := 0
+	if settings.Table == customRomajiTable {
+		var err error
+		table, skipped, err = typing.ParseRomajiTable(settings.TSV)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		var ok bool
+		if table, ok = typing.Preset(settings.Table); !ok {
+			return 0, errors.New("対応表のプリセットは \"standard\"、\"azik\"、\"kana\" のいずれかである必要があります")
+		}
+		settings.TSV = ""
+	}
+	typingData.Table = table
+	typingConfig = settings
+	return skipped, nil
+}
+
 // CreateTyping はJavaScriptから呼び出され、タイピングゲームで使用する単語データを初期化します。
-// アプリケーションデータ (appData) をシャッフルし、タイピング用のデータセット (typingData.FilteredArray) を準備します。
+// アプリケーションデータ (appData) を絞り込んでシャッフルし、タイピング用のデータセット (typingData.FilteredArray) を準備します。
 //
 // 引数:
-//   - なし (args は使用されません)
+//   - args[0]: 絞り込み条件 (オブジェクト型、形式は parseFilter を参照) または level (数値型)。省略可能。
+//     省略した場合はアプリケーションデータ全体を対象にします。
+//   - args[1]: options (オブジェクト型、省略可能) - 次のキーを指定できます。
+//   - seed: 0 以上の整数。指定すると出題順が毎回同じになります。
+//   - romaji: ローマ字のガイドで優先する綴りの方式 ("hepburn" または "kunrei"、既定値は "hepburn")。
+//   - practice: 入力する文の種類。"sentence" (例文、既定値) または "word" (見出し語と、かなのみの場合は日本語の意味)。
+//   - minLength, maxLength: 入力する英語 (例文または見出し語) の文字数の範囲。
+//     絞り込み条件 (args[0]) の minLength, maxLength は見出し語の文字数である点に注意してください。
+//   - ignoreCase: true の場合、英語の大文字と小文字を区別しません。
+//   - skipPunctuation: true の場合、英語の句読点 (ピリオド、カンマ、引用符、ダッシュなど) を入力せずに飛ばします。
+//     設定に関係なく、英語の曲がった引用符、ダッシュ、三点リーダーは ASCII の ' " - ... でも入力できます。
 //
 // 戻り値:
 //   - JavaScriptのPromiseオブジェクト。
-//   - 成功時: 初期化されたタイピングデータの総数 (int) で解決されます。
+//   - 成功時: 初期化されたタイピングデータの総数 (int) で解決されます。条件に一致するデータがない場合は 0 です。
 //   - 失敗時: エラーメッセージ (string) で拒否されます。
 //
 // 処理内容:
 //  1. appDataが初期化されているか確認します。
-//  2. typingData.Init(&appData) を呼び出し、appData.DataをシャッフルしてtypingData.FilteredArrayに格納します。
+//  2. typingData.Init(&appData, filter) を呼び出し、絞り込んだデータをシャッフルしてtypingData.FilteredArrayに格納します。
+//     入力する英語がないデータと、文字数が範囲外のデータは除きます。
 //  3. FilteredArrayの要素数をPromiseのresolve関数に渡して返します。
 //  4. エラーが発生した場合は、Promiseのreject関数にエラーメッセージを渡します。
 func CreateTyping(this js.Value, args []js.Value) any {
@@ -31,7 +84,32 @@ func CreateTyping(this js.Value, args []js.Value) any {
 				reject.Invoke(js.ValueOf("Go関数(CreateTyping)エラー: appDataが初期化されていません。CreateObjectを先に呼び出してください。"))
 				return
 			}
-			typingData.Init(&appData)
+			// 引数がない場合は従来どおりアプリケーションデータ全体を対象にする
+			filter := objects.Filter{Excluded: objects.ExcludedAll}
+			if len(args) > 0 {
+				var err error
+				filter, err = parseFilter(args[0], objects.LevelFilter)
+				if err != nil {
+					reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CreateTyping)エラー: %v", err)))
+					return
+				}
+			}
+			var options typingOptions
+			if len(args) > 1 {
+				var err error
+				options, err = parseTypingOptions(args[1])
+				if err != nil {
+					reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(CreateTyping)エラー: %v", err)))
+					return
+				}
+			}
+			typingData.Seed = options.seed
+			typingData.Style = options.style
+			typingData.Practice = options.practice
+			typingData.MinLength = options.minLength
+			typingData.MaxLength = options.maxLength
+			typingData.English = options.english
+			typingData.Init(&appData, filter)
 			resolve.Invoke(len(typingData.FilteredArray))
 		}()
 		return nil
@@ -40,6 +118,72 @@ func CreateTyping(this js.Value, args []js.Value) any {
 	return promiseConstructor.New(handler)
 }
 
+// typingOptions は CreateTyping の options 引数を解析した結果です。
+type typingOptions struct {
+	seed      *uint64
+	style     typing.RomajiStyle
+	practice  typing.Practice
+	minLength int
+	maxLength int
+	english   typing.EnglishOptions
+}
+
+// parseTypingOptions は CreateTyping の options 引数 (オブジェクト型、省略可能) を解析します。
+// 省略した場合や、キーを省略した場合は既定値 (シードなし、ヘボン式、例文、文字数の制限なし、英語は厳密に判定) を使用します。
+func parseTypingOptions(value js.Value) (typingOptions, error) {
+	options := typingOptions{style: typing.StyleHepburn, practice: typing.PracticeSentence}
+	if value.IsUndefined() || value.IsNull() {
+		return options, nil
+	}
+	if value.Type() != js.TypeObject {
+		return options, errors.New("引数1はオブジェクトである必要があります")
+	}
+	var err error
+	if options.seed, err = getSeed(value, "seed"); err != nil {
+		return options, err
+	}
+	style, err := getString(value, "romaji")
+	if err != nil {
+		return options, err
+	}
+	if style != "" {
+		if !typing.IsValidStyle(style) {
+			return options, fmt.Errorf("romajiは\"%s\"か\"%s\"である必要があります", typing.StyleHepburn, typing.StyleKunrei)
+		}
+		options.style = typing.RomajiStyle(style)
+	}
+	practice, err := getString(value, "practice")
+	if err != nil {
+		return options, err
+	}
+	if practice != "" {
+		if !typing.IsValidPractice(practice) {
+			return options, fmt.Errorf("practiceは\"%s\"か\"%s\"である必要があります", typing.PracticeSentence, typing.PracticeWord)
+		}
+		options.practice = typing.Practice(practice)
+	}
+	for key, dst := range map[string]*int{
+		"minLength": &options.minLength,
+		"maxLength": &options.maxLength,
+	} {
+		v := value.Get(key)
+		if v.IsUndefined() || v.IsNull() {
+			continue
+		}
+		if v.Type() != js.TypeNumber || v.Int() < 0 {
+			return options, fmt.Errorf("%s は 0 以上の数値である必要があります", key)
+		}
+		*dst = v.Int()
+	}
+	if options.english.IgnoreCase, err = getBool(value, "ignoreCase"); err != nil {
+		return options, err
+	}
+	if options.english.SkipPunctuation, err = getBool(value, "skipPunctuation"); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
 // GetTypingQuestion はJavaScriptから呼び出され、指定されたインデックスに対応する
 // タイピングの問題文（英語と日本語）を取得します。
 // 内部で、指定されたインデックスのデータを typingData.CurrentData に設定し、
@@ -51,6 +195,7 @@ func CreateTyping(this js.Value, args []js.Value) any {
 // 戻り値:
 //   - JavaScriptのPromiseオブジェクト。
 //   - 成功時: 問題文の英語 (`en2`) と日本語 (`jp2`) を含むJavaScriptオブジェクト (`{en2, jp2}`) で解決されます。
+//     単語の練習 (CreateTyping の practice が "word") では見出し語と日本語の意味です。
 //   - 失敗時: エラーメッセージ (string) で拒否されます。
 //
 // 処理内容:
@@ -70,6 +215,10 @@ func GetTypingQuestion(this js.Value, args []js.Value) any {
 				reject.Invoke(js.ValueOf("Go関数(GetTypingQuestion)エラー: typingDataが初期化されていません。CreateTypingを先に呼び出してください。"))
 				return
 			}
+			if len(typingData.FilteredArray) == 0 {
+				reject.Invoke(js.ValueOf("Go関数(GetTypingQuestion)エラー: 条件に一致する問題がありません。"))
+				return
+			}
 			if len(args) != 1 {
 				reject.Invoke(js.ValueOf("Go関数(GetTypingQuestion)エラー: 引数は1つ必要です"))
 				return
@@ -86,9 +235,10 @@ func GetTypingQuestion(this js.Value, args []js.Value) any {
 				return
 			}
 			// 結果をJavaScriptのオブジェクトとして返す
+			en, ja := typingData.Question()
 			result := map[string]interface{}{
-				"en2": typingData.CurrentData.ExampleEn,
-				"jp2": typingData.CurrentData.ExampleJa,
+				"en2": en,
+				"jp2": ja,
 			}
 			resolve.Invoke(result)
 		}()
@@ -160,31 +310,32 @@ func GetTypingQuestionSlice(this js.Value, args []js.Value) any {
 	return promiseConstructor.New(handler)
 }
 
-// TypingKeyDown はJavaScriptから呼び出され、ユーザーのキー入力を受け取り、
-// 現在のタイピング問題と比較して、正解であれば次の文字に進むための新しいインデックスを返します。
+// TypingKeyDown はJavaScriptから呼び出され、ユーザーのキー入力を1つ受け取り、現在のタイピング問題の入力を進めます。
+// 入力の状態 (入力済みのキー) はGo側で問題ごとに保持するため、JavaScript側で入力を連結する必要はありません。
 // ローマ字入力の判定（促音「っ」や撥音「ん」の特殊処理を含む）を行います。
 //
 // 引数:
-//   - args[0]: ユーザーが入力した現在の文字列全体 (文字列型)。
-//   - args[1]: 現在判定対象となっている文字のインデックス (数値型)。
-//   - args[2]: モード (数値型)。
+//   - args[0]: 入力されたキー (文字列型、KeyboardEvent.key の1文字)。
+//   - args[1]: モード (数値型)。
 //   - 1: 英語の問題文 (typingData.CurrentDataArrayE) に対して判定します。
 //   - 2: 日本語（かな）の問題文 (typingData.CurrentDataArrayJ) に対して判定します。
+//   - args[2]: キーを入力した時刻 (数値型、ミリ秒、省略可能)。KeyboardEvent.timeStamp を渡します。
+//     問題ごとの最初のキー入力から入力が完了するまでの時間を CPM などの計算に使用します (GetTypingStats を参照)。
 //
 // 戻り値:
 //   - JavaScriptのPromiseオブジェクト。
-//   - 成功時: 判定後の新しい文字インデックス (int) で解決されます。
-//   - 入力が正しく、次の文字に進む場合は `index + 1` または `index + 2` (促音の場合など)。
-//   - 入力が不正解、またはまだ文字入力が完了していない場合は現在の `index`。
+//   - 成功時: `{index, status, mistake, expected, validKeys, buffer, done, guide, personalBest, replay}` で解決されます。
+//     index は判定後の文字インデックス (入力が完了した文字の数)、
+//     status は "accepted" (文字の入力が完了した)、"pending" (入力途中)、"rejected" (誤ったキー) のいずれか、
+//     mistake は誤ったキーを入力した場合に true、expected はこのキーの入力前に入力できたキーの配列、
+//     validKeys は次に入力できるキーの配列、buffer は現在の文字に対して入力済みのキー、
+//     done は問題文の最後の文字まで入力が完了した場合に true、guide は判定後の入力のガイド (GetTypingGuide を参照) です。
+//     キー入力はセッションの記録に追加されます (GetTypingSessionStats を参照)。
+//     done が true の場合はセッションの結果を履歴に保存し、personalBest に自己ベストを更新したモード ("en" または "jp") の配列を設定します。
+//     英語と日本語の両方の入力が完了した場合は、キー入力の記録をゴーストと比較し、ゴーストより速ければゴーストとして保存して、
+//     replay に `{newGhost, comparison}` を設定します (それ以外は null)。newGhost はゴーストを更新した場合に true、
+//     comparison はゴーストとの比較 (comparisonToJS を参照、ゴーストがなかった場合は null) です。
 //   - 失敗時: エラーメッセージ (string) で拒否されます。
-//
-// 処理内容:
-//  1. typingDataの文字配列 (CurrentDataArrayE, CurrentDataArrayJ) が初期化されているか確認します。
-//  2. 引数の数と型を検証します。
-//  3. 引数からユーザー入力、現在のインデックス、モードを取得します。
-//  4. typingData.KeyDown(userInput, index, mode) を呼び出し、入力判定ロジックを実行します。
-//  5. KeyDown関数から返された新しいインデックスをPromiseのresolve関数に渡して返します。
-//  6. エラーが発生した場合は、Promiseのreject関数にエラーメッセージを渡します。
 func TypingKeyDown(this js.Value, args []js.Value) any {
 	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
 		resolve := promiseArgs[0]
@@ -194,8 +345,8 @@ func TypingKeyDown(this js.Value, args []js.Value) any {
 				reject.Invoke(js.ValueOf("Go関数(TypingKeyDown)エラー: typingDataが初期化されていません。GetTypingQuestionを先に呼び出してください。"))
 				return
 			}
-			if len(args) != 3 {
-				reject.Invoke(js.ValueOf("Go関数(TypingKeyDown)エラー: 引数は3つ必要です"))
+			if len(args) != 2 && len(args) != 3 {
+				reject.Invoke(js.ValueOf("Go関数(TypingKeyDown)エラー: 引数は2つまたは3つ必要です"))
 				return
 			}
 			if args[0].Type() != js.TypeString {
@@ -206,18 +357,536 @@ func TypingKeyDown(this js.Value, args []js.Value) any {
 				reject.Invoke(js.ValueOf("Go関数(TypingKeyDown)エラー: 引数1は数値型である必要があります"))
 				return
 			}
-			if args[2].Type() != js.TypeNumber {
-				reject.Invoke(js.ValueOf("Go関数(TypingKeyDown)エラー: 引数2は数値型である必要があります"))
+			mode := args[1].Int()
+			if typingData.Input(mode) == nil {
+				reject.Invoke(js.ValueOf("Go関数(TypingKeyDown)エラー: 引数1は1か2である必要があります"))
+				return
+			}
+			var at time.Duration
+			if len(args) == 3 {
+				if args[2].Type() != js.TypeNumber {
+					reject.Invoke(js.ValueOf("Go関数(TypingKeyDown)エラー: 引数2は数値型である必要があります"))
+					return
+				}
+				at = time.Duration(args[2].Float() * float64(time.Millisecond))
+			}
+			// typingパッケージのKeyDownAt関数を呼び出して判定する
+			result := typingData.KeyDownAt(args[0].String(), mode, at)
+			personalBest := []interface{}{}
+			var replay interface{}
+			if result.Done {
+				if session := typingData.Recorder.Session(); session.Questions > 0 {
+					personalBest = toJSStringArray(typingHistory.Record(session))
+					if errMsg := saveTypingHistory(); errMsg != "" {
+						reject.Invoke(js.ValueOf(errMsg))
+						return
+					}
+				}
+				if recording := typingData.Recording; recording.Done() {
+					var comparison interface{}
+					if typingData.Ghost != nil {
+						comparison = comparisonToJS(recording.Compare(*typingData.Ghost))
+					}
+					newGhost, err := typingGhosts.Record(recording)
+					if err != nil {
+						reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(TypingKeyDown)エラー: %v", err)))
+						return
+					}
+					if newGhost {
+						if errMsg := saveTypingGhosts(); errMsg != "" {
+							reject.Invoke(js.ValueOf(errMsg))
+							return
+						}
+					}
+					replay = map[string]interface{}{
+						"newGhost":   newGhost,
+						"comparison": comparison,
+					}
+				}
+			}
+			resolve.Invoke(map[string]interface{}{
+				"index":        result.Index,
+				"status":       string(result.Status),
+				"mistake":      result.Mistake,
+				"expected":     toJSStringArray(result.Expected),
+				"validKeys":    toJSStringArray(result.Next),
+				"buffer":       result.Buffer,
+				"done":         result.Done,
+				"guide":        guideToJS(typingData.Guide(mode)),
+				"personalBest": personalBest,
+				"replay":       replay,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// GetTypingGuide はJavaScriptから呼び出され、現在のタイピング問題の入力のガイド (おすすめのキー) を返します。
+// 綴りは CreateTyping の romaji オプションの方式を優先しますが、入力途中の文字は入力済みのキーにつながる綴りに切り替わります
+// (例: 「し」を "s" "i" と入力し始めると "shi" から "si" に切り替わります)。
+//
+// 引数:
+//   - args[0]: モード (数値型)。
+//   - 1: 英語の問題文 (typingData.CurrentDataArrayE) のガイドを取得します
+//     (問題文の文字そのものです。CreateTyping の skipPunctuation を指定した場合は句読点を除きます)。
+//   - 2: 日本語（かな）の問題文 (typingData.CurrentDataArrayJ) のローマ字のガイドを取得します。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{typed, rest}` で解決されます。typed は入力済みのキー、rest は残りの入力のおすすめのキーです。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func GetTypingGuide(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if typingData.CurrentDataArrayE == nil || typingData.CurrentDataArrayJ == nil {
+				reject.Invoke(js.ValueOf("Go関数(GetTypingGuide)エラー: typingDataが初期化されていません。GetTypingQuestionを先に呼び出してください。"))
+				return
+			}
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(GetTypingGuide)エラー: 引数は1つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeNumber {
+				reject.Invoke(js.ValueOf("Go関数(GetTypingGuide)エラー: 引数は数値型である必要があります"))
+				return
+			}
+			mode := args[0].Int()
+			if typingData.Input(mode) == nil {
+				reject.Invoke(js.ValueOf("Go関数(GetTypingGuide)エラー: 引数は1か2である必要があります"))
+				return
+			}
+			resolve.Invoke(guideToJS(typingData.Guide(mode)))
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// guideToJS は入力のガイドをJavaScriptのオブジェクト (`{typed, rest}`) に変換します。
+func guideToJS(typed, rest string) map[string]interface{} {
+	return map[string]interface{}{
+		"typed": typed,
+		"rest":  rest,
+	}
+}
+
+// GetTypingSessionStats はJavaScriptから呼び出され、現在のタイピングのセッション (CreateTyping 以降) の
+// キー入力の記録を集計して返します。
+//
+// 引数:
+//   - なし (args は使用されません)
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{keystrokes, mistakes, accuracy, current, questions, misses, confusions}` で解決されます。
+//     keystrokes と mistakes はセッション全体のキー入力と誤りの数、accuracy は正確さ (0.0〜1.0)、
+//     current は現在の問題の集計、questions は完了した問題の集計 (いずれも `{id, keystrokes, mistakes, accuracy, en, jp}`) の配列、
+//     misses は誤りの多い文字 (`{char, count}`) の配列、confusions は誤って入力したキーの組み合わせ
+//     (`{expected, typed, count}`、例: "l" のところで "r") の配列で、いずれも多い順に並びます。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func GetTypingSessionStats(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if typingData.FilteredArray == nil {
+				reject.Invoke(js.ValueOf("Go関数(GetTypingSessionStats)エラー: typingDataが初期化されていません。CreateTypingを先に呼び出してください。"))
+				return
+			}
+			r := &typingData.Recorder
+			questions := make([]interface{}, len(r.Questions))
+			for i, q := range r.Questions {
+				questions[i] = questionStatsToJS(q)
+			}
+			topMisses := r.TopMisses()
+			misses := make([]interface{}, len(topMisses))
+			for i, m := range topMisses {
+				misses[i] = map[string]interface{}{
+					"char":  m.Char,
+					"count": m.Count,
+				}
+			}
+			topConfusions := r.TopConfusions()
+			confusions := make([]interface{}, len(topConfusions))
+			for i, c := range topConfusions {
+				confusions[i] = map[string]interface{}{
+					"expected": c.Expected,
+					"typed":    c.Typed,
+					"count":    c.Count,
+				}
+			}
+			resolve.Invoke(map[string]interface{}{
+				"keystrokes": r.Total.Keystrokes,
+				"mistakes":   r.Total.Mistakes,
+				"accuracy":   r.Total.Accuracy(),
+				"current":    questionStatsToJS(r.Current),
+				"questions":  questions,
+				"misses":     misses,
+				"confusions": confusions,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// questionStatsToJS は1問分のキー入力の集計をJavaScriptのオブジェクト (`{id, keystrokes, mistakes, accuracy, en, jp}`) に変換します。
+// en と jp は英語と日本語の入力の結果です (modeResultToJS を参照)。
+func questionStatsToJS(s typing.QuestionStats) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         s.ID,
+		"keystrokes": s.Keystrokes,
+		"mistakes":   s.Mistakes,
+		"accuracy":   s.Accuracy(),
+		"en":         modeResultToJS(s.English),
+		"jp":         modeResultToJS(s.Japanese),
+	}
+}
+
+// GetTypingStats はJavaScriptから呼び出され、タイピングの速度の記録 (現在のセッション、保存されたセッションの履歴、
+// モードごとの自己ベストと傾向) を返します。速度は TypingKeyDown に渡したキー入力の時刻から計算します。
+//
+// 引数:
+//   - なし (args は使用されません)
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{session, sessions, bests, trend}` で解決されます。
+//     session は現在のセッションの結果 (CreateTyping の前は null)、sessions は保存されたセッションの結果の配列 (古い順)、
+//     いずれも `{date, questions, en, jp}` で、date はセッションの開始時刻 (Unix ミリ秒)、questions は入力が完了した問題の数、
+//     en と jp は英語と日本語の結果 `{chars, keystrokes, mistakes, duration, cpm, wpm, kpm, accuracy}` です。
+//     bests はモード ("en"、"jp") ごとの自己ベスト `{date, result}` (記録がないモードは null)、
+//     trend はモードごとの直近のセッションの傾向 `{recent, previous, cpmChange, accuracyChange}` で、
+//     recent と previous は直近とその前の typing.TrendWindow 件のセッションの結果の合計です。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func GetTypingStats(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		go func() {
+			var session interface{}
+			if typingData.FilteredArray != nil {
+				session = sessionResultToJS(typingData.Recorder.Session())
+			}
+			sessions := make([]interface{}, len(typingHistory.Sessions))
+			for i, s := range typingHistory.Sessions {
+				sessions[i] = sessionResultToJS(s)
+			}
+			bests := map[string]interface{}{}
+			trend := map[string]interface{}{}
+			for _, key := range []string{typing.ModeKeyEnglish, typing.ModeKeyJapanese} {
+				bests[key] = nil
+				if best, ok := typingHistory.Bests[key]; ok {
+					bests[key] = map[string]interface{}{
+						"date":   best.Date,
+						"result": modeResultToJS(best.Result),
+					}
+				}
+				t := typingHistory.Trend(key)
+				trend[key] = map[string]interface{}{
+					"recent":         modeResultToJS(t.Recent),
+					"previous":       modeResultToJS(t.Previous),
+					"cpmChange":      t.CPMChange(),
+					"accuracyChange": t.AccuracyChange(),
+				}
+			}
+			resolve.Invoke(map[string]interface{}{
+				"session":  session,
+				"sessions": sessions,
+				"bests":    bests,
+				"trend":    trend,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// sessionResultToJS はセッションの結果をJavaScriptのオブジェクト (`{date, questions, en, jp}`) に変換します。
+func sessionResultToJS(s typing.SessionResult) map[string]interface{} {
+	return map[string]interface{}{
+		"date":      s.Date,
+		"questions": s.Questions,
+		"en":        modeResultToJS(s.English),
+		"jp":        modeResultToJS(s.Japanese),
+	}
+}
+
+// modeResultToJS は英語または日本語の入力の結果をJavaScriptのオブジェクト
+// (`{chars, keystrokes, mistakes, duration, cpm, wpm, kpm, accuracy}`) に変換します。duration はミリ秒です。
+func modeResultToJS(r typing.ModeResult) map[string]interface{} {
+	return map[string]interface{}{
+		"chars":      r.Chars,
+		"keystrokes": r.Keystrokes,
+		"mistakes":   r.Mistakes,
+		"duration":   r.Duration,
+		"cpm":        r.CPM(),
+		"wpm":        r.WPM(),
+		"kpm":        r.KPM(),
+		"accuracy":   r.Accuracy(),
+	}
+}
+
+// SetTypingRomajiTable はJavaScriptから呼び出され、日本語のタイピングに使うローマ字の対応表を設定し、
+// 設定をブラウザの localStorage に保存します。対応表は次の問題 (GetTypingQuestion) から有効になります。
+//
+// 引数:
+//   - args[0]: options (オブジェクト型) - 次のいずれかのキーを指定します。
+//   - preset: プリセットの名前。"standard" (標準のローマ字入力)、"azik" (AZIK)、"kana" (かな入力) のいずれか。
+//   - tsv: Google 日本語入力の形式 (入力<TAB>出力) のローマ字テーブルの文字列。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{table, skipped}` で解決されます。table は設定した対応表 (プリセットの名前または "custom")、
+//     skipped は TSV の中で入力の判定で扱えないため読み飛ばしたエントリ (例: "kz" で「かん」) の数です。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func SetTypingRomajiTable(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if len(args) != 1 || args[0].Type() != js.TypeObject {
+				reject.Invoke(js.ValueOf("Go関数(SetTypingRomajiTable)エラー: 引数はオブジェクトである必要があります"))
+				return
+			}
+			preset, err := getString(args[0], "preset")
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(SetTypingRomajiTable)エラー: %v", err)))
+				return
+			}
+			tsv, err := getString(args[0], "tsv")
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(SetTypingRomajiTable)エラー: %v", err)))
+				return
+			}
+			if (preset == "") == (tsv == "") {
+				reject.Invoke(js.ValueOf("Go関数(SetTypingRomajiTable)エラー: presetかtsvのどちらか一方を指定する必要があります"))
+				return
+			}
+			settings := typingSettings{Table: preset}
+			if tsv != "" {
+				settings = typingSettings{Table: customRomajiTable, TSV: tsv}
+			}
+			skipped, err := applyTypingSettings(settings)
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(SetTypingRomajiTable)エラー: %v", err)))
+				return
+			}
+			if errMsg := saveTypingSettings(); errMsg != "" {
+				reject.Invoke(js.ValueOf(errMsg))
 				return
 			}
-			userInput := args[0].String()
-			index := args[1].Int()
-			mode := args[2].Int()
-			// typingパッケージのKeyDown関数を呼び出して判定し、新しいインデックスを取得
-			resolve.Invoke(typingData.KeyDown(userInput, index, mode))
+			resolve.Invoke(map[string]interface{}{
+				"table":   typingConfig.Table,
+				"skipped": skipped,
+			})
 		}()
 		return nil
 	})
 	promiseConstructor := js.Global().Get("Promise")
 	return promiseConstructor.New(handler)
 }
+
+// GetTypingRomajiTable はJavaScriptから呼び出され、現在のローマ字の対応表の設定を返します。
+//
+// 引数:
+//   - なし (args は使用されません)
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{table}` で解決されます。table はプリセットの名前 ("standard"、"azik"、"kana") または "custom" です。
+func GetTypingRomajiTable(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		go func() {
+			table := typingConfig.Table
+			if table == "" {
+				table = typing.PresetStandard
+			}
+			resolve.Invoke(map[string]interface{}{
+				"table": table,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// GetTypingGhost はJavaScriptから呼び出され、現在の問題のゴースト (同じ問題文の自己ベスト、または SetTypingGhost で設定した記録) の
+// 時刻 at の入力位置を返します。ゴーストは現在の問題の最初のキー入力と同時にスタートし、記録と同じ速さで進みます。
+// requestAnimationFrame などで繰り返し呼び出して、ゴーストを実時間で表示します。
+//
+// 引数:
+//   - args[0]: 時刻 (数値型、ミリ秒)。TypingKeyDown に渡す KeyboardEvent.timeStamp と同じ基準の performance.now() を渡します。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: ゴーストがない場合は null、ある場合は `{mode, index, progress, done, elapsed, duration}` で解決されます。
+//     mode はゴーストが入力中のモード (1: 英語、2: 日本語)、index はそのモードの文字インデックス、
+//     progress は英語と日本語を合わせた進み具合 (0.0〜1.0)、done はゴーストの入力が完了した場合に true、
+//     elapsed は現在の問題の最初のキー入力からの経過時間 (まだ入力していない場合は 0)、duration はゴーストの入力にかかった時間 (いずれもミリ秒) です。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func GetTypingGhost(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(GetTypingGhost)エラー: 引数は1つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeNumber {
+				reject.Invoke(js.ValueOf("Go関数(GetTypingGhost)エラー: 引数は数値型である必要があります"))
+				return
+			}
+			ghost := typingData.Ghost
+			if ghost == nil {
+				resolve.Invoke(js.Null())
+				return
+			}
+			elapsed := typingData.Recording.Elapsed(time.Duration(args[0].Float() * float64(time.Millisecond)))
+			pos := ghost.Position(elapsed)
+			resolve.Invoke(map[string]interface{}{
+				"mode":     pos.Mode,
+				"index":    pos.Index,
+				"progress": pos.Progress,
+				"done":     pos.Done,
+				"elapsed":  elapsed,
+				"duration": ghost.Duration(),
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// SetTypingGhost はJavaScriptから呼び出され、JSONのキー入力の記録 (GetTypingRecordings で取得したものなど) を
+// 現在の問題のゴーストに設定します。友達の記録や過去のセッションの記録と競争するために使用します。
+// 設定したゴーストは現在の問題の間だけ有効で、次の問題では自己ベストのゴーストに戻ります。
+//
+// 引数:
+//   - args[0]: キー入力の記録 (文字列型、JSON)。現在の問題と同じ問題文の記録である必要があります。
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: ゴーストの入力にかかった時間 (ミリ秒) で解決されます。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func SetTypingGhost(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if typingData.CurrentData == nil {
+				reject.Invoke(js.ValueOf("Go関数(SetTypingGhost)エラー: typingDataが初期化されていません。GetTypingQuestionを先に呼び出してください。"))
+				return
+			}
+			if len(args) != 1 {
+				reject.Invoke(js.ValueOf("Go関数(SetTypingGhost)エラー: 引数は1つ必要です"))
+				return
+			}
+			if args[0].Type() != js.TypeString {
+				reject.Invoke(js.ValueOf("Go関数(SetTypingGhost)エラー: 引数は文字列型である必要があります"))
+				return
+			}
+			recording, err := typing.ParseRecording(args[0].String())
+			if err != nil {
+				reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(SetTypingGhost)エラー: 記録を読み込めません: %v", err)))
+				return
+			}
+			if recording.Text != typingData.Recording.Text {
+				reject.Invoke(js.ValueOf("Go関数(SetTypingGhost)エラー: 現在の問題と異なる問題文の記録です"))
+				return
+			}
+			typingData.Ghost = &recording
+			resolve.Invoke(recording.Duration())
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// GetTypingRecordings はJavaScriptから呼び出され、現在のセッションのキー入力の記録をJSONの文字列で返します。
+// 記録は SetTypingGhost でゴーストとして設定したり、保存してリプレイしたりできます。
+// JSON は `{text, date, chars, keys}` で、keys の各キー入力は `[at, mode, key, index, mistake]` の配列です
+// (at は問題の最初のキー入力からの経過時間 (ミリ秒)、mistake は誤ったキーの場合 1)。
+//
+// 引数:
+//   - なし (args は使用されません)
+//
+// 戻り値:
+//   - JavaScriptのPromiseオブジェクト。
+//   - 成功時: `{recordings, current, ghost}` で解決されます。recordings は入力した問題の記録の配列 (出題順)、
+//     current は現在の問題の記録、ghost は現在の問題のゴーストの記録 (ない場合は null) です。
+//   - 失敗時: エラーメッセージ (string) で拒否されます。
+func GetTypingRecordings(this js.Value, args []js.Value) any {
+	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+		resolve := promiseArgs[0]
+		reject := promiseArgs[1]
+		go func() {
+			if typingData.FilteredArray == nil {
+				reject.Invoke(js.ValueOf("Go関数(GetTypingRecordings)エラー: typingDataが初期化されていません。CreateTypingを先に呼び出してください。"))
+				return
+			}
+			encode := func(r typing.Recording) (string, bool) {
+				jsonData, err := json.Marshal(r)
+				if err != nil {
+					reject.Invoke(js.ValueOf(fmt.Sprintf("Go関数(GetTypingRecordings)エラー: 記録のJSONエンコード失敗: %v", err)))
+					return "", false
+				}
+				return string(jsonData), true
+			}
+			recordings := make([]interface{}, len(typingData.Recordings))
+			for i, r := range typingData.Recordings {
+				s, ok := encode(r)
+				if !ok {
+					return
+				}
+				recordings[i] = s
+			}
+			current, ok := encode(typingData.Recording)
+			if !ok {
+				return
+			}
+			var ghost interface{}
+			if typingData.Ghost != nil {
+				s, ok := encode(*typingData.Ghost)
+				if !ok {
+					return
+				}
+				ghost = s
+			}
+			resolve.Invoke(map[string]interface{}{
+				"recordings": recordings,
+				"current":    current,
+				"ghost":      ghost,
+			})
+		}()
+		return nil
+	})
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
+
+// comparisonToJS はキー入力の記録とゴーストの比較をJavaScriptのオブジェクト (`{duration, mistakes, splits}`) に変換します。
+// いずれも今回の入力からゴーストを引いた差で、duration は入力にかかった時間 (ミリ秒、負の場合は今回の入力が速い)、
+// mistakes は誤ったキー入力の数、splits は1文字ずつ、その文字までの入力が完了した時刻 (ミリ秒) の差の配列です。
+func comparisonToJS(c typing.Comparison) map[string]interface{} {
+	splits := make([]interface{}, len(c.Splits))
+	for i, v := range c.Splits {
+		splits[i] = v
+	}
+	return map[string]interface{}{
+		"duration": c.Duration,
+		"mistakes": c.Mistakes,
+		"splits":   splits,
+	}
+}
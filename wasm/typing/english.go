@@ -0,0 +1,63 @@
+package typing
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// EnglishOptions は英語 (CurrentDataArrayE) の入力の判定の設定です。ゼロ値は大文字と小文字を区別し、句読点も入力する設定です。
+// 設定に関係なく、キーボードで入力しにくい記号 (typographicSpellings) は ASCII の文字でも入力できます。
+type EnglishOptions struct {
+	IgnoreCase      bool // 大文字と小文字を区別しない
+	SkipPunctuation bool // 句読点 (ピリオド、カンマ、引用符、ダッシュなど) を入力せずに飛ばす
+}
+
+// typographicSpellings は英語の例文に含まれる活版印刷用の記号と、代わりに入力できる ASCII の綴りです。
+var typographicSpellings = map[string]string{
+	"‘": "'", "’": "'", "‚": "'", "‛": "'", "′": "'",
+	"“": "\"", "”": "\"", "„": "\"", "‟": "\"", "″": "\"",
+	"‐": "-", "‑": "-", "‒": "-", "–": "-", "—": "-", "―": "-", "−": "-",
+	"…":      "...",
+	"\u00a0": " ", // ノーブレークスペース
+}
+
+// newEnglishInput は英語の問題文の文字の配列に対する入力の状態を作成します。
+// 問題文の文字はそのまま残し (表示用)、入力できる綴りだけを options に従って広げます。
+//
+// 引数:
+//   - units: 問題文の文字の配列 (createDataArray の結果)。
+//   - options: 英語の入力の判定の設定。
+func newEnglishInput(units []string, options EnglishOptions) *Input {
+	table := make(RomajiTable)
+	for _, unit := range units {
+		key := normalizeKana(unit)
+		if _, exists := table[key]; exists {
+			continue
+		}
+		spellings := []string{key}
+		if ascii, ok := typographicSpellings[key]; ok {
+			spellings = []string{ascii, key}
+		}
+		if options.IgnoreCase {
+			for _, s := range []string{strings.ToLower(key), strings.ToUpper(key)} {
+				if s != key {
+					spellings = append(spellings, s)
+				}
+			}
+		}
+		table[key] = spellings
+	}
+	in := NewInput(units, table)
+	if options.SkipPunctuation {
+		in.skip = isPunctuation
+		in.skipAhead()
+	}
+	return in
+}
+
+// isPunctuation は文字が句読点 (1文字の Unicode の句読点) かどうかを判定します。
+func isPunctuation(unit string) bool {
+	r, size := utf8.DecodeRuneInString(unit)
+	return size == len(unit) && unicode.IsPunct(r)
+}
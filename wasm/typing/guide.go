@@ -0,0 +1,116 @@
+package typing
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// RomajiStyle はローマ字のガイドで優先して表示する綴りの方式です。
+type RomajiStyle string
+
+const (
+	StyleHepburn RomajiStyle = "hepburn" // ヘボン式 (shi, chi, tsu, fu, ji, sha など、既定値)
+	StyleKunrei  RomajiStyle = "kunrei"  // 訓令式 (si, ti, tu, hu, zi, sya など)
+)
+
+// IsValidStyle は文字列が有効な RomajiStyle かどうかを判定します。
+func IsValidStyle(s string) bool {
+	switch RomajiStyle(s) {
+	case StyleHepburn, StyleKunrei:
+		return true
+	}
+	return false
+}
+
+// styleSpellings は方式ごとに優先する綴りです。ここにない文字は preferredSpelling の規則で選びます。
+var styleSpellings = map[RomajiStyle]map[string]string{
+	StyleHepburn: {
+		"っ": "xtu", "ん": "nn", "し": "shi", "ち": "chi", "つ": "tsu", "ふ": "fu", "じ": "ji", "ぢ": "di", "づ": "du",
+		"しゃ": "sha", "しゅ": "shu", "しぇ": "she", "しょ": "sho",
+		"ちゃ": "cha", "ちゅ": "chu", "ちぇ": "che", "ちょ": "cho",
+		"じゃ": "ja", "じゅ": "ju", "じぇ": "je", "じょ": "jo",
+	},
+	StyleKunrei: {
+		"っ": "xtu", "ん": "nn", "し": "si", "ち": "ti", "つ": "tu", "ふ": "hu", "じ": "zi", "ぢ": "di", "づ": "du",
+		"しゃ": "sya", "しゅ": "syu", "しぇ": "sye", "しょ": "syo",
+		"ちゃ": "tya", "ちゅ": "tyu", "ちぇ": "tye", "ちょ": "tyo",
+		"じゃ": "zya", "じゅ": "zyu", "じぇ": "zye", "じょ": "zyo",
+	},
+}
+
+// Guide は入力のガイドとして、入力済みのキー (typed) と、残りの文字を入力するためのおすすめのキー (rest) を返します。
+// rest は style で優先する綴りで作りますが、現在の文字を入力途中の場合は入力済みのキーにつながる綴りを選ぶため、
+// 「し」を "s" "i" と入力し始めると、ガイドも "shi" から "si" に切り替わります。
+//
+// 引数:
+//   - style: 優先して表示する綴りの方式。
+//
+// 戻り値:
+//   - typed: 入力済みのキー (入力途中の文字の分を含む)。
+//   - rest: 残りの入力のおすすめのキー。
+func (in *Input) Guide(style RomajiStyle) (typed, rest string) {
+	var sb strings.Builder
+	for i := in.pos; i < len(in.units); i++ {
+		if in.skip != nil && in.skip(in.units[i]) {
+			continue
+		}
+		prefix := ""
+		if i == in.pos {
+			prefix = in.buffer
+		}
+		spelling := in.preferredSpelling(i, style, prefix)
+		sb.WriteString(spelling[len(prefix):])
+	}
+	return in.typed + in.buffer, sb.String()
+}
+
+// preferredSpelling は i 番目の文字を入力するおすすめの綴りを、prefix で始まる綴りの中から選びます。
+// style で優先する綴りがあればそれを選び、なければ短い綴り、c・x・l で始まらない綴り、アルファベット順の順に優先します。
+// 英語の活版印刷用の記号は ASCII の綴り (typographicSpellings) を、それ以外で文字そのものを入力できる場合はその文字を選びます。
+// 「っ」は次の文字のおすすめの綴りの子音を重ね、「ん」は "n" 1文字で入力できる場合は "n" を選びます。
+func (in *Input) preferredSpelling(i int, style RomajiStyle, prefix string) string {
+	candidates := slices.DeleteFunc(slices.Clone(in.candidatesAt(i)), func(c string) bool {
+		return !strings.HasPrefix(c, prefix)
+	})
+	if len(candidates) == 0 {
+		return prefix
+	}
+	if in.kana[i] == "っ" && i+1 < len(in.kana) {
+		// 次の文字に綴りがない場合は重ねる子音がないため、単独で入力する綴りを選ぶ
+		if next := in.preferredSpelling(i+1, style, ""); next != "" {
+			if c := firstKey(next); slices.Contains(candidates, c) {
+				return c
+			}
+		}
+	}
+	if ascii, ok := typographicSpellings[in.kana[i]]; ok && slices.Contains(candidates, ascii) {
+		// 英語の活版印刷用の記号 (’ — … など) はキーボードで入力できる ASCII の綴りを選ぶ
+		return ascii
+	}
+	if slices.Contains(candidates, in.kana[i]) {
+		// 英語やかな入力のように文字そのものを入力できる場合はその文字を選ぶ
+		return in.kana[i]
+	}
+	if in.kana[i] == "ん" && slices.Contains(candidates, "n") {
+		return "n"
+	}
+	if s, ok := styleSpellings[style][in.kana[i]]; ok && slices.Contains(candidates, s) {
+		return s
+	}
+	return slices.MinFunc(candidates, func(a, b string) int {
+		return cmp.Or(
+			cmp.Compare(len(a), len(b)),
+			cmp.Compare(fallbackRank(a), fallbackRank(b)),
+			cmp.Compare(a, b),
+		)
+	})
+}
+
+// fallbackRank は綴りが c・x・l で始まる (代わりの入力方法である) 場合に 1、それ以外の場合に 0 を返します。
+func fallbackRank(spelling string) int {
+	if strings.IndexAny(spelling, "cxl") == 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,254 @@
+package typing
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// KeyStatus は1回のキー入力の判定結果です。
+type KeyStatus string
+
+const (
+	KeyAccepted KeyStatus = "accepted" // 1文字以上の入力が完了し、次の文字に進んだ
+	KeyPending  KeyStatus = "pending"  // 正しいキーだが、文字の入力がまだ完了していない
+	KeyRejected KeyStatus = "rejected" // 誤ったキー (入力は受け付けられず、状態は変わらない)
+)
+
+// Input は文字単位に分割した問題文 (CurrentDataArrayE または CurrentDataArrayJ) に対する入力の状態です。
+// キーを1つずつ受け取り、ローマ字の対応表 (RomajiTable) のいずれかの綴りで文字の入力が完了すると次の文字に進みます。
+// 入力済みのキーは現在の文字に対するもの (Buffer) だけを保持するため、前の文字の入力が後の文字の判定に影響しません。
+// カタカナと全角英数字は、ひらがなと半角に変換した文字の綴りで判定します (例: 「ゴー！」は "go-!")。
+type Input struct {
+	units  []string               // 問題文の文字 (拗音などは2文字で1要素)
+	kana   []string               // units を normalizeKana で変換した文字 (綴りの参照に使用)
+	table  RomajiTable            // 綴りを参照するローマ字の対応表
+	skip   func(unit string) bool // 入力せずに飛ばす文字を判定する関数 (nil の場合は飛ばさない)
+	pos    int                    // 現在の文字のインデックス
+	buffer string                 // 現在の文字に対して入力済みのキー
+	last   string                 // 直前に入力が完了した文字に対して入力したキー
+	typed  string                 // 入力が完了した文字に対して入力したキー (誤ったキーを除く)
+}
+
+// NewInput は問題文の文字の配列に対する入力の状態を作成します。
+//
+// 引数:
+//   - units: 問題文の文字の配列 (createDataArray の結果)。
+//   - table: 綴りを参照するローマ字の対応表。nil の場合は RomajiMap を使用します。
+func NewInput(units []string, table RomajiTable) *Input {
+	kana := make([]string, len(units))
+	for i, unit := range units {
+		kana[i] = normalizeKana(unit)
+	}
+	if table == nil {
+		table = RomajiMap
+	}
+	return &Input{units: units, kana: kana, table: table}
+}
+
+// Position は現在の文字のインデックス (入力が完了した文字の数) を返します。
+func (in *Input) Position() int {
+	return in.pos
+}
+
+// Buffer は現在の文字に対して入力済みのキーを返します。
+func (in *Input) Buffer() string {
+	return in.buffer
+}
+
+// Unit は現在の文字 (変換前の元の文字) を返します。入力が完了している場合は空文字列を返します。
+func (in *Input) Unit() string {
+	if in.Done() {
+		return ""
+	}
+	return in.units[in.pos]
+}
+
+// Done は問題文の最後の文字まで入力が完了したかどうかを返します。
+func (in *Input) Done() bool {
+	return in.pos >= len(in.units)
+}
+
+// Key はキーを1つ受け取り、現在の文字の入力を進めます。
+// 入力済みのキーにつなげた綴りが現在の文字の綴りのいずれかと一致した場合は次の文字に進み (KeyAccepted)、
+// いずれかの綴りの途中であれば入力を保持し (KeyPending)、どの綴りにもつながらない場合は状態を変えません (KeyRejected)。
+//
+// 「ん」を "n" 1文字で入力する場合のように、入力済みのキーだけで文字が完了しているが、
+// さらに長い綴りの途中でもある場合は、次のキーで判断します。次のキーが長い綴りにつながらず、
+// 次の文字の最初のキーとして正しい場合は、文字を完了したうえでそのキーを次の文字の入力として扱います。
+//
+// 引数:
+//   - key: 入力されたキー (1文字)。
+//
+// 戻り値:
+//   - キー入力の判定結果。
+func (in *Input) Key(key string) KeyStatus {
+	if in.Done() || key == "" {
+		return KeyRejected
+	}
+	candidates := in.candidatesAt(in.pos)
+	typed := in.buffer + key
+	complete, prefix := match(candidates, typed)
+	switch {
+	case prefix:
+		// 長い綴りの途中の場合は、一致していても次のキーまで判断を保留する
+		in.buffer = typed
+		return KeyPending
+	case complete:
+		in.buffer = typed
+		in.advance(1)
+		return KeyAccepted
+	}
+
+	// 入力済みのキーだけで現在の文字が完了している場合は、key を次の文字の最初のキーとして扱う
+	if complete, _ := match(candidates, in.buffer); complete && in.pos+1 < len(in.units) {
+		next := in.candidatesAt(in.pos + 1)
+		if complete, prefix := match(next, key); complete || prefix {
+			in.advance(1)
+			in.Key(key)
+			return KeyAccepted
+		}
+	}
+	return KeyRejected
+}
+
+// ValidKeys は次に入力できるキーを重複なく昇順で返します。
+func (in *Input) ValidKeys() []string {
+	if in.Done() {
+		return nil
+	}
+	var keys []string
+	for _, c := range in.candidatesAt(in.pos) {
+		if len(c) > len(in.buffer) && strings.HasPrefix(c, in.buffer) {
+			keys = append(keys, firstKey(c[len(in.buffer):]))
+		}
+	}
+	if complete, _ := match(in.candidatesAt(in.pos), in.buffer); complete && in.buffer != "" && in.pos+1 < len(in.units) {
+		for _, c := range in.candidatesAt(in.pos + 1) {
+			keys = append(keys, firstKey(c))
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// firstKey は綴りの最初のキー (1文字) を返します。かな入力の対応表のように、キーが ASCII 以外の場合にも対応します。
+func firstKey(spelling string) string {
+	_, size := utf8.DecodeRuneInString(spelling)
+	return spelling[:size]
+}
+
+// advance は現在の文字から n 文字進み、入力済みのキーを typed に移します。
+func (in *Input) advance(n int) {
+	in.pos += n
+	in.typed += in.buffer
+	in.last = in.buffer
+	in.buffer = ""
+	in.skipAhead()
+}
+
+// skipAhead は現在の文字が skip で飛ばす文字の間、次の文字に進みます。
+func (in *Input) skipAhead() {
+	for in.skip != nil && !in.Done() && in.skip(in.units[in.pos]) {
+		in.pos++
+	}
+}
+
+// candidatesAt は i 番目の文字を入力する綴りの一覧を返します。
+// 対応表にない文字 (英字や記号) は、その文字自体を綴りとします。
+// 「っ」と「ん」は次の文字によって入力できる綴りが変わるため、次の文字を先読みして決めます。
+// 直前の「っ」を子音の重ね打ちで入力した場合は、その子音で始まる綴りだけにします (例: 「っか」を "c" で始めたら "ka" は不可)。
+func (in *Input) candidatesAt(i int) []string {
+	candidates := in.spellingsAt(i)
+	if c := in.doubledConsonant(i); c != "" {
+		candidates = slices.DeleteFunc(slices.Clone(candidates), func(word string) bool {
+			return !strings.HasPrefix(word, c)
+		})
+	}
+	return candidates
+}
+
+// spellingsAt は i 番目の文字の綴りを、直前の文字の入力に関係なく返します。
+func (in *Input) spellingsAt(i int) []string {
+	unit := in.kana[i]
+	next := ""
+	if i+1 < len(in.kana) {
+		next = in.kana[i+1]
+	}
+	switch unit {
+	case "っ":
+		return sokuonCandidates(in.table, next)
+	case "ん":
+		return hatsuonCandidates(in.table, next)
+	}
+	if words, exists := in.table[unit]; exists {
+		return words
+	}
+	return []string{unit}
+}
+
+// doubledConsonant は i 番目の文字の直前の「っ」を子音の重ね打ち (1文字の綴り) で入力した場合に、その子音を返します。
+// 「っ」が現在の文字の場合は入力済みのキー、入力が完了している場合はそのキーで判定します。それ以外の場合は空文字列を返します。
+func (in *Input) doubledConsonant(i int) string {
+	if i == 0 || in.kana[i-1] != "っ" {
+		return ""
+	}
+	var spelling string
+	switch i {
+	case in.pos:
+		spelling = in.last
+	case in.pos + 1:
+		spelling = in.buffer
+	}
+	if len(spelling) != 1 || slices.Contains(in.table["っ"], spelling) {
+		return ""
+	}
+	return spelling
+}
+
+// sokuonCandidates は「っ」の綴りを返します。単独で入力する綴り ("xtu" など) に加えて、
+// 次の文字の子音を重ねる入力 (例: 「っこ」の "k") を1文字の綴りとして含めます。
+// 母音と n、単独入力の接頭辞になる x と l は重ねる入力として扱いません。
+func sokuonCandidates(table RomajiTable, next string) []string {
+	candidates := slices.Clone(table["っ"])
+	for _, word := range table[next] {
+		c := word[:1]
+		if c[0] >= 'a' && c[0] <= 'z' && !strings.Contains("aiueonxl", c) && !slices.Contains(candidates, c) {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// hatsuonCandidates は「ん」の綴りを返します。
+// "n" 1文字の綴りは、次の文字がローマ字で入力する文字で、その綴りがすべて母音・n・y 以外で始まる場合にのみ含めます
+// (例: 「んご」は "n" でよいが、「んい」「んな」「んよ」や文末は "nn" が必要)。
+// 対応表の「ん」に "n" がない場合 (かな入力など) は含めません。
+func hatsuonCandidates(table RomajiTable, next string) []string {
+	hasN := slices.Contains(table["ん"], "n")
+	candidates := slices.DeleteFunc(slices.Clone(table["ん"]), func(word string) bool {
+		return word == "n"
+	})
+	words, exists := table[next]
+	if !hasN || !exists || next == "っ" || next == "ん" {
+		return candidates
+	}
+	for _, word := range words {
+		if strings.Contains("aiueony", word[:1]) {
+			return candidates
+		}
+	}
+	return append(candidates, "n")
+}
+
+// match は入力 typed が綴りのいずれかと一致するか (complete)、いずれかのより長い綴りの途中か (prefix) を返します。
+func match(candidates []string, typed string) (complete, prefix bool) {
+	for _, c := range candidates {
+		if c == typed {
+			complete = true
+		} else if strings.HasPrefix(c, typed) {
+			prefix = true
+		}
+	}
+	return complete, prefix
+}
@@ -0,0 +1,184 @@
+package typing
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// KeyResult は KeyDown の判定結果です。
+type KeyResult struct {
+	Index    int       // 判定後の文字インデックス (入力が完了した文字の数)
+	Status   KeyStatus // キー入力の判定結果
+	Mistake  bool      // 誤ったキーを入力した場合 true (Status が KeyRejected の場合)
+	Expected []string  // このキーの入力前に入力できたキー (Input.ValidKeys)
+	Next     []string  // 次に入力できるキー
+	Buffer   string    // 現在の文字に対して入力済みのキー
+	Done     bool      // 問題文の最後の文字まで入力が完了した場合 true
+}
+
+// Confusion は誤って入力したキーの組み合わせです (例: "l" のところで "r" を入力)。
+type Confusion struct {
+	Expected string // 入力すべきだったキー
+	Typed    string // 実際に入力したキー
+}
+
+// QuestionStats は1問分のキー入力の集計です。
+type QuestionStats struct {
+	ID         int        // 問題の単語ID
+	Keystrokes int        // キー入力の総数 (誤りを含む)
+	Mistakes   int        // 誤ったキー入力の数
+	English    ModeResult // 英語の入力の結果 (Chars と Duration は入力が完了した時点で設定)
+	Japanese   ModeResult // 日本語の入力の結果 (Chars と Duration は入力が完了した時点で設定)
+}
+
+// Mode はモード (ModeEnglish または ModeJapanese) の入力の結果へのポインタを返します。無効なモードの場合は nil を返します。
+func (s *QuestionStats) Mode(mode int) *ModeResult {
+	switch mode {
+	case ModeEnglish:
+		return &s.English
+	case ModeJapanese:
+		return &s.Japanese
+	}
+	return nil
+}
+
+// Total は英語と日本語の入力の結果の合計を返します。
+func (s QuestionStats) Total() ModeResult {
+	return s.English.Add(s.Japanese)
+}
+
+// Accuracy はキー入力の正確さ (0.0〜1.0) を返します。キー入力がない場合は 0 を返します。
+func (s QuestionStats) Accuracy() float64 {
+	if s.Keystrokes == 0 {
+		return 0
+	}
+	return float64(s.Keystrokes-s.Mistakes) / float64(s.Keystrokes)
+}
+
+// Recorder はタイピングの1セッション (CreateTyping から次の CreateTyping まで) のキー入力を記録します。
+// 文字 (かなまたは英字) ごとの誤りの数と、誤って入力したキーの組み合わせを集計します。
+type Recorder struct {
+	Total      QuestionStats         // セッション全体の集計 (ID は使用しない)
+	Current    QuestionStats         // 現在の問題の集計
+	Questions  []QuestionStats       // 完了した (次の問題に移った) 問題の集計 (出題順)
+	MissByChar map[string]int        // 誤りが起きた文字 (CurrentDataArrayE/J の要素) ごとの誤りの数
+	Confusions map[Confusion]int     // 誤って入力したキーの組み合わせごとの数
+	Started    time.Time             // セッションの開始時刻
+	starts     map[int]time.Duration // 現在の問題のモードごとの最初のキー入力の時刻
+}
+
+// Begin は新しい問題の記録を開始します。現在の問題にキー入力がある場合は Questions に追加します。
+//
+// 引数:
+//   - id: 新しい問題の単語ID。
+func (r *Recorder) Begin(id int) {
+	if r.Current.Keystrokes > 0 {
+		r.Questions = append(r.Questions, r.Current)
+	}
+	r.Current = QuestionStats{ID: id}
+	r.starts = nil
+}
+
+// Record はキー入力1回の結果を記録します。
+// 誤りの場合は、その時点の文字 unit と、入力すべきだったキーが1つに決まる場合はキーの組み合わせも記録します。
+// モードの入力が完了した場合は、そのモードの最初のキー入力からの時間と文字数を現在の問題の結果に設定します。
+//
+// 引数:
+//   - mode: ModeEnglish または ModeJapanese。
+//   - unit: キーを入力した時点の文字 (CurrentDataArrayE/J の要素)。
+//   - key: 入力されたキー。
+//   - at: キーを入力した時刻 (JavaScriptの KeyboardEvent.timeStamp など、任意の基準からの経過時間)。
+//   - result: キー入力の判定結果。
+func (r *Recorder) Record(mode int, unit, key string, at time.Duration, result KeyResult) {
+	r.Total.Keystrokes++
+	r.Current.Keystrokes++
+	if m := r.Current.Mode(mode); m != nil {
+		if r.starts == nil {
+			r.starts = make(map[int]time.Duration)
+		}
+		start, ok := r.starts[mode]
+		if !ok {
+			start = at
+			r.starts[mode] = at
+		}
+		m.Keystrokes++
+		if result.Mistake {
+			m.Mistakes++
+		}
+		if result.Done {
+			m.Chars = result.Index
+			m.Duration = (at - start).Milliseconds()
+		}
+	}
+	if !result.Mistake {
+		return
+	}
+	r.Total.Mistakes++
+	r.Current.Mistakes++
+	if r.MissByChar == nil {
+		r.MissByChar = make(map[string]int)
+	}
+	r.MissByChar[unit]++
+	// 入力できたキーが複数ある場合 (例: 「し」の s と c) は、どのキーと取り違えたか決められないため記録しない
+	if len(result.Expected) == 1 {
+		if r.Confusions == nil {
+			r.Confusions = make(map[Confusion]int)
+		}
+		r.Confusions[Confusion{Expected: result.Expected[0], Typed: key}]++
+	}
+}
+
+// Session は完了した問題と現在の問題の結果から、セッションの結果を作成します。
+// 入力が完了していないモードの結果 (Chars が 0) は含めません。
+func (r *Recorder) Session() SessionResult {
+	session := SessionResult{Date: r.Started.UnixMilli()}
+	for _, q := range append(slices.Clone(r.Questions), r.Current) {
+		if q.English.Chars > 0 {
+			session.English = session.English.Add(q.English)
+		}
+		if q.Japanese.Chars > 0 {
+			session.Japanese = session.Japanese.Add(q.Japanese)
+		}
+		if q.English.Chars > 0 || q.Japanese.Chars > 0 {
+			session.Questions++
+		}
+	}
+	return session
+}
+
+// CharCount は文字と誤りの数の組です。
+type CharCount struct {
+	Char  string
+	Count int
+}
+
+// ConfusionCount はキーの組み合わせと誤りの数の組です。
+type ConfusionCount struct {
+	Confusion
+	Count int
+}
+
+// TopMisses は誤りの多い文字を、誤りの数の多い順 (同じ場合は文字の順) に返します。
+func (r *Recorder) TopMisses() []CharCount {
+	results := make([]CharCount, 0, len(r.MissByChar))
+	for char, count := range r.MissByChar {
+		results = append(results, CharCount{char, count})
+	}
+	slices.SortFunc(results, func(a, b CharCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Char, b.Char))
+	})
+	return results
+}
+
+// TopConfusions は誤って入力したキーの組み合わせを、数の多い順 (同じ場合はキーの順) に返します。
+func (r *Recorder) TopConfusions() []ConfusionCount {
+	results := make([]ConfusionCount, 0, len(r.Confusions))
+	for c, count := range r.Confusions {
+		results = append(results, ConfusionCount{c, count})
+	}
+	slices.SortFunc(results, func(a, b ConfusionCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Expected, b.Expected), cmp.Compare(a.Typed, b.Typed))
+	})
+	return results
+}
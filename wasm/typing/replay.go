@@ -0,0 +1,285 @@
+package typing
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GhostLimit は保存するゴースト (問題文ごとの最速の記録) の上限です。上限を超えた場合は古い記録から削除します。
+const GhostLimit = 300
+
+// Keystroke は記録したキー入力1回分です。
+// JSON では [at, mode, key, index, mistake] の配列 (mistake は 0 または 1) として保存し、記録を小さくします。
+type Keystroke struct {
+	At      int64  // 問題の最初のキー入力からの経過時間 (ミリ秒)
+	Mode    int    // ModeEnglish または ModeJapanese
+	Key     string // 入力されたキー
+	Index   int    // キー入力の判定後の文字インデックス (KeyResult.Index)
+	Mistake bool   // 誤ったキーの場合 true
+}
+
+// MarshalJSON はキー入力を [at, mode, key, index, mistake] の配列にエンコードします。
+func (k Keystroke) MarshalJSON() ([]byte, error) {
+	mistake := 0
+	if k.Mistake {
+		mistake = 1
+	}
+	return json.Marshal([]any{k.At, k.Mode, k.Key, k.Index, mistake})
+}
+
+// UnmarshalJSON は [at, mode, key, index, mistake] の配列からキー入力をデコードします。
+func (k *Keystroke) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 5 {
+		return fmt.Errorf("キー入力の要素数が5ではありません: %d", len(fields))
+	}
+	var mistake int
+	for i, v := range []any{&k.At, &k.Mode, &k.Key, &k.Index, &mistake} {
+		if err := json.Unmarshal(fields[i], v); err != nil {
+			return err
+		}
+	}
+	k.Mistake = mistake != 0
+	return nil
+}
+
+// Recording は1問分のキー入力の記録です。JSON で保存・共有し、リプレイやゴースト (過去の記録との競争) に使用します。
+type Recording struct {
+	Text  string        `json:"text"`  // 問題文 (英語と日本語の入力する文字を改行でつないだもの、recordingText を参照)
+	Date  int64         `json:"date"`  // 記録を開始した時刻 (Unix ミリ秒)
+	Chars [2]int        `json:"chars"` // 英語と日本語の文字数
+	Keys  []Keystroke   `json:"keys"`  // キー入力 (入力順)
+	start time.Duration // 最初のキー入力の時刻 (KeyDownAt の at)
+}
+
+// GhostPosition はある時刻のゴーストの入力位置です。
+type GhostPosition struct {
+	Mode     int     // 入力中のモード (ModeEnglish または ModeJapanese)
+	Index    int     // Mode の文字インデックス (入力が完了した文字の数)
+	Progress float64 // 英語と日本語を合わせた入力の進み具合 (0.0〜1.0)
+	Done     bool    // すべての入力が完了している場合 true
+}
+
+// Comparison は2つの記録 (今回の入力とゴースト) の比較です。差はいずれも今回の入力からゴーストを引いた値です。
+type Comparison struct {
+	Duration int64   // 入力にかかった時間の差 (ミリ秒、負の場合は今回の入力が速い)
+	Mistakes int     // 誤ったキー入力の数の差
+	Splits   []int64 // 1文字ずつ、その文字までの入力が完了した時刻の差 (ミリ秒、英語と日本語の通しの順)
+}
+
+// recordingText は記録を問題文ごとに比較するため、英語と日本語の入力する文字を改行でつないだ文字列を作成します。
+func recordingText(e, j []string) string {
+	return strings.Join(e, "") + "\n" + strings.Join(j, "")
+}
+
+// newRecording は英語と日本語の文字配列に対する新しい記録を作成します。
+func newRecording(e, j []string) Recording {
+	return Recording{Text: recordingText(e, j), Date: now().UnixMilli(), Chars: [2]int{len(e), len(j)}}
+}
+
+// ParseRecording は JSON の記録をデコードし、キー入力の順序やモードが正しいかを検証します。
+//
+// 引数:
+//   - data: Recording を JSON にエンコードした文字列。
+//
+// 戻り値:
+//   - デコードした記録。
+//   - JSON が不正な場合、またはキー入力の時刻が入力順に並んでいない・モードや文字インデックスが範囲外の場合はエラー。
+func ParseRecording(data string) (Recording, error) {
+	var r Recording
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		return Recording{}, err
+	}
+	if err := r.validate(); err != nil {
+		return Recording{}, err
+	}
+	return r, nil
+}
+
+// validate はキー入力の時刻が入力順に並んでいるか、モードと文字インデックスが範囲内かを検証します。
+// 検証していない記録を Position などに渡すと、範囲外のモードで panic するおそれがあります。
+func (r Recording) validate() error {
+	if r.Chars[0] < 0 || r.Chars[1] < 0 {
+		return fmt.Errorf("文字数が不正です: %v", r.Chars)
+	}
+	var last int64
+	for i, k := range r.Keys {
+		if k.Mode != ModeEnglish && k.Mode != ModeJapanese {
+			return fmt.Errorf("%d番目のキー入力のモードが不正です: %d", i+1, k.Mode)
+		}
+		if k.At < last {
+			return fmt.Errorf("%d番目のキー入力の時刻が前のキー入力より前です", i+1)
+		}
+		if k.Index < 0 || k.Index > r.Chars[k.Mode-1] {
+			return fmt.Errorf("%d番目のキー入力の文字インデックスが範囲外です: %d", i+1, k.Index)
+		}
+		last = k.At
+	}
+	return nil
+}
+
+// record はキー入力1回の結果を記録します。時刻は問題の最初のキー入力からの経過時間として記録します。
+func (r *Recording) record(mode int, key string, at time.Duration, result KeyResult) {
+	if len(r.Keys) == 0 {
+		r.start = at
+	}
+	r.Keys = append(r.Keys, Keystroke{
+		At:      (at - r.start).Milliseconds(),
+		Mode:    mode,
+		Key:     key,
+		Index:   result.Index,
+		Mistake: result.Mistake,
+	})
+}
+
+// Elapsed は時刻 at (KeyDownAt の at と同じ基準) の、問題の最初のキー入力からの経過時間をミリ秒で返します。
+// まだキー入力がない場合は 0 を返します。
+func (r *Recording) Elapsed(at time.Duration) int64 {
+	if len(r.Keys) == 0 {
+		return 0
+	}
+	return max(0, (at - r.start).Milliseconds())
+}
+
+// Done は英語と日本語のすべての文字の入力が完了しているかどうかを返します。
+func (r Recording) Done() bool {
+	return r.Position(r.Duration()).Done
+}
+
+// Duration は最初のキー入力から最後のキー入力までの時間をミリ秒で返します。
+func (r Recording) Duration() int64 {
+	if len(r.Keys) == 0 {
+		return 0
+	}
+	return r.Keys[len(r.Keys)-1].At
+}
+
+// Mistakes は誤ったキー入力の数を返します。
+func (r Recording) Mistakes() int {
+	n := 0
+	for _, k := range r.Keys {
+		if k.Mistake {
+			n++
+		}
+	}
+	return n
+}
+
+// Position は記録をリプレイしたときの、最初のキー入力からの経過時間 elapsed (ミリ秒) の入力位置を返します。
+// ゴーストとして今回の入力と同時に進める場合は、elapsed に Recording.Elapsed の値を指定します。
+func (r Recording) Position(elapsed int64) GhostPosition {
+	pos := GhostPosition{Mode: ModeEnglish}
+	var index [2]int
+	for _, k := range r.Keys {
+		if k.At > elapsed {
+			break
+		}
+		pos.Mode = k.Mode
+		index[k.Mode-1] = k.Index
+	}
+	pos.Index = index[pos.Mode-1]
+	total := r.Chars[0] + r.Chars[1]
+	if total > 0 {
+		pos.Progress = float64(index[0]+index[1]) / float64(total)
+	}
+	pos.Done = index[0] >= r.Chars[0] && index[1] >= r.Chars[1]
+	return pos
+}
+
+// splits は英語と日本語の通しの順で、1文字ずつその文字までの入力が完了した時刻 (ミリ秒) を返します。
+func (r Recording) splits() []int64 {
+	var times []int64
+	var index [2]int
+	for _, k := range r.Keys {
+		index[k.Mode-1] = k.Index
+		for len(times) < index[0]+index[1] {
+			times = append(times, k.At)
+		}
+	}
+	return times
+}
+
+// Compare は記録をゴースト ghost と比較します。Splits は両方の記録で入力が完了した文字の分だけ返します。
+func (r Recording) Compare(ghost Recording) Comparison {
+	c := Comparison{
+		Duration: r.Duration() - ghost.Duration(),
+		Mistakes: r.Mistakes() - ghost.Mistakes(),
+	}
+	mine, theirs := r.splits(), ghost.splits()
+	for i := range min(len(mine), len(theirs)) {
+		c.Splits = append(c.Splits, mine[i]-theirs[i])
+	}
+	return c
+}
+
+// Ghosts は問題文 (Recording.Text) から、その問題文の最速の記録 (自己ベストのゴースト) への対応表です。
+// ローカルの記録として JSON で保存します。
+type Ghosts map[string]Recording
+
+// ParseGhosts は JSON で保存したゴーストをデコードします。記録ごとに ParseRecording と同じ検証を行い、
+// 検証に失敗した記録、入力が完了していない記録、問題文が保存したキーと異なる記録は取り除きます。
+//
+// 引数:
+//   - data: Ghosts を JSON にエンコードした文字列。
+//
+// 戻り値:
+//   - デコードしたゴースト。
+//   - 取り除いた記録の数。
+//   - JSON が不正な場合はエラー。
+func ParseGhosts(data string) (Ghosts, int, error) {
+	var ghosts Ghosts
+	if err := json.Unmarshal([]byte(data), &ghosts); err != nil {
+		return nil, 0, err
+	}
+	if ghosts == nil {
+		ghosts = make(Ghosts)
+	}
+	dropped := 0
+	for text, r := range ghosts {
+		if r.Text != text || r.validate() != nil || !r.Done() {
+			delete(ghosts, text)
+			dropped++
+		}
+	}
+	return ghosts, dropped, nil
+}
+
+// ErrRecordingNotDone は入力が完了していない記録をゴーストとして保存しようとした場合のエラーです。
+var ErrRecordingNotDone = errors.New("入力が完了していない記録です")
+
+// Record は入力が完了した記録を、同じ問題文のゴーストより速い場合 (またはゴーストがない場合) にゴーストとして保存します。
+// 保存した記録の数が GhostLimit を超えた場合は、記録した時刻の古いものから削除します。
+//
+// 戻り値:
+//   - ゴーストを更新した場合 true。
+//   - 記録の入力が完了していない場合は ErrRecordingNotDone。
+func (g Ghosts) Record(r Recording) (bool, error) {
+	if !r.Done() {
+		return false, ErrRecordingNotDone
+	}
+	if ghost, ok := g[r.Text]; ok && ghost.Duration() <= r.Duration() {
+		return false, nil
+	}
+	g[r.Text] = r
+	if len(g) > GhostLimit {
+		texts := make([]string, 0, len(g))
+		for text := range g {
+			texts = append(texts, text)
+		}
+		slices.SortFunc(texts, func(a, b string) int {
+			return cmp.Or(cmp.Compare(g[a].Date, g[b].Date), cmp.Compare(a, b))
+		})
+		for _, text := range texts[:len(g)-GhostLimit] {
+			delete(g, text)
+		}
+	}
+	return true, nil
+}
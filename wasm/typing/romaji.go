@@ -0,0 +1,275 @@
+package typing
+
+import "slices"
+
+// RomajiTable はひらがな (拗音などは2文字) と記号から、入力する綴りの一覧への対応表です。
+// 入力の判定 (Input) と問題文の分割 (createDataArray) はこの対応表を参照します。
+type RomajiTable map[string][]string
+
+// RomajiMap はひらがな（および一部記号）と対応するローマ字入力の組み合わせを保持するマップです。
+// 1つのひらがなに対して複数のローマ字入力（例: し -> si, shi, ci）が存在する場合も考慮されています。
+// 拗音（きゃ、きゅ、きょなど）や促音（っ）、長音（ー）なども含まれます。
+// カタカナと全角英数字はキーに含めず、normalizeKana でひらがなと半角に変換してから参照します。
+//
+// 綴りは Microsoft IME と Google 日本語入力の標準のローマ字テーブルに合わせています。
+// 2文字の要素 (拗音など) には直接入力する綴りだけを記載し、1文字ずつ入力する綴り (例: 「きゅ」の "kixyu") は
+// 初期化時に addSeparateSpellings で追加します。
+// 同じ文字の綴りは互いに他の綴りの接頭辞にならないようにします (「ん」の "n" を除く)。
+// 接頭辞になる綴りがあると、文末でその文字の入力が完了しなくなるためです。
+var RomajiMap = RomajiTable{
+	// 清音
+	"あ": {"a"},
+	"い": {"i", "yi"},
+	"う": {"u", "wu", "whu"},
+	"え": {"e"},
+	"お": {"o"},
+	"か": {"ka", "ca"},
+	"き": {"ki"},
+	"く": {"ku", "cu", "qu"},
+	"け": {"ke"},
+	"こ": {"ko", "co"},
+	"さ": {"sa"},
+	"し": {"si", "shi", "ci"},
+	"す": {"su"},
+	"せ": {"se", "ce"},
+	"そ": {"so"},
+	"た": {"ta"},
+	"ち": {"ti", "chi"},
+	"つ": {"tu", "tsu"},
+	"て": {"te"},
+	"と": {"to"},
+	"な": {"na"},
+	"に": {"ni"},
+	"ぬ": {"nu"},
+	"ね": {"ne"},
+	"の": {"no"},
+	"は": {"ha"},
+	"ひ": {"hi"},
+	"ふ": {"hu", "fu"},
+	"へ": {"he"},
+	"ほ": {"ho"},
+	"ま": {"ma"},
+	"み": {"mi"},
+	"む": {"mu"},
+	"め": {"me"},
+	"も": {"mo"},
+	"や": {"ya"},
+	"ゆ": {"yu"},
+	"よ": {"yo"},
+	"ら": {"ra"},
+	"り": {"ri"},
+	"る": {"ru"},
+	"れ": {"re"},
+	"ろ": {"ro"},
+	"わ": {"wa"},
+	"ゐ": {"wyi"},
+	"ゑ": {"wye"},
+	"を": {"wo"},
+
+	// 撥音。"n" 1文字の綴りは次の文字によって使えない場合がある (hatsuonCandidates を参照)
+	"ん": {"n", "nn", "xn", "n'"},
+
+	// 濁音・半濁音
+	"が": {"ga"},
+	"ぎ": {"gi"},
+	"ぐ": {"gu"},
+	"げ": {"ge"},
+	"ご": {"go"},
+	"ざ": {"za"},
+	"じ": {"zi", "ji"},
+	"ず": {"zu"},
+	"ぜ": {"ze"},
+	"ぞ": {"zo"},
+	"だ": {"da"},
+	"ぢ": {"di"},
+	"づ": {"du"},
+	"で": {"de"},
+	"ど": {"do"},
+	"ば": {"ba"},
+	"び": {"bi"},
+	"ぶ": {"bu"},
+	"べ": {"be"},
+	"ぼ": {"bo"},
+	"ぱ": {"pa"},
+	"ぴ": {"pi"},
+	"ぷ": {"pu"},
+	"ぺ": {"pe"},
+	"ぽ": {"po"},
+	"ゔ": {"vu"},
+
+	// 小書きの仮名 (単独で入力する場合)
+	"ぁ": {"xa", "la"},
+	"ぃ": {"xi", "li", "xyi", "lyi"},
+	"ぅ": {"xu", "lu"},
+	"ぇ": {"xe", "le", "xye", "lye"},
+	"ぉ": {"xo", "lo"},
+	"ゃ": {"xya", "lya"},
+	"ゅ": {"xyu", "lyu"},
+	"ょ": {"xyo", "lyo"},
+	"ゎ": {"xwa", "lwa"},
+	"ゕ": {"xka", "lka"},
+	"ゖ": {"xke", "lke"},
+	"っ": {"xtu", "ltu", "xtsu", "ltsu"},
+
+	// 拗音
+	"きゃ": {"kya"},
+	"きぃ": {"kyi"},
+	"きゅ": {"kyu"},
+	"きぇ": {"kye"},
+	"きょ": {"kyo"},
+	"ぎゃ": {"gya"},
+	"ぎぃ": {"gyi"},
+	"ぎゅ": {"gyu"},
+	"ぎぇ": {"gye"},
+	"ぎょ": {"gyo"},
+	"しゃ": {"sya", "sha"},
+	"しぃ": {"syi"},
+	"しゅ": {"syu", "shu"},
+	"しぇ": {"sye", "she"},
+	"しょ": {"syo", "sho"},
+	"じゃ": {"zya", "ja", "jya"},
+	"じぃ": {"zyi", "jyi"},
+	"じゅ": {"zyu", "ju", "jyu"},
+	"じぇ": {"zye", "je", "jye"},
+	"じょ": {"zyo", "jo", "jyo"},
+	"ちゃ": {"tya", "cha", "cya"},
+	"ちぃ": {"tyi", "cyi"},
+	"ちゅ": {"tyu", "chu", "cyu"},
+	"ちぇ": {"tye", "che", "cye"},
+	"ちょ": {"tyo", "cho", "cyo"},
+	"ぢゃ": {"dya"},
+	"ぢぃ": {"dyi"},
+	"ぢゅ": {"dyu"},
+	"ぢぇ": {"dye"},
+	"ぢょ": {"dyo"},
+	"にゃ": {"nya"},
+	"にぃ": {"nyi"},
+	"にゅ": {"nyu"},
+	"にぇ": {"nye"},
+	"にょ": {"nyo"},
+	"ひゃ": {"hya"},
+	"ひぃ": {"hyi"},
+	"ひゅ": {"hyu"},
+	"ひぇ": {"hye"},
+	"ひょ": {"hyo"},
+	"びゃ": {"bya"},
+	"びぃ": {"byi"},
+	"びゅ": {"byu"},
+	"びぇ": {"bye"},
+	"びょ": {"byo"},
+	"ぴゃ": {"pya"},
+	"ぴぃ": {"pyi"},
+	"ぴゅ": {"pyu"},
+	"ぴぇ": {"pye"},
+	"ぴょ": {"pyo"},
+	"みゃ": {"mya"},
+	"みぃ": {"myi"},
+	"みゅ": {"myu"},
+	"みぇ": {"mye"},
+	"みょ": {"myo"},
+	"りゃ": {"rya"},
+	"りぃ": {"ryi"},
+	"りゅ": {"ryu"},
+	"りぇ": {"rye"},
+	"りょ": {"ryo"},
+
+	// 外来語の表記などに使う組み合わせ
+	"いぇ": {"ye"},
+	"うぁ": {"wha"},
+	"うぃ": {"wi", "whi"},
+	"うぇ": {"we", "whe"},
+	"うぉ": {"who"},
+	"ゔぁ": {"va"},
+	"ゔぃ": {"vi", "vyi"},
+	"ゔぇ": {"ve", "vye"},
+	"ゔぉ": {"vo"},
+	"ゔゃ": {"vya"},
+	"ゔゅ": {"vyu"},
+	"ゔょ": {"vyo"},
+	"くぁ": {"qa", "qwa", "kwa"},
+	"くぃ": {"qi", "qwi", "qyi"},
+	"くぅ": {"qwu"},
+	"くぇ": {"qe", "qwe", "qye"},
+	"くぉ": {"qo", "qwo"},
+	"くゃ": {"qya"},
+	"くゅ": {"qyu"},
+	"くょ": {"qyo"},
+	"ぐぁ": {"gwa"},
+	"ぐぃ": {"gwi"},
+	"ぐぅ": {"gwu"},
+	"ぐぇ": {"gwe"},
+	"ぐぉ": {"gwo"},
+	"すぁ": {"swa"},
+	"すぃ": {"swi"},
+	"すぅ": {"swu"},
+	"すぇ": {"swe"},
+	"すぉ": {"swo"},
+	"つぁ": {"tsa"},
+	"つぃ": {"tsi"},
+	"つぇ": {"tse"},
+	"つぉ": {"tso"},
+	"てゃ": {"tha"},
+	"てぃ": {"thi"},
+	"てゅ": {"thu"},
+	"てぇ": {"the"},
+	"てょ": {"tho"},
+	"でゃ": {"dha"},
+	"でぃ": {"dhi"},
+	"でゅ": {"dhu"},
+	"でぇ": {"dhe"},
+	"でょ": {"dho"},
+	"とぁ": {"twa"},
+	"とぃ": {"twi"},
+	"とぅ": {"twu"},
+	"とぇ": {"twe"},
+	"とぉ": {"two"},
+	"どぁ": {"dwa"},
+	"どぃ": {"dwi"},
+	"どぅ": {"dwu"},
+	"どぇ": {"dwe"},
+	"どぉ": {"dwo"},
+	"ふぁ": {"fa", "fwa"},
+	"ふぃ": {"fi", "fwi", "fyi"},
+	"ふぅ": {"fwu"},
+	"ふぇ": {"fe", "fwe", "fye"},
+	"ふぉ": {"fo", "fwo"},
+	"ふゃ": {"fya"},
+	"ふゅ": {"fyu"},
+	"ふょ": {"fyo"},
+
+	// 記号 (全角の英数字・記号は normalizeKana で半角に変換するため不要)
+	"ー": {"-"},
+	"、": {","},
+	"。": {"."},
+	"〜": {"~"},
+	"「": {"["},
+	"」": {"]"},
+	"・": {"/"},
+	"　": {" "},
+}
+
+func init() {
+	addSeparateSpellings(RomajiMap)
+}
+
+// addSeparateSpellings は表の2文字の要素 (拗音など) に、1文字目と2文字目を1文字ずつ入力する綴りを追加します
+// (例: 「きゅ」に "ki" + "xyu" の "kixyu" と "ki" + "lyu" の "kilyu")。
+//
+// 引数:
+//   - table: ローマ字の対応表。直接書き換えます。
+func addSeparateSpellings(table RomajiTable) {
+	for unit, words := range table {
+		runes := []rune(unit)
+		if len(runes) != 2 {
+			continue
+		}
+		for _, first := range table[string(runes[0])] {
+			for _, second := range table[string(runes[1])] {
+				if word := first + second; !slices.Contains(words, word) {
+					words = append(words, word)
+				}
+			}
+		}
+		table[unit] = words
+	}
+}
@@ -0,0 +1,186 @@
+package typing
+
+import "time"
+
+// タイピングの記録の設定です。
+const (
+	HistoryLimit = 100 // 保存するセッションの結果の上限 (古いものから削除)
+	TrendWindow  = 5   // 傾向を計算する直近のセッションの数
+	charsPerWord = 5   // WPM の計算で1語とみなす文字数
+)
+
+// モードごとの結果のキーです (SessionResult と History.Bests の JSON のキー)。
+const (
+	ModeKeyEnglish  = "en" // 英語
+	ModeKeyJapanese = "jp" // 日本語
+)
+
+// now は現在時刻を返す関数です。テストで差し替えられるように変数にしています。
+var now = time.Now
+
+// ModeKey はモード (ModeEnglish または ModeJapanese) の結果のキーを返します。無効なモードの場合は空文字列を返します。
+func ModeKey(mode int) string {
+	switch mode {
+	case ModeEnglish:
+		return ModeKeyEnglish
+	case ModeJapanese:
+		return ModeKeyJapanese
+	}
+	return ""
+}
+
+// ModeResult は英語または日本語の入力の結果です。複数の問題の結果は Add で合計します。
+// 時間は各問題の最初のキー入力から入力が完了したキー入力までの時間 (JavaScriptのキー入力の時刻の差) です。
+type ModeResult struct {
+	Chars      int   `json:"chars"`      // 入力した文字数 (CurrentDataArrayE/J の要素数)
+	Keystrokes int   `json:"keystrokes"` // キー入力の総数 (誤りを含む)
+	Mistakes   int   `json:"mistakes"`   // 誤ったキー入力の数
+	Duration   int64 `json:"duration"`   // 入力にかかった時間 (ミリ秒)
+}
+
+// Add は2つの結果を合計した結果を返します。
+func (r ModeResult) Add(other ModeResult) ModeResult {
+	return ModeResult{
+		Chars:      r.Chars + other.Chars,
+		Keystrokes: r.Keystrokes + other.Keystrokes,
+		Mistakes:   r.Mistakes + other.Mistakes,
+		Duration:   r.Duration + other.Duration,
+	}
+}
+
+// perMinute は1分あたりの数を計算します。時間が 0 の場合は 0 を返します。
+func (r ModeResult) perMinute(n int) float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(n) * float64(time.Minute/time.Millisecond) / float64(r.Duration)
+}
+
+// CPM は1分あたりに入力した文字数を返します。
+func (r ModeResult) CPM() float64 {
+	return r.perMinute(r.Chars)
+}
+
+// WPM は1分あたりに入力した語数 (charsPerWord 文字を1語とする) を返します。
+func (r ModeResult) WPM() float64 {
+	return r.CPM() / charsPerWord
+}
+
+// KPM は1分あたりの正しいキー入力の数を返します。
+func (r ModeResult) KPM() float64 {
+	return r.perMinute(r.Keystrokes - r.Mistakes)
+}
+
+// Accuracy はキー入力の正確さ (0.0〜1.0) を返します。キー入力がない場合は 0 を返します。
+func (r ModeResult) Accuracy() float64 {
+	return QuestionStats{Keystrokes: r.Keystrokes, Mistakes: r.Mistakes}.Accuracy()
+}
+
+// SessionResult はタイピングの1セッション (CreateTyping から次の CreateTyping まで) の結果です。
+// ローカルの履歴として JSON で保存します。
+type SessionResult struct {
+	Date      int64      `json:"date"`      // セッションの開始時刻 (Unix ミリ秒)。セッションの識別にも使用します
+	Questions int        `json:"questions"` // 入力が完了した問題の数
+	English   ModeResult `json:"en"`        // 英語の結果の合計
+	Japanese  ModeResult `json:"jp"`        // 日本語の結果の合計
+}
+
+// Mode はモードのキー (ModeKeyEnglish または ModeKeyJapanese) に対応する結果を返します。
+func (s SessionResult) Mode(key string) ModeResult {
+	if key == ModeKeyJapanese {
+		return s.Japanese
+	}
+	return s.English
+}
+
+// PersonalBest はモードごとの自己ベスト (CPM が最も高いセッションの結果) です。
+type PersonalBest struct {
+	Date   int64      `json:"date"`   // セッションの開始時刻 (Unix ミリ秒)
+	Result ModeResult `json:"result"` // そのセッションのモードの結果
+}
+
+// History はタイピングのセッションの結果の履歴と、モードごとの自己ベストです。ローカルの履歴として JSON で保存します。
+type History struct {
+	Sessions []SessionResult         `json:"sessions"` // セッションの結果 (古い順、最大 HistoryLimit 件)
+	Bests    map[string]PersonalBest `json:"bests"`    // モードのキーから自己ベストへの対応表
+}
+
+// Record はセッションの結果を履歴に記録します。同じセッション (Date が同じ) の結果がすでにある場合は置き換えるため、
+// 問題を解くたびに呼び出して途中の結果を保存できます。
+// CPM が自己ベストを上回ったモードは自己ベストを更新します。
+//
+// 戻り値:
+//   - 自己ベストを更新したモードのキーの一覧。
+func (h *History) Record(session SessionResult) []string {
+	if n := len(h.Sessions); n > 0 && h.Sessions[n-1].Date == session.Date {
+		h.Sessions[n-1] = session
+	} else {
+		h.Sessions = append(h.Sessions, session)
+	}
+	if len(h.Sessions) > HistoryLimit {
+		h.Sessions = h.Sessions[len(h.Sessions)-HistoryLimit:]
+	}
+
+	var updated []string
+	for _, key := range []string{ModeKeyEnglish, ModeKeyJapanese} {
+		result := session.Mode(key)
+		if result.CPM() <= 0 {
+			continue
+		}
+		if best, ok := h.Bests[key]; ok && best.Result.CPM() >= result.CPM() {
+			continue
+		}
+		if h.Bests == nil {
+			h.Bests = make(map[string]PersonalBest)
+		}
+		h.Bests[key] = PersonalBest{Date: session.Date, Result: result}
+		updated = append(updated, key)
+	}
+	return updated
+}
+
+// Trend は直近のセッションの結果と、その前のセッションからの変化です。
+type Trend struct {
+	Recent   ModeResult // 直近 TrendWindow 件のセッションの結果の合計
+	Previous ModeResult // その前の TrendWindow 件のセッションの結果の合計
+}
+
+// CPMChange は直近のセッションの CPM から、その前のセッションの CPM を引いた値を返します。
+// その前のセッションがない場合は 0 を返します。
+func (t Trend) CPMChange() float64 {
+	if t.Previous.Duration <= 0 {
+		return 0
+	}
+	return t.Recent.CPM() - t.Previous.CPM()
+}
+
+// AccuracyChange は直近のセッションの正確さから、その前のセッションの正確さを引いた値を返します。
+// その前のセッションがない場合は 0 を返します。
+func (t Trend) AccuracyChange() float64 {
+	if t.Previous.Keystrokes == 0 {
+		return 0
+	}
+	return t.Recent.Accuracy() - t.Previous.Accuracy()
+}
+
+// Trend はモードの結果の傾向を計算します。入力した文字のないセッション (そのモードを完了していないセッション) は除きます。
+//
+// 引数:
+//   - key: モードのキー (ModeKeyEnglish または ModeKeyJapanese)。
+func (h *History) Trend(key string) Trend {
+	var results []ModeResult
+	for _, s := range h.Sessions {
+		if r := s.Mode(key); r.Chars > 0 {
+			results = append(results, r)
+		}
+	}
+	var trend Trend
+	for i := len(results) - 1; i >= 0 && i >= len(results)-2*TrendWindow; i-- {
+		if i >= len(results)-TrendWindow {
+			trend.Recent = trend.Recent.Add(results[i])
+		} else {
+			trend.Previous = trend.Previous.Add(results[i])
+		}
+	}
+	return trend
+}
@@ -0,0 +1,188 @@
+package typing
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ローマ字の対応表のプリセットの名前です。
+const (
+	PresetStandard = "standard" // 標準のローマ字入力 (RomajiMap)
+	PresetAZIK     = "azik"     // AZIK (標準の綴りに AZIK の拡張ローマ字の綴りを追加)
+	PresetKana     = "kana"     // かな入力 (JIS かな配列などで、キーに割り当てられたかな文字をそのまま入力)
+)
+
+// azikSpellings は AZIK で標準のローマ字入力に追加される綴りです。
+// AZIK の撥音拡張 (例: "kz" で「かん」) と二重母音拡張 (例: "kq" で「かい」) は
+// 複数の文字にまたがり、文字ごとに判定する Input では扱えないため含めません。
+var azikSpellings = RomajiTable{
+	"ん":  {"q"},
+	"っ":  {";"},
+	"し":  {"xi"},
+	"しゃ": {"xa"},
+	"しゅ": {"xu"},
+	"しぇ": {"xe"},
+	"しょ": {"xo"},
+	"ち":  {"ci"},
+	"ちゃ": {"ca"},
+	"ちゅ": {"cu"},
+	"ちぇ": {"ce"},
+	"ちょ": {"co"},
+	"てぃ": {"tgi"},
+	"でぃ": {"dci"},
+}
+
+// 濁音・半濁音と、かな入力で続けて入力する清音の組み合わせです。
+const (
+	voicedKana   = "がぎぐげござじずぜぞだぢづでどばびぶべぼゔ"
+	unvoicedKana = "かきくけこさしすせそたちつてとはひふへほう"
+	semiKana     = "ぱぴぷぺぽ"
+	semiBaseKana = "はひふへほ"
+)
+
+// smallKana は拗音などで2文字目になる小書きの仮名です。
+const smallKana = "ぁぃぅぇぉゃゅょゎ"
+
+// Preset は名前に対応するプリセットの対応表を返します。
+//
+// 引数:
+//   - name: PresetStandard、PresetAZIK、PresetKana のいずれか。
+//
+// 戻り値:
+//   - 対応表と、名前が有効な場合は true。
+func Preset(name string) (RomajiTable, bool) {
+	switch name {
+	case PresetStandard:
+		return RomajiMap, true
+	case PresetAZIK:
+		return azikTable(), true
+	case PresetKana:
+		return kanaTable(), true
+	}
+	return nil, false
+}
+
+// azikTable は RomajiMap に azikSpellings を追加した対応表を作成します。
+func azikTable() RomajiTable {
+	table := cloneTable(RomajiMap)
+	for unit, words := range azikSpellings {
+		table[unit] = append(table[unit], words...)
+	}
+	addSeparateSpellings(table)
+	return table
+}
+
+// kanaTable はかな入力の対応表を作成します。かなはその文字自体を綴りとし、
+// 濁音・半濁音は清音に続けて「゛」「゜」を入力する綴りも含めます (例: 「が」は "が" または "か゛")。
+// 記号はその文字自体に加えて RomajiMap の綴り (例: 「ー」の "-") でも入力できます。
+func kanaTable() RomajiTable {
+	voiced, unvoiced := []rune(voicedKana), []rune(unvoicedKana)
+	semi, semiBase := []rune(semiKana), []rune(semiBaseKana)
+	table := make(RomajiTable, len(RomajiMap))
+	for unit, words := range RomajiMap {
+		runes := []rune(unit)
+		switch {
+		case len(runes) == 2:
+			// 拗音などは addSeparateSpellings で1文字ずつ入力する綴りを追加する
+			table[unit] = nil
+		case runes[0] >= 'ぁ' && runes[0] <= 'ゖ':
+			table[unit] = []string{unit}
+			if i := slices.Index(voiced, runes[0]); i >= 0 {
+				table[unit] = append(table[unit], string(unvoiced[i])+"゛")
+			}
+			if i := slices.Index(semi, runes[0]); i >= 0 {
+				table[unit] = append(table[unit], string(semiBase[i])+"゜")
+			}
+		default:
+			table[unit] = append([]string{unit}, words...)
+		}
+	}
+	addSeparateSpellings(table)
+	return table
+}
+
+// ParseRomajiTable は Google 日本語入力の形式 (TSV) のローマ字テーブルを読み込みます。
+// 各行は「入力<TAB>出力」または「入力<TAB>出力<TAB>次の入力」で、空行と # で始まる行は無視します。
+//
+// 次のエントリは入力の判定で扱えないため読み飛ばし、その数を返します。
+//   - 出力が1文字 (拗音などは2文字) の単位にならないエントリ (例: "kz" で「かん」)。
+//   - 「次の入力」があるエントリ。ただし「っ」の子音の重ね打ち (例: "kk" で「っ」、次の入力 "k") は
+//     入力の判定で扱うため数えず、「ん」の "n" 1文字の入力 (例: "nk" で「ん」、次の入力 "k") は "n" の綴りとして読み込みます
+//     (入力が次の入力と同じで綴りが空になるエントリ、例: "n" で「ん」、次の入力 "n" は読み飛ばします)。
+//
+// テーブルにない文字は RomajiMap の綴りで補い、2文字の単位には1文字ずつ入力する綴りを追加します。
+//
+// 引数:
+//   - text: TSV 形式のローマ字テーブル。
+//
+// 戻り値:
+//   - 読み込んだ対応表。
+//   - 読み飛ばしたエントリの数。
+//   - 形式が正しくない行がある場合や、有効なエントリがない場合はエラー。
+func ParseRomajiTable(text string) (RomajiTable, int, error) {
+	table := make(RomajiTable)
+	skipped := 0
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			return nil, 0, fmt.Errorf("%d行目: 入力と出力をタブで区切る必要があります", n+1)
+		}
+		input, output := fields[0], normalizeKana(fields[1])
+		if len(fields) > 2 && fields[2] != "" {
+			switch {
+			case output == "ん" && len(input) > len(fields[2]) && strings.HasSuffix(input, fields[2]):
+				input = strings.TrimSuffix(input, fields[2])
+			case output == "っ":
+				continue
+			default:
+				skipped++
+				continue
+			}
+		}
+		if !isTableUnit(output) {
+			skipped++
+			continue
+		}
+		if !slices.Contains(table[output], input) {
+			table[output] = append(table[output], input)
+		}
+	}
+	if len(table) == 0 {
+		return nil, skipped, errors.New("有効なエントリがありません")
+	}
+	for unit, words := range RomajiMap {
+		if _, exists := table[unit]; !exists {
+			table[unit] = slices.Clone(words)
+		}
+	}
+	addSeparateSpellings(table)
+	return table, skipped, nil
+}
+
+// isTableUnit は出力が対応表の単位 (1文字、または2文字目が小書きの仮名の2文字) かどうかを判定します。
+func isTableUnit(output string) bool {
+	runes := []rune(output)
+	switch len(runes) {
+	case 1:
+		return true
+	case 2:
+		return strings.ContainsRune(smallKana, runes[1])
+	}
+	return false
+}
+
+// cloneTable は対応表の複製を作成します。綴りのスライスも複製するため、複製への追加は元の対応表に影響しません。
+func cloneTable(table RomajiTable) RomajiTable {
+	clone := maps.Clone(table)
+	for unit, words := range clone {
+		clone[unit] = slices.Clone(words)
+	}
+	return clone
+}
@@ -2,228 +2,98 @@ package typing
 
 import (
 	"english_app_for_japanese/wasm/objects"
+	"math/rand/v2"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-// RomajiMap はひらがな（および一部記号）と対応するローマ字入力の組み合わせを保持するマップです。
-// 1つのひらがなに対して複数のローマ字入力（例: し -> si, shi, ci）が存在する場合も考慮されています。
-// 拗音（きゃ、きゅ、きょなど）や促音（っ）、長音（ー）なども含まれます。
-var RomajiMap = map[string][]string{
-	"あ":  {"a"},
-	"い":  {"i"},
-	"う":  {"u", "wu", "whu"},
-	"え":  {"e"},
-	"お":  {"o"},
-	"か":  {"ka", "ca"},
-	"き":  {"ki"},
-	"く":  {"ku", "cu"},
-	"け":  {"ke"},
-	"こ":  {"ko", "co"},
-	"さ":  {"sa"},
-	"し":  {"si", "shi", "ci"},
-	"す":  {"su"},
-	"せ":  {"se", "ce"},
-	"そ":  {"so"},
-	"た":  {"ta"},
-	"ち":  {"ti", "chi"},
-	"つ":  {"tu", "tsu"},
-	"て":  {"te"},
-	"と":  {"to"},
-	"な":  {"na"},
-	"に":  {"ni"},
-	"ぬ":  {"nu"},
-	"ね":  {"ne"},
-	"の":  {"no"},
-	"は":  {"ha"},
-	"ひ":  {"hi"},
-	"ふ":  {"hu", "fu"},
-	"へ":  {"he"},
-	"ほ":  {"ho"},
-	"ま":  {"ma"},
-	"み":  {"mi"},
-	"む":  {"mu"},
-	"め":  {"me"},
-	"も":  {"mo"},
-	"や":  {"ya"},
-	"ゆ":  {"yu"},
-	"よ":  {"yo"},
-	"ら":  {"ra"},
-	"り":  {"ri"},
-	"る":  {"ru"},
-	"れ":  {"re"},
-	"ろ":  {"ro"},
-	"わ":  {"wa"},
-	"を":  {"wo"},
-	"ん":  {"n", "nn"},
-	"が":  {"ga"},
-	"ぎ":  {"gi"},
-	"ぐ":  {"gu"},
-	"げ":  {"ge"},
-	"ご":  {"go"},
-	"ざ":  {"za"},
-	"じ":  {"zi", "ji"},
-	"ず":  {"zu"},
-	"ぜ":  {"ze"},
-	"ぞ":  {"zo"},
-	"だ":  {"da"},
-	"ぢ":  {"di"},
-	"づ":  {"du"},
-	"で":  {"de"},
-	"ど":  {"do"},
-	"ば":  {"ba"},
-	"び":  {"bi"},
-	"ぶ":  {"bu"},
-	"べ":  {"be"},
-	"ぼ":  {"bo"},
-	"ぱ":  {"pa"},
-	"ぴ":  {"pi"},
-	"ぷ":  {"pu"},
-	"ぺ":  {"pe"},
-	"ぽ":  {"po"},
-	"ゔ":  {"vu"},
-	"うぁ": {"wha", "uxa", "ula"},
-	"うぃ": {"whi", "wi", "uxi", "uli"},
-	"うぇ": {"whe", "we", "uxe", "ule"},
-	"うぉ": {"who", "uxo", "ulo"},
-	"ゔぁ": {"va", "vuxa", "vula"},
-	"ゔぃ": {"vi", "vuxi", "vuli"},
-	"ゔぇ": {"ve", "vuxe", "vule"},
-	"ゔぉ": {"vo", "vuxo", "vulo"},
-	"いぇ": {"ye", "ixe", "ile"},
-	"きゃ": {"kixya", "kilya", "kya"},
-	"きぃ": {"kixi", "kili", "kyi"},
-	"きゅ": {"kixyu", "kilyu", "kyu"},
-	"きぇ": {"kixe", "kye", "kile"},
-	"きょ": {"kyo", "kilyo", "kixyo"},
-	"ぎゃ": {"gilya", "gya", "gixya"},
-	"ぎぃ": {"gyi", "gixi", "gili"},
-	"ぎゅ": {"gilyu", "gixyu", "gyu"},
-	"ぎぇ": {"gile", "gye", "gixe"},
-	"ぎょ": {"gixyo", "gilyo", "gyo"},
-	"しゃ": {"shilya", "shixya", "cixya", "silya", "sixya", "sya", "cilya", "sha"},
-	"しぃ": {"cixi", "sili", "syi", "shixi", "shili", "cili", "sixi"},
-	"しゅ": {"sixyu", "syu", "shixyu", "shilyu", "cixyu", "shu", "silyu", "cilyu"},
-	"しぇ": {"sixe", "shile", "cile", "sye", "she", "sile", "shixe", "cixe"},
-	"しょ": {"shixyo", "cixyo", "cilyo", "sixyo", "silyo", "sho", "shilyo", "syo"},
-	"じゃ": {"zilya", "jya", "zixya", "zya", "jixya", "ja", "jilya"},
-	"じぃ": {"zili", "jixi", "zixi", "zyi", "jili", "jyi"},
-	"じゅ": {"jilyu", "jixyu", "ju", "jyu", "zilyu", "zyu", "zixyu"},
-	"じぇ": {"zile", "jixe", "zye", "je", "jile", "zixe", "jye"},
-	"じょ": {"zyo", "jyo", "jilyo", "zixyo", "jixyo", "jo", "zilyo"},
-	"ちゃ": {"cya", "tya", "tixya", "chilya", "chixya", "tilya"},
-	"ちぃ": {"cyi", "tili", "tixi", "tyi", "chixi", "chili"},
-	"ちゅ": {"cyu", "tyu", "tilyu", "tixyu", "chilyu", "chixyu"},
-	"ちぇ": {"cye", "tixe", "tile", "chile", "chixe", "tye"},
-	"ちょ": {"cyo", "chixyo", "chilyo", "tixyo", "tilyo", "tyo"},
-	"ぢゃ": {"dilya", "dya", "dixya"},
-	"ぢぃ": {"dyi", "dixi", "dili"},
-	"ぢゅ": {"dyu", "dixyu", "dilyu"},
-	"ぢぇ": {"dile", "dixe", "dye"},
-	"ぢょ": {"dyo", "dixyo", "dilyo"},
-	"てゃ": {"texya", "telya", "tha"},
-	"てぃ": {"teli", "thi", "texi"},
-	"てゅ": {"texyu", "telyu", "thu"},
-	"てぇ": {"tele", "texe", "the"},
-	"てょ": {"texyo", "telyo", "tho"},
-	"でゃ": {"dha", "dexya", "delya"},
-	"でぃ": {"dhi", "deli", "dexi"},
-	"でゅ": {"dexyu", "delyu", "dhu"},
-	"でぇ": {"dele", "dexe", "dhe"},
-	"でょ": {"dho", "delyo", "dexyo"},
-	"にゃ": {"nilya", "nya", "nixya"},
-	"にぃ": {"nili", "nyi", "nixi"},
-	"にゅ": {"nixyu", "nyu", "nilyu"},
-	"にぇ": {"nye", "nixe", "nile"},
-	"にょ": {"nilyo", "nixyo", "nyo"},
-	"ひゃ": {"hilya", "hya", "hixya"},
-	"ひぃ": {"hyi", "hili", "hixi"},
-	"ひゅ": {"hixyu", "hilyu", "hyu"},
-	"ひぇ": {"hye", "hile", "hixe"},
-	"ひょ": {"hixyo", "hyo", "hilyo"},
-	"びゃ": {"bya", "bixya", "bilya"},
-	"びぃ": {"byi", "bili", "bixi"},
-	"びゅ": {"bixyu", "bilyu", "byu"},
-	"びぇ": {"bye", "bile", "bixe"},
-	"びょ": {"byo", "bixyo", "bilyo"},
-	"ぴゃ": {"pilya", "pixya", "pya"},
-	"ぴぃ": {"pixi", "pili", "pyi"},
-	"ぴゅ": {"pilyu", "pyu", "pixyu"},
-	"ぴぇ": {"pye", "pixe", "pile"},
-	"ぴょ": {"pyo", "pilyo", "pixyo"},
-	"ふぁ": {"hula", "fula", "huxa", "fuxa", "fa"},
-	"ふぃ": {"fuxi", "huxi", "fuli", "huli", "fi"},
-	"ふぇ": {"fuxe", "huxe", "fe", "fule", "hule"},
-	"ふぉ": {"fo", "fuxo", "hulo", "fulo", "huxo"},
-	"ふゃ": {"hulya", "fya", "fuxya", "fulya", "huxya"},
-	"ふょ": {"fulyo", "huxyo", "fuxyo", "fyo", "hulyo"},
-	"みゃ": {"mixya", "milya", "mya"},
-	"みぃ": {"myi", "mili", "mixi"},
-	"みゅ": {"myu", "milyu", "mixyu"},
-	"みぇ": {"mile", "mixe", "mye"},
-	"みょ": {"myo", "mixyo", "milyo"},
-	"りゃ": {"rilya", "rixya", "rya"},
-	"りぃ": {"rili", "rixi", "ryi"},
-	"りゅ": {"rilyu", "rixyu", "ryu"},
-	"りぇ": {"rixe", "rile", "rye"},
-	"りょ": {"ryo", "rilyo", "rixyo"},
-	"とぅ": {"twu", "tolu", "toxu"},
-	"どぅ": {"dwu", "dolu", "doxu"},
-	"ぁ":  {"xa", "la"},
-	"ぃ":  {"xi", "li"},
-	"ぅ":  {"xu", "lu"},
-	"ぇ":  {"xe", "le"},
-	"ぉ":  {"xo", "lo"},
-	"ゃ":  {"xya", "lya"},
-	"ゅ":  {"xyu", "lyu"},
-	"ょ":  {"xyo", "lyo"},
-	"ゎ":  {"xwa", "lwa"},
-	"っ":  {"xtu", "ltu"},
-	"ー":  {"-"},
-	"、":  {","},
-	"。":  {"."},
-	"？":  {"?"},
-	"！":  {"!"},
-	"〜":  {"~"},
-	"（":  {"("},
-	"）":  {")"},
-	"「":  {"["},
-	"」":  {"]"},
-	"・":  {"/"},
-	"；":  {";"},
-	"：":  {":"},
-	"　":  {" "},
+// Practice はタイピングで入力する文の種類です。
+type Practice string
+
+const (
+	PracticeSentence Practice = "sentence" // 例文 (ExampleEn と Kana) を入力する (既定値)
+	PracticeWord     Practice = "word"     // 見出し語 (Word) と、かなのみの場合は日本語の意味 (DefinitionJa) を入力する
+)
+
+// IsValidPractice は文字列が有効な Practice かどうかを判定します。
+func IsValidPractice(s string) bool {
+	switch Practice(s) {
+	case PracticeSentence, PracticeWord:
+		return true
+	}
+	return false
 }
 
 // Typing はタイピングゲームのデータと状態を管理する構造体です。
 type Typing struct {
 	appData           *objects.AppData // アプリケーション全体のデータへのポインタ
+	Filter            objects.Filter   // 現在選択されている問題の絞り込み条件
 	FilteredArray     []objects.Datum  // シャッフルされた問題データのスライス
 	CurrentData       *objects.Datum   // 現在表示中の問題データへのポインタ
 	CurrentDataArrayE []string         // 現在の問題の英語例文 (En2) を文字単位に分割したスライス
 	CurrentDataArrayJ []string         // 現在の問題の日本語かな (Kana) を文字単位（拗音含む）に分割したスライス
+	inputE            *Input           // 英語の入力の状態
+	inputJ            *Input           // 日本語の入力の状態
+	Recorder          Recorder         // 現在のセッションのキー入力の記録
+	Style             RomajiStyle      // ローマ字のガイドで優先する綴りの方式 (空の場合は StyleHepburn)
+	Table             RomajiTable      // 日本語の入力に使うローマ字の対応表 (nil の場合は RomajiMap)。次の問題の設定 (SetData) から有効になります
+	Seed              *uint64          // 乱数のシード (nil の場合はグローバルな乱数生成器を使用)
+	Practice          Practice         // 入力する文の種類 (空の場合は PracticeSentence)
+	English           EnglishOptions   // 英語の入力の判定の設定。次の問題の設定 (SetData) から有効になります
+	MinLength         int              // 入力する英語の文字数の下限 (0 の場合は制限なし)
+	MaxLength         int              // 入力する英語の文字数の上限 (0 の場合は制限なし)
+	Recording         Recording        // 現在の問題のキー入力の記録 (リプレイ用)
+	Recordings        []Recording      // 現在のセッションで入力した (次の問題に移った) 問題の記録 (出題順)
+	Ghost             *Recording       // 現在の問題のゴースト (同じ問題文の自己ベストの記録、ない場合は nil)
+	Ghosts            Ghosts           // 問題文ごとの自己ベストの記録。SetData で現在の問題のゴーストを選びます
 }
 
 // Init は Typing 構造体を初期化します。
-// アプリケーションデータ全体をシャッフルし、FilteredArray に格納します。
+// 指定された絞り込み条件に一致するデータを並び替えて (既定ではシャッフルして) FilteredArray に格納します。
+// Practice で入力する英語 (例文または見出し語) がないデータと、英語の文字数が MinLength〜MaxLength の範囲外のデータは除きます。
+// Seed が設定されている場合は、そのシードで作成した乱数生成器で並び替えるため、同じシードでは同じ順序になります。
 //
 // 引数:
 //   - appData: アプリケーション全体のデータ (objects.AppData) へのポインタ。
-func (t *Typing) Init(appData *objects.AppData) {
+//   - filter: 問題の絞り込み条件。Excluded に objects.ExcludedAll を指定するとアプリケーションデータ全体が対象になります。
+func (t *Typing) Init(appData *objects.AppData, filter objects.Filter) {
 	t.appData = appData
-	// アプリケーションデータ全体をシャッフルしてタイピング問題リストとする
-	t.FilteredArray = objects.ShuffleCopy(t.appData.Data)
+	t.Filter = filter
+	var rng *rand.Rand
+	if t.Seed != nil {
+		rng = objects.NewRand(*t.Seed)
+	}
+	// 絞り込み条件に一致するデータをタイピング問題リストとする
+	t.FilteredArray = slices.DeleteFunc(t.appData.QueryRand(t.Filter, rng), func(d objects.Datum) bool {
+		n := utf8.RuneCountInString(t.typingText(&d))
+		return n == 0 || (t.MinLength > 0 && n < t.MinLength) || (t.MaxLength > 0 && n > t.MaxLength)
+	})
+	// キー入力の記録はセッションごとに集計する
+	t.Recorder = Recorder{Started: now()}
+	t.Recording = Recording{}
+	t.Recordings = nil
 }
 
 // SetData は指定されたインデックスに対応する問題データを設定します。
 // FilteredArray から該当する Datum を CurrentData に設定し、
-// その Datum の En2 (英語例文) と Kana (日本語かな) を
-// それぞれ文字単位に分割したスライス (CurrentDataArrayE, CurrentDataArrayJ) を生成します。
-// インデックスが範囲外の場合は、有効な範囲内に調整されます。
+// その Datum の英語例文と日本語かな (単語の練習では見出し語と日本語の意味、typingText と typingKana を参照) を
+// それぞれ文字単位に分割したスライス (CurrentDataArrayE, CurrentDataArrayJ) を生成し、入力の状態を初期化します。
+// 前の問題にキー入力がある場合はその記録を Recordings に追加し、新しい問題の記録と、Ghosts にある同じ問題文のゴーストを設定します。
+// インデックスが範囲外の場合は、有効な範囲内に調整されます。FilteredArray が空の場合は CurrentData と文字配列を nil にします。
 //
 // 引数:
 //   - index: FilteredArray 内の問題データのインデックス。
 func (t *Typing) SetData(index int) {
 	allQuestions := len(t.FilteredArray)
+	if allQuestions == 0 {
+		t.CurrentData = nil
+		t.CurrentDataArrayE, t.CurrentDataArrayJ = nil, nil
+		t.inputE, t.inputJ = nil, nil
+		t.Ghost = nil
+		return
+	}
 	// インデックスが負の場合は0にする
 	if index < 0 {
 		index = 0
@@ -234,13 +104,60 @@ func (t *Typing) SetData(index int) {
 	}
 	// 現在の問題データを設定
 	t.CurrentData = &t.FilteredArray[index]
+	t.Recorder.Begin(t.CurrentData.ID)
 	// 英語と日本語の文字配列を生成
 	t.createCurrentDataArrayE()
 	t.createCurrentDataArrayJ()
+	if len(t.Recording.Keys) > 0 {
+		t.Recordings = append(t.Recordings, t.Recording)
+	}
+	t.Recording = newRecording(t.CurrentDataArrayE, t.CurrentDataArrayJ)
+	t.Ghost = nil
+	if ghost, ok := t.Ghosts[t.Recording.Text]; ok {
+		t.Ghost = &ghost
+	}
+}
+
+// Question は現在の問題の表示用の英語と日本語を返します。
+// 例文の練習では例文 (ExampleEn, ExampleJa)、単語の練習では見出し語と日本語の意味 (Word, DefinitionJa) です。
+// 問題が設定されていない場合は空文字列を返します。
+func (t *Typing) Question() (en, ja string) {
+	if t.CurrentData == nil {
+		return "", ""
+	}
+	if t.Practice == PracticeWord {
+		return t.CurrentData.Word, t.CurrentData.DefinitionJa
+	}
+	return t.CurrentData.ExampleEn, t.CurrentData.ExampleJa
+}
+
+// typingText は Practice に応じて、データの入力する英語を返します。
+func (t *Typing) typingText(d *objects.Datum) string {
+	if t.Practice == PracticeWord {
+		return d.Word
+	}
+	return d.ExampleEn
+}
+
+// typingKana は Practice に応じて、データの入力する日本語 (かな) を返します。
+// 単語の練習では見出し語の読みのデータがないため、日本語の意味がかなのみの場合にそれを使い、
+// 漢字などを含む場合は空文字列 (日本語の入力なし) を返します。
+func (t *Typing) typingKana(d *objects.Datum) string {
+	if t.Practice != PracticeWord {
+		return d.Kana
+	}
+	for _, r := range normalizeKana(d.DefinitionJa) {
+		if (r < 'ぁ' || r > 'ゖ') && r != 'ー' {
+			return ""
+		}
+	}
+	return d.DefinitionJa
 }
 
 // createDataArray は与えられたテキスト文字列を、タイピングに適した文字単位のスライスに分割します。
-// 特に日本語の場合、RomajiMap を参照して拗音（例: "きゃ"）などを1つの要素として扱います。
+// 特に日本語の場合、ローマ字の対応表 (Table) を参照して拗音（例: "きゃ"）などを1つの要素として扱います。
+// カタカナ (例: "ヴァ") も normalizeKana でひらがなに変換して参照するため、ひらがなと同じ単位に分割されます。
+// 要素は元の文字のまま (カタカナや全角文字のまま) 表示用に残し、入力の判定時に変換します。
 // 英語や記号は基本的に1文字ずつ分割されます。
 //
 // 引数:
@@ -251,14 +168,15 @@ func (t *Typing) SetData(index int) {
 func (t *Typing) createDataArray(text string) []string {
 	tmp := make([]string, 0, len([]rune(text))) // rune スライスのおおよその長さで初期化
 	runes := []rune(text)                       // 文字列を rune スライスに変換
+	table := t.romajiTable()
 	i := 0
 	for i < len(runes) {
 		// 次の文字が存在する場合
 		if i < len(runes)-1 {
 			// 現在の文字と次の文字を結合して2文字の文字列を作成
 			twoChars := string(runes[i : i+2])
-			// RomajiMap に2文字の組み合わせが存在するか確認 (拗音などのチェック)
-			if _, exists := RomajiMap[twoChars]; exists {
+			// 対応表に2文字の組み合わせが存在するか確認 (拗音などのチェック)
+			if _, exists := table[normalizeKana(twoChars)]; exists {
 				// 存在すれば2文字を1要素としてスライスに追加し、インデックスを2進める
 				tmp = append(tmp, twoChars)
 				i += 2
@@ -276,158 +194,132 @@ func (t *Typing) createDataArray(text string) []string {
 	return tmp
 }
 
-// createCurrentDataArrayE は現在の問題データ (CurrentData) の英語例文を
-// 文字単位に分割し、CurrentDataArrayE に格納します。
+// romajiTable は入力に使うローマ字の対応表を返します。Table が設定されていない場合は RomajiMap を返します。
+func (t *Typing) romajiTable() RomajiTable {
+	if t.Table == nil {
+		return RomajiMap
+	}
+	return t.Table
+}
+
+// normalizeKana はローマ字の対応表を参照するために、カタカナをひらがなに、全角英数字・記号を半角に変換します。
+// 変換するカタカナは「ァ」から「ヶ」まで (小書きの「ァィゥェォヵヶ」と「ヴ」を含む) で、
+// 長音「ー」や中黒「・」などはそのまま対応表のキーとして扱います。
+//
+// 引数:
+//   - s: 変換する文字列 (createDataArray の要素)。
+//
+// 戻り値:
+//   - 変換後の文字列。
+func normalizeKana(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'ァ' && r <= 'ヶ':
+			return r - ('ァ' - 'ぁ')
+		case r >= '！' && r <= '～':
+			return r - ('！' - '!')
+		}
+		return r
+	}, s)
+}
+
+// createCurrentDataArrayE は現在の問題データ (CurrentData) の英語例文 (単語の練習では見出し語) を
+// 文字単位に分割し、CurrentDataArrayE に格納します。CurrentDataArrayE は元の文字のまま残し、
+// 入力の判定は English の設定に従います (newEnglishInput を参照)。
 func (t *Typing) createCurrentDataArrayE() {
-	t.CurrentDataArrayE = t.createDataArray(t.CurrentData.ExampleEn)
+	t.CurrentDataArrayE = t.createDataArray(t.typingText(t.CurrentData))
+	t.inputE = newEnglishInput(t.CurrentDataArrayE, t.English)
 }
 
-// createCurrentDataArrayJ は現在の問題データ (CurrentData) の日本語かな (Kana) を
+// createCurrentDataArrayJ は現在の問題データ (CurrentData) の日本語かな (Kana、単語の練習では typingKana を参照) を
 // 文字単位（拗音などを考慮）に分割し、CurrentDataArrayJ に格納します。
 func (t *Typing) createCurrentDataArrayJ() {
-	t.CurrentDataArrayJ = t.createDataArray(t.CurrentData.Kana)
+	t.CurrentDataArrayJ = t.createDataArray(t.typingKana(t.CurrentData))
+	t.inputJ = NewInput(t.CurrentDataArrayJ, t.romajiTable())
+}
+
+// タイピングの対象 (KeyDown などの mode 引数) です。
+const (
+	ModeEnglish  = 1 // 英語 (CurrentDataArrayE) を対象とする
+	ModeJapanese = 2 // 日本語 (CurrentDataArrayJ) を対象とする
+)
+
+// Input は指定されたモードの入力の状態を返します。問題が設定されていない場合や無効なモードの場合は nil を返します。
+//
+// 引数:
+//   - mode: ModeEnglish または ModeJapanese。
+func (t *Typing) Input(mode int) *Input {
+	switch mode {
+	case ModeEnglish:
+		return t.inputE
+	case ModeJapanese:
+		return t.inputJ
+	}
+	return nil
 }
 
-// KeyDown はユーザーのキー入力 (userInput) を受け取り、現在のタイピング問題の
-// 指定されたインデックス (index) の文字と比較して、正解かどうかを判定します。
-// 正解の場合、次の文字に進むための新しいインデックスを返します。
-// 不正解、または入力がまだ完了していない場合は、現在のインデックスを返します。
-// ローマ字入力の判定（RomajiMapに基づく複数の入力パターン、促音「っ」、撥音「ん」の特殊処理）を行います。
+// Guide は指定されたモードの入力のガイド (入力済みのキーと、残りの入力のおすすめのキー) を返します。
+// 綴りは Style の方式を優先し、入力途中の文字は入力済みのキーにつながる綴りに切り替えます (Input.Guide を参照)。
 //
 // 引数:
-//   - userInput: ユーザーが入力した現在の文字列全体。
-//   - index: 現在判定対象となっている文字のインデックス (CurrentDataArrayE または CurrentDataArrayJ のインデックス)。
-//   - mode: 判定対象の配列を指定するモード。
-//   - 1: 英語 (CurrentDataArrayE) を対象とする。
-//   - 2: 日本語 (CurrentDataArrayJ) を対象とする。
+//   - mode: ModeEnglish または ModeJapanese。
 //
 // 戻り値:
-//   - 判定後の新しい文字インデックス。
-//   - 正解の場合: `index + 1` または `index + 2` (促音「っ」＋子音の場合など)。
-//   - 不正解または入力未完了の場合: `index`。
-func (t *Typing) KeyDown(userInput string, index, mode int) int {
-	var dataArry []string
-	// mode に基づいて判定対象の配列を選択
-	if mode == 1 {
-		dataArry = t.CurrentDataArrayE
-	} else if mode == 2 {
-		dataArry = t.CurrentDataArrayJ
-	} else {
-		// 無効な mode の場合は現在のインデックスを返す
-		return index
+//   - 入力済みのキーと残りの入力のおすすめのキー。問題が設定されていない場合や無効なモードの場合は空文字列。
+func (t *Typing) Guide(mode int) (typed, rest string) {
+	in := t.Input(mode)
+	if in == nil {
+		return "", ""
 	}
-
-	sliceLength := len(dataArry)
-
-	// 配列が空、またはインデックスが範囲外の場合は現在のインデックスを返す
-	if sliceLength == 0 || index >= sliceLength {
-		return index
+	style := t.Style
+	if style == "" {
+		style = StyleHepburn
 	}
+	return in.Guide(style)
+}
 
-	// 判定対象の文字（または文字列、例: "きゃ"）を取得
-	targetElement := dataArry[index]
+// KeyDown はユーザーのキー入力を1つ受け取り、現在のタイピング問題の入力を進めて、Recorder に記録します。
+// キー入力の時刻を記録しないため、入力の時間は 0 になります。時刻を記録する場合は KeyDownAt を使用します。
+// 判定は Input.Key で行い、ローマ字の複数の綴り (Table)、促音「っ」の子音の重ね打ち、
+// 撥音「ん」の "n" 1文字での入力 (次の文字の先読み) に対応します。
+//
+// 引数:
+//   - key: 入力されたキー (1文字)。
+//   - mode: 判定対象の配列を指定するモード。
+//   - 1: 英語 (CurrentDataArrayE) を対象とする。
+//   - 2: 日本語 (CurrentDataArrayJ) を対象とする。
+//
+// 戻り値:
+//   - キー入力の判定結果。無効なモードや問題が設定されていない場合は Status が KeyRejected の結果 (記録はしない)。
+func (t *Typing) KeyDown(key string, mode int) KeyResult {
+	return t.KeyDownAt(key, mode, 0)
+}
 
-	// --- 通常の文字（促音「っ」、撥音「ん」以外）の判定 ---
-	if targetElement != "っ" && targetElement != "ん" {
-		// RomajiMap に対象文字が存在するか確認 (主に日本語ひらがな)
-		if words, exists := RomajiMap[targetElement]; exists {
-			// 対応するローマ字入力パターンを順にチェック
-			for _, word := range words {
-				// ユーザー入力がローマ字パターンで終わっているか確認
-				if strings.HasSuffix(userInput, word) {
-					// 正解ならインデックスを1進めて返す
-					return index + 1
-				}
-			}
-		} else if strings.HasSuffix(userInput, targetElement) {
-			// RomajiMap に存在しない文字（英語アルファベット、記号など）の場合、
-			// ユーザー入力が対象文字で終わっているか確認
-			return index + 1
-		}
-		// どのパターンにも一致しない場合は現在のインデックスを返す (入力途中または不正解)
-		return index
+// KeyDownAt は KeyDown と同じ処理を、キーを入力した時刻 at とともに記録します。
+// at は JavaScriptの KeyboardEvent.timeStamp のように任意の基準からの経過時間で、
+// 同じ問題のキー入力の間の差から CPM などを計算し (Recorder.Record を参照)、リプレイ用の記録 (Recording) にも追加します。
+func (t *Typing) KeyDownAt(key string, mode int, at time.Duration) KeyResult {
+	in := t.Input(mode)
+	if in == nil {
+		return KeyResult{Status: KeyRejected}
 	}
-
-	// --- 特殊文字（促音「っ」、撥音「ん」）の判定 ---
-	nextElement := ""
-	// 次の要素が存在するか確認
-	if index < sliceLength-1 {
-		nextElement = dataArry[index+1]
+	if in.Done() {
+		// 入力が完了した後のキーは誤りとして数えない
+		return KeyResult{Index: in.Position(), Status: KeyRejected, Done: true}
 	}
-
-	// --- 促音「っ」の判定 ---
-	if targetElement == "っ" {
-		// 文末が「っ」の場合（通常は稀）
-		if nextElement == "" {
-			// "xtu" または "ltu" で終わっていれば正解
-			for _, word := range []string{"xtu", "ltu"} {
-				if strings.HasSuffix(userInput, word) {
-					return index + 1
-				}
-			}
-		} else {
-			// 次の文字が存在する場合 (例: 「っこ」「っぱ」)
-			// 次の文字に対応するローマ字パターンを取得
-			if words, exists := RomajiMap[nextElement]; exists {
-				// 判定用パターンリストを初期化 ("xtu", "ltu" は「っ」単独入力用)
-				patterns := []string{"xtu", "ltu"}
-				// 次の文字のローマ字パターンから促音パターンを生成
-				for _, word := range words {
-					// 例: word が "ko" なら firstChar は "k"
-					firstChar := word[:1]
-					// 例: "k" + "ko" -> "kko" をパターンに追加
-					patterns = append(patterns, firstChar+word)
-				}
-				// 生成したパターンとユーザー入力を比較
-				for _, pattern := range patterns {
-					if strings.HasSuffix(userInput, pattern) {
-						// "xtu" または "ltu" なら「っ」のみ入力完了 -> インデックスを1進める
-						if pattern == "xtu" || pattern == "ltu" {
-							return index + 1
-						}
-						// それ以外のパターン（例: "kko"）なら「っ」＋次の文字まで入力完了 -> インデックスを2進める
-						return index + 2
-					}
-				}
-			}
-		}
-	} else if targetElement == "ん" { // --- 撥音「ん」の判定 ---
-		// 文末が「ん」の場合
-		if nextElement == "" {
-			// 文末の「ん」は "nn" で入力する必要がある
-			if strings.HasSuffix(userInput, "nn") {
-				return index + 1
-			}
-		} else {
-			// 次の文字が存在する場合
-			// 次の文字に対応するローマ字パターンを取得
-			if words, exists := RomajiMap[nextElement]; exists {
-				needsNN := false // "nn" が必要かどうかのフラグ
-				// 次の文字のローマ字パターンをチェック
-				for _, word := range words {
-					// パターンの最初の文字を取得
-					firstChar := string(word[0])
-					// 次の文字が母音(a,i,u,e,o)、n、y で始まる場合、"nn" が必要
-					if strings.Contains("aiueony", firstChar) {
-						needsNN = true
-						break
-					}
-				}
-				// "nn" が必要で、ユーザー入力が "nn" で終わっている場合
-				if needsNN && strings.HasSuffix(userInput, "nn") {
-					return index + 1
-				}
-				// "nn" が不要で、ユーザー入力が "n" で終わっている場合
-				if !needsNN && strings.HasSuffix(userInput, "n") {
-					return index + 1
-				}
-			} else if strings.HasSuffix(userInput, "nn") {
-				// 次の文字が RomajiMap にない場合 (例: んa)、"nn" で入力する必要がある
-				return index + 1
-			}
-		}
+	unit := in.Unit()
+	expected := in.ValidKeys()
+	status := in.Key(key)
+	result := KeyResult{
+		Index:    in.Position(),
+		Status:   status,
+		Mistake:  status == KeyRejected,
+		Expected: expected,
+		Next:     in.ValidKeys(),
+		Buffer:   in.Buffer(),
+		Done:     in.Done(),
 	}
-
-	// どの条件にも一致しない場合は現在のインデックスを返す (入力途中または不正解)
-	return index
+	t.Recorder.Record(mode, unit, key, at, result)
+	t.Recording.record(mode, key, at, result)
+	return result
 }
@@ -1,9 +1,12 @@
 package typing
 
 import (
+	"encoding/json"
 	"english_app_for_japanese/wasm/objects"
-	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 // equalStringSlice は2つの文字列スライスが等しいか比較します。
@@ -22,29 +25,33 @@ func equalStringSlice(a, b []string) bool {
 func TestCreateCurrentDataArray(t *testing.T) {
 	typingInstance := Typing{}
 	testCases := []struct {
-		name     string
-		en2      string
-		kana     string
-		expected []string
+		name      string
+		en2       string
+		kana      string
+		expectedE []string
+		expectedJ []string
 	}{
-		{"Hiragana", "", "あきょう", []string{"あ", "きょ", "う"}},
-		{"Mixed", "hello ", "こんにちは", []string{"h", "e", "l", "l", "o", " ", "こ", "ん", "に", "ち", "は"}},
-		{"Empty", "", "", []string{}},
-		{"Small Kana", "", "しゃしん", []string{"しゃ", "し", "ん"}},
-		{"Complex Small Kana", "", "ちぇるのびゅいりゅ", []string{"ちぇ", "る", "の", "びゅ", "い", "りゅ"}},
-		// カタカナは未対応
-		{"With Symbols", "Go!", "ゴー！", []string{"G", "o", "!", " ", "ゴ", "ー", "！"}},
+		{"Hiragana", "", "あきょう", []string{}, []string{"あ", "きょ", "う"}},
+		{"Mixed", "hello ", "こんにちは", []string{"h", "e", "l", "l", "o", " "}, []string{"こ", "ん", "に", "ち", "は"}},
+		{"Empty", "", "", []string{}, []string{}},
+		{"Small Kana", "", "しゃしん", []string{}, []string{"しゃ", "し", "ん"}},
+		{"Complex Small Kana", "", "ちぇるのびゅいりゅ", []string{}, []string{"ちぇ", "る", "の", "びゅ", "い", "りゅ"}},
+		{"With Symbols", "Go!", "ゴー！", []string{"G", "o", "!"}, []string{"ゴ", "ー", "！"}},
+		{"Katakana Small Kana", "", "ヴァイオリンとティー", []string{}, []string{"ヴァ", "イ", "オ", "リ", "ン", "と", "ティ", "ー"}},
+		{"Full-width Alphanumerics", "", "ＣＤを２まい", []string{}, []string{"Ｃ", "Ｄ", "を", "２", "ま", "い"}},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			typingInstance.CurrentData = &objects.Datum{ExampleEn: tc.en2, Kana: tc.kana}
 			typingInstance.createCurrentDataArrayE()
-			got := typingInstance.CurrentDataArrayE
-			if equalStringSlice(got, tc.expected) {
-				t.Errorf("createCurrentDataArrayE() for '%s %s' failed: expected %v, got %v", tc.en2, tc.kana, tc.expected, got)
+			typingInstance.createCurrentDataArrayJ()
+			if got := typingInstance.CurrentDataArrayE; !equalStringSlice(got, tc.expectedE) {
+				t.Errorf("createCurrentDataArrayE() for '%s' failed: expected %v, got %v", tc.en2, tc.expectedE, got)
+			}
+			if got := typingInstance.CurrentDataArrayJ; !equalStringSlice(got, tc.expectedJ) {
+				t.Errorf("createCurrentDataArrayJ() for '%s' failed: expected %v, got %v", tc.kana, tc.expectedJ, got)
 			}
-			fmt.Println(tc.name)
 		})
 	}
 }
@@ -53,48 +60,626 @@ func TestKeyDown(t *testing.T) {
 	typingInstance := Typing{} // Typing インスタンスを作成
 
 	testCases := []struct {
-		name          string
-		questionSlice []string
-		userInput     string
-		index         int
-		expectedIndex int
+		name           string
+		questionSlice  []string
+		keys           string
+		expectedIndex  int
+		expectedStatus KeyStatus
+	}{
+		{"Normal Hiragana", []string{"ら", "こ"}, "ra", 1, KeyAccepted},
+		{"Pending", []string{"ら", "こ"}, "r", 0, KeyPending},
+		{"Wrong key", []string{"ら", "こ"}, "x", 0, KeyRejected},
+		{"Wrong key keeps input", []string{"し"}, "sx", 0, KeyRejected},
+		{"Normal Alphabet", []string{"i", "s", "a"}, "isa", 3, KeyAccepted},
+		{"Sokuon (xtu)", []string{"ら", "っ", "こ"}, "raxtu", 2, KeyAccepted},             // 「っ」だけ入力
+		{"Sokuon (ltu)", []string{"ら", "っ", "こ"}, "raltu", 2, KeyAccepted},             // 「っ」だけ入力
+		{"Sokuon + Consonant (kko)", []string{"ら", "っ", "こ"}, "rakko", 3, KeyAccepted}, // 「っこ」を入力
+		{"Sokuon + Consonant (k)", []string{"ら", "っ", "こ"}, "rak", 2, KeyAccepted},     // 子音の重ね打ちで「っ」が完了
+		{"Sokuon + Consonant (ppa)", []string{"ら", "っ", "ぱ"}, "rappa", 3, KeyAccepted},
+		{"Sokuon + wrong consonant", []string{"ら", "っ", "ぱ"}, "rak", 1, KeyRejected},
+		{"Sokuon (cca)", []string{"ら", "っ", "か"}, "racca", 3, KeyAccepted},
+		{"Sokuon (cka, fail)", []string{"ら", "っ", "か"}, "rack", 2, KeyRejected}, // 「っ」を c で入力したら「か」も c で始める
+		{"Sokuon (csi, fail)", []string{"ら", "っ", "し"}, "racs", 2, KeyRejected},
+		{"Sokuon (cti, fail)", []string{"ら", "っ", "ち"}, "ract", 2, KeyRejected},
+		{"Sokuon (cchi)", []string{"ら", "っ", "ち"}, "racchi", 3, KeyAccepted},
+		{"Sokuon (tti)", []string{"ら", "っ", "ち"}, "ratti", 3, KeyAccepted},
+		{"N + Consonant (n)", []string{"り", "ん", "ご"}, "ringo", 3, KeyAccepted},       // 「ん」の後に子音 -> n
+		{"N + Consonant (n, pending)", []string{"り", "ん", "ご"}, "rin", 1, KeyPending}, // "n" だけでは nn の途中と区別できない
+		{"N + Consonant (ng)", []string{"り", "ん", "ご"}, "ring", 2, KeyAccepted},       // 次のキーで「ん」が完了
+		{"N + Consonant (nn)", []string{"り", "ん", "ご"}, "rinngo", 3, KeyAccepted},
+		{"N + Vowel (nn)", []string{"か", "ん", "い"}, "kanni", 3, KeyAccepted},          // 「ん」の後に母音 -> nn
+		{"N + Vowel (n, fail)", []string{"か", "ん", "い"}, "kani", 1, KeyRejected},      // 「ん」の後に母音 -> n ではダメ
+		{"N + N sound (nn)", []string{"ほ", "ん", "な"}, "honnna", 3, KeyAccepted},       // 「ん」の後に「な行」 -> nn
+		{"N + N sound (n, fail)", []string{"ほ", "ん", "な"}, "honna", 2, KeyRejected},   // 「ん」の後に「な行」 -> n ではダメ
+		{"N + Y sound (nn)", []string{"き", "ん", "よ", "う"}, "kinnyou", 4, KeyAccepted}, // 「ん」の後に「や行」 -> nn
+		{"N + Y sound (n, fail)", []string{"き", "ん", "よ", "う"}, "kiny", 1, KeyRejected},
+		{"N at end (nn)", []string{"ぺ", "ん"}, "penn", 2, KeyAccepted},       // 文末の「ん」 -> nn
+		{"N at end (n, pending)", []string{"ぺ", "ん"}, "pen", 1, KeyPending}, // 文末の「ん」 -> n だけでは完了しない
+		{"Leftover keys do not count", []string{"か", "き"}, "kaka", 1, KeyRejected},
+		{"Finished", []string{"a", "b"}, "abc", 2, KeyRejected},
+		{"Empty slice", []string{}, "a", 0, KeyRejected},
+		{"Romaji variation (shi)", []string{"し"}, "shi", 1, KeyAccepted},
+		{"Romaji variation (si)", []string{"し"}, "si", 1, KeyAccepted},
+		{"Romaji variation (chi)", []string{"ち"}, "chi", 1, KeyAccepted},
+		{"Romaji variation (ti)", []string{"ち"}, "ti", 1, KeyAccepted},
+		{"Romaji variation (tsu)", []string{"つ"}, "tsu", 1, KeyAccepted},
+		{"Romaji variation (tu)", []string{"つ"}, "tu", 1, KeyAccepted},
+		{"Romaji variation (ja)", []string{"じゃ"}, "ja", 1, KeyAccepted},
+		{"Romaji variation (jya)", []string{"じゃ"}, "jya", 1, KeyAccepted},
+		{"Romaji variation (zya)", []string{"じゃ"}, "zya", 1, KeyAccepted},
+		{"Katakana", []string{"ゴ", "ー", "！"}, "go-!", 3, KeyAccepted},
+		{"Katakana Sokuon", []string{"カ", "ッ", "プ"}, "kappu", 3, KeyAccepted},
+		{"Katakana N", []string{"リ", "ン", "ゴ"}, "ringo", 3, KeyAccepted},
+		{"Katakana Small Kana", []string{"ヴァ", "ヵ", "ヶ"}, "vaxkalke", 3, KeyAccepted},
+		{"Full-width", []string{"Ｃ", "Ｄ", "２", "？"}, "CD2?", 4, KeyAccepted},
+		{"Full-width (case)", []string{"Ｃ"}, "c", 0, KeyRejected},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			typingInstance.CurrentDataArrayJ = tc.questionSlice
+			typingInstance.inputJ = NewInput(tc.questionSlice, nil)
+			var result KeyResult
+			for _, key := range tc.keys {
+				result = typingInstance.KeyDown(string(key), ModeJapanese)
+			}
+			if result.Index != tc.expectedIndex || result.Status != tc.expectedStatus {
+				t.Errorf("KeyDown(%q) with slice %v failed: expected %d %s, got %d %s", tc.keys, tc.questionSlice, tc.expectedIndex, tc.expectedStatus, result.Index, result.Status)
+			}
+		})
+	}
+}
+
+func TestValidKeys(t *testing.T) {
+	testCases := []struct {
+		name     string
+		units    []string
+		keys     string
+		expected []string
+	}{
+		{"Start", []string{"し"}, "", []string{"c", "s"}},
+		{"Middle", []string{"し"}, "s", []string{"h", "i"}},
+		{"Sokuon", []string{"っ", "か"}, "", []string{"c", "k", "l", "x"}},
+		{"N before consonant", []string{"ん", "か"}, "n", []string{"'", "c", "k", "n"}},
+		{"N before vowel", []string{"ん", "あ"}, "n", []string{"'", "n"}},
+		{"Done", []string{"a"}, "a", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			in := NewInput(tc.units, nil)
+			for _, key := range tc.keys {
+				in.Key(string(key))
+			}
+			if got := in.ValidKeys(); !equalStringSlice(got, tc.expected) {
+				t.Errorf("ValidKeys() after %q: expected %v, got %v", tc.keys, tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	typingInstance := Typing{FilteredArray: []objects.Datum{
+		{ID: 1, ExampleEn: "lip", Kana: "しか"},
+		{ID: 2, ExampleEn: "a", Kana: "あ"},
+	}}
+	typingInstance.SetData(0)
+	// "l" のところで "r"、「し」の最初で "x"、「か」の途中で "o" を誤って入力する
+	for _, step := range []struct {
+		keys string
+		mode int
+	}{
+		{"rlip", ModeEnglish},
+		{"xshkikoa", ModeJapanese},
+	} {
+		for _, key := range step.keys {
+			result := typingInstance.KeyDown(string(key), step.mode)
+			if result.Mistake != (result.Status == KeyRejected) {
+				t.Errorf("unexpected mistake flag for %q: %+v", key, result)
+			}
+		}
+	}
+	typingInstance.SetData(1)
+	typingInstance.KeyDown("a", ModeJapanese)
+
+	r := typingInstance.Recorder
+	if len(r.Questions) != 1 || r.Questions[0] != (QuestionStats{
+		ID: 1, Keystrokes: 12, Mistakes: 4,
+		English:  ModeResult{Chars: 3, Keystrokes: 4, Mistakes: 1},
+		Japanese: ModeResult{Chars: 2, Keystrokes: 8, Mistakes: 3},
+	}) {
+		t.Errorf("unexpected question stats %+v", r.Questions)
+	}
+	if r.Total.Keystrokes != 13 || r.Total.Mistakes != 4 || r.Current.Accuracy() != 1 {
+		t.Errorf("unexpected totals %+v, current %+v", r.Total, r.Current)
+	}
+	// 「し」の "sh" の後の "k" と「か」の途中の "o" は同じ「し」「か」の誤りとして数える
+	misses := r.TopMisses()
+	if len(misses) != 3 || misses[0] != (CharCount{"し", 2}) {
+		t.Errorf("unexpected misses %v", misses)
+	}
+	// 入力すべきキーが1つに決まる誤りのみ組み合わせを記録する ("x" は s/c のどちらか決まらない)
+	confusions := r.TopConfusions()
+	want := []ConfusionCount{{Confusion{"a", "o"}, 1}, {Confusion{"i", "k"}, 1}, {Confusion{"l", "r"}, 1}}
+	if !slices.Equal(confusions, want) {
+		t.Errorf("expected confusions %v, got %v", want, confusions)
+	}
+}
+
+func TestGuide(t *testing.T) {
+	testCases := []struct {
+		name      string
+		kana      string
+		style     RomajiStyle
+		keys      string
+		wantTyped string
+		wantRest  string
+	}{
+		{"Hepburn", "ちかてつ", StyleHepburn, "", "", "chikatetsu"},
+		{"Kunrei", "ちかてつ", StyleKunrei, "", "", "tikatetu"},
+		{"Youon", "しゃしん", StyleHepburn, "", "", "shashinn"},
+		{"Youon Kunrei", "じゃくてん", StyleKunrei, "", "", "zyakutenn"},
+		{"Sokuon", "がっこう", StyleHepburn, "", "", "gakkou"},
+		{"Hatsuon before consonant", "しんぶん", StyleHepburn, "", "", "shinbunn"},
+		{"Hatsuon before vowel", "きんえん", StyleHepburn, "", "", "kinnenn"},
+		{"Switch to typed spelling", "しち", StyleHepburn, "s", "s", "hichi"},
+		{"Switch mid-word", "しち", StyleHepburn, "si", "si", "chi"},
+		{"Switch next char", "しち", StyleHepburn, "sit", "sit", "i"},
+		{"Kunrei switch", "ふじ", StyleKunrei, "f", "f", "uzi"},
+		{"Wrong key ignored", "か", StyleHepburn, "kx", "k", "a"},
+		{"Sokuon typed with xtu", "がっこう", StyleHepburn, "gaxtu", "gaxtu", "kou"},
+		{"Sokuon typed with c", "がっこう", StyleHepburn, "gac", "gac", "cou"}, // 重ねた子音で始まる綴りを選ぶ
+		{"Hatsuon pending", "しんぶん", StyleHepburn, "shin", "shin", "bunn"},
+		{"Done", "か", StyleHepburn, "ka", "ka", ""},
+		{"Katakana", "コーヒーショップ", StyleHepburn, "", "", "ko-hi-shoppu"},
+		{"Katakana Kunrei", "シャツ", StyleKunrei, "", "", "syatu"},
+	}
+	// 綴りのない文字の前の「っ」は子音を重ねずに単独で入力する綴りを表示する (panic しない)
+	table := cloneTable(RomajiMap)
+	table["　"] = nil
+	if _, rest := NewInput([]string{"あ", "っ", "　"}, table).Guide(StyleHepburn); rest != "axtu" {
+		t.Errorf("Guide() before a unit without spellings = %q", rest)
+	}
+	if got := fallbackRank(""); got != 0 {
+		t.Errorf("fallbackRank(\"\") = %d", got)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			typingInstance := Typing{Style: tc.style, FilteredArray: []objects.Datum{{Kana: tc.kana}}}
+			typingInstance.SetData(0)
+			for _, key := range tc.keys {
+				typingInstance.KeyDown(string(key), ModeJapanese)
+			}
+			typed, rest := typingInstance.Guide(ModeJapanese)
+			if typed != tc.wantTyped || rest != tc.wantRest {
+				t.Errorf("Guide() after %q: expected %q + %q, got %q + %q", tc.keys, tc.wantTyped, tc.wantRest, typed, rest)
+			}
+		})
+	}
+}
+
+// checkTableTypable は対応表のすべての文字が、記載されたすべての綴りで入力できることを確認します。
+func checkTableTypable(t *testing.T, table RomajiTable) {
+	t.Helper()
+	for unit, words := range table {
+		if len(words) == 0 {
+			// 綴りのない文字を含む問題文は最後まで入力できない
+			t.Errorf("%s: no spellings", unit)
+		}
+		for _, word := range words {
+			// 文末の「ん」は "n" では完了しないため、後ろに「。」を置いて最後まで入力する
+			in := NewInput([]string{unit, "。"}, table)
+			for _, key := range word + "." {
+				if in.Key(string(key)) == KeyRejected {
+					t.Errorf("%s: %q rejected at %q", unit, word, key)
+					break
+				}
+			}
+			if !in.Done() {
+				t.Errorf("%s: %q did not complete", unit, word)
+			}
+		}
+		if unit == "ん" {
+			continue
+		}
+		for i, a := range words {
+			for _, b := range words[i+1:] {
+				if strings.HasPrefix(a, b) || strings.HasPrefix(b, a) {
+					t.Errorf("%s: %q and %q are ambiguous", unit, a, b)
+				}
+			}
+		}
+	}
+}
+
+// TestRomajiMapConformance は RomajiMap が主要な IME の綴りに対応していることを確認します。
+func TestRomajiMapConformance(t *testing.T) {
+	checkTableTypable(t, RomajiMap)
+
+	// Microsoft IME と Google 日本語入力で入力できる代表的な綴り
+	reference := []struct {
+		kana string
+		keys string
+	}{
+		{"っ", "ltsu"}, {"っ", "xtsu"}, {"ん", "xn"}, {"ん", "n'"},
+		{"ぁ", "la"}, {"ぃ", "xyi"}, {"ぅ", "xu"}, {"ぇ", "lye"}, {"ぉ", "xo"},
+		{"ゃ", "lya"}, {"ゅ", "xyu"}, {"ょ", "lyo"}, {"ゎ", "xwa"}, {"ヵ", "lka"}, {"ヶ", "xke"},
+		{"てゃ", "tha"}, {"でぃ", "dhi"}, {"とぅ", "twu"}, {"くぁ", "kwa"}, {"くぁ", "qa"}, {"ぐぉ", "gwo"},
+		{"ちゃ", "cha"}, {"ちゅ", "cyu"}, {"ちょ", "cho"}, {"ふゅ", "fyu"}, {"つぁ", "tsa"}, {"すぃ", "swi"},
+		{"きゅ", "kixyu"}, {"しゃ", "cilya"}, {"ちぇ", "chixe"}, {"ゔぁ", "vuxa"}, {"いぇ", "yixe"},
+		{"ゐ", "wyi"}, {"ゑ", "wye"}, {"い", "yi"}, {"く", "qu"},
+		{"きゃんでぃー", "kyan'dhi-"}, {"ヴィンテージ", "vinte-ji"}, {"ウィンドウ", "winndou"},
+		{"あ　い", "a i"}, // 全角スペースは半角スペースで入力する
+	}
+	for _, tc := range reference {
+		typingInstance := Typing{FilteredArray: []objects.Datum{{Kana: tc.kana}}}
+		typingInstance.SetData(0)
+		var result KeyResult
+		for _, key := range tc.keys {
+			if result = typingInstance.KeyDown(string(key), ModeJapanese); result.Mistake {
+				t.Errorf("%s: %q rejected at %q", tc.kana, tc.keys, key)
+				break
+			}
+		}
+		if !result.Done {
+			t.Errorf("%s: %q did not complete", tc.kana, tc.keys)
+		}
+	}
+}
+
+func TestPreset(t *testing.T) {
+	testCases := []struct {
+		preset string
+		kana   string
+		keys   string
+	}{
+		{PresetStandard, "しゃしん", "shashinn"},
+		{PresetAZIK, "しゃしん", "xaxiq"},
+		{PresetAZIK, "ちょっと", "co;to"},
+		{PresetAZIK, "ちょっと", "chotto"},
+		{PresetAZIK, "パーティー", "pa-tgi-"},
+		{PresetKana, "がっこう", "か゛っこう"},
+		{PresetKana, "ギョーザ", "き゛ょーさ゛"},
+		{PresetKana, "ぱんだ", "ぱんだ"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.preset+" "+tc.keys, func(t *testing.T) {
+			table, ok := Preset(tc.preset)
+			if !ok {
+				t.Fatalf("Preset(%q) not found", tc.preset)
+			}
+			checkTableTypable(t, table)
+			typingInstance := Typing{Table: table, FilteredArray: []objects.Datum{{Kana: tc.kana}}}
+			typingInstance.SetData(0)
+			var result KeyResult
+			for _, key := range tc.keys {
+				if result = typingInstance.KeyDown(string(key), ModeJapanese); result.Mistake {
+					t.Fatalf("%s: %q rejected at %q", tc.kana, tc.keys, key)
+				}
+			}
+			if !result.Done {
+				t.Errorf("%s: %q did not complete", tc.kana, tc.keys)
+			}
+		})
+	}
+	if _, ok := Preset("qwerty"); ok {
+		t.Error("Preset(\"qwerty\") should not exist")
+	}
+	// プリセットの作成で RomajiMap が変更されないこと
+	if slices.Contains(RomajiMap["ん"], "q") {
+		t.Error("RomajiMap was modified by the AZIK preset")
+	}
+}
+
+func TestParseRomajiTable(t *testing.T) {
+	tsv := "# custom table\r\n" +
+		"a\tあ\r\n" +
+		"ka\tか\n" +
+		"kya\tキャ\n" +
+		"kk\tっ\tk\n" +
+		"nk\tん\tk\n" +
+		"nn\tん\n" +
+		"n\tん\tn\n" + // 次の入力を除くと綴りが空になる
+		"kz\tかん\n" +
+		"\n" +
+		"ca\tか\n"
+	table, skipped, err := ParseRomajiTable(tsv)
+	if err != nil {
+		t.Fatalf("ParseRomajiTable() error: %v", err)
+	}
+	if skipped != 2 {
+		t.Errorf("expected 2 skipped entries, got %d", skipped)
+	}
+	if !slices.Equal(table["か"], []string{"ka", "ca"}) || !slices.Equal(table["ん"], []string{"n", "nn"}) {
+		t.Errorf("unexpected spellings か %v, ん %v", table["か"], table["ん"])
+	}
+	// カタカナの出力はひらがなとして読み込み、1文字ずつ入力する綴りを追加する
+	if !slices.Contains(table["きゃ"], "kya") || !slices.Contains(table["きゃ"], "kixya") {
+		t.Errorf("unexpected spellings きゃ %v", table["きゃ"])
+	}
+	// テーブルにない文字は RomajiMap で補う
+	if !slices.Equal(table["し"], RomajiMap["し"]) {
+		t.Errorf("unexpected spellings し %v", table["し"])
+	}
+	checkTableTypable(t, table)
+
+	for _, invalid := range []string{"", "# only comment\n", "ka か\n", "\tか\n"} {
+		if _, _, err := ParseRomajiTable(invalid); err == nil {
+			t.Errorf("ParseRomajiTable(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestInitPractice(t *testing.T) {
+	appData := objects.AppData{Data: []objects.Datum{
+		{ID: 1, Word: "dog", DefinitionJa: "いぬ", Level: 1, ExampleEn: "I have a dog.", Kana: "いぬをかっています。"},
+		{ID: 2, Word: "cat", DefinitionJa: "猫", Level: 1, ExampleEn: "The cat is sleeping on the sofa.", Kana: "ねこがそふぁでねています。"},
+		{ID: 3, Word: "coffee", DefinitionJa: "コーヒー", Level: 1, ExampleEn: "", Kana: ""},
+		{ID: 4, Word: "run", DefinitionJa: "走る", Level: 2, ExampleEn: "Run!", Kana: "はしれ！"},
+	}}
+	ids := func(data []objects.Datum) []int {
+		var results []int
+		for _, d := range data {
+			results = append(results, d.ID)
+		}
+		return results
+	}
+	testCases := []struct {
+		name     string
+		filter   objects.Filter
+		practice Practice
+		min, max int
+		expected []int
+	}{
+		{"Sentence skips empty example", objects.Filter{Excluded: objects.ExcludedAll, Sort: objects.SortID}, PracticeSentence, 0, 0, []int{1, 2, 4}},
+		{"Sentence length", objects.Filter{Excluded: objects.ExcludedAll, Sort: objects.SortID}, "", 5, 20, []int{1}},
+		{"Word", objects.Filter{Excluded: objects.ExcludedAll, Sort: objects.SortID}, PracticeWord, 0, 0, []int{1, 2, 3, 4}},
+		{"Word length and level", objects.Filter{Excluded: objects.ExcludedAll, Sort: objects.SortID, Levels: []int{1}}, PracticeWord, 0, 3, []int{1, 2}},
+		{"No match", objects.Filter{Excluded: objects.ExcludedAll, Sort: objects.SortID}, PracticeWord, 10, 0, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			typingInstance := Typing{Practice: tc.practice, MinLength: tc.min, MaxLength: tc.max}
+			typingInstance.Init(&appData, tc.filter)
+			if got := ids(typingInstance.FilteredArray); !slices.Equal(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	// 単語の練習では見出し語と、かなのみの場合は日本語の意味を入力する
+	typingInstance := Typing{Practice: PracticeWord}
+	typingInstance.Init(&appData, objects.Filter{Excluded: objects.ExcludedAll, Sort: objects.SortID})
+	for _, want := range []struct {
+		index  int
+		en, ja []string
 	}{
-		{"Normal Hiragana", []string{"ら", "こ"}, "ra", 0, 1},
-		{"Normal Alphabet", []string{"i", "s", "a"}, "a", 2, 3},
-		{"Sokuon (xtu)", []string{"ら", "っ", "こ"}, "xtu", 1, 2},                 // 「っ」だけ入力
-		{"Sokuon (ltu)", []string{"ら", "っ", "こ"}, "ltu", 1, 2},                 // 「っ」だけ入力
-		{"Sokuon + Consonant (kko)", []string{"ら", "っ", "こ"}, "zkko", 1, 3},    // 「っこ」を入力
-		{"Sokuon + Consonant (ppa)", []string{"ら", "っ", "ぱ"}, "aaaappa", 1, 3}, // 「っぱ」を入力
-		{"N + Consonant (n)", []string{"り", "ん", "ご"}, "n", 1, 2},              // 「ん」の後に子音 -> n
-		{"N + Vowel (nn)", []string{"か", "ん", "い"}, "nn", 1, 2},                // 「ん」の後に母音 -> nn
-		{"N + Vowel (n, fail)", []string{"か", "ん", "い"}, "n", 1, 1},            // 「ん」の後に母音 -> n ではダメ
-		{"N + N sound (nn)", []string{"ほ", "ん", "な"}, "nn", 1, 2},              // 「ん」の後に「な行」 -> nn
-		{"N + N sound (n, fail)", []string{"ほ", "ん", "な"}, "n", 1, 1},          // 「ん」の後に「な行」 -> n ではダメ
-		{"N + Y sound (nn)", []string{"き", "ん", "よう"}, "nn", 1, 2},             // 「ん」の後に「や行」 -> nn
-		{"N + Y sound (n, fail)", []string{"き", "ん", "よう"}, "n", 1, 1},         // 「ん」の後に「や行」 -> n ではダメ
-		{"N at end (nn)", []string{"ぺ", "ん"}, "nn", 1, 2},                      // 文末の「ん」 -> nn
-		{"N at end (n, fail)", []string{"ぺ", "ん"}, "n", 1, 1},                  // 文末の「ん」 -> n ではダメ
-		{"Out of bounds index", []string{"a", "b"}, "c", 2, 2},
-		{"Empty slice", []string{}, "a", 0, 0},
-		{"Romaji variation (shi)", []string{"し"}, "shi", 0, 1},
-		{"Romaji variation (si)", []string{"し"}, "si", 0, 1},
-		{"Romaji variation (chi)", []string{"ち"}, "chi", 0, 1},
-		{"Romaji variation (ti)", []string{"ち"}, "ti", 0, 1},
-		{"Romaji variation (tsu)", []string{"つ"}, "tsu", 0, 1},
-		{"Romaji variation (tu)", []string{"つ"}, "tu", 0, 1},
-		{"Romaji variation (ja)", []string{"じゃ"}, "ja", 0, 1},
-		{"Romaji variation (jya)", []string{"じゃ"}, "jya", 0, 1},
-		{"Romaji variation (zya)", []string{"じゃ"}, "zya", 0, 1},
+		{0, []string{"d", "o", "g"}, []string{"い", "ぬ"}},
+		{1, []string{"c", "a", "t"}, []string{}},
+		{2, []string{"c", "o", "f", "f", "e", "e"}, []string{"コ", "ー", "ヒ", "ー"}},
+	} {
+		typingInstance.SetData(want.index)
+		if !equalStringSlice(typingInstance.CurrentDataArrayE, want.en) || !equalStringSlice(typingInstance.CurrentDataArrayJ, want.ja) {
+			t.Errorf("SetData(%d): expected %v %v, got %v %v", want.index, want.en, want.ja, typingInstance.CurrentDataArrayE, typingInstance.CurrentDataArrayJ)
+		}
+	}
+	if en, ja := typingInstance.Question(); en != "coffee" || ja != "コーヒー" {
+		t.Errorf("Question() = %q, %q", en, ja)
 	}
 
+	typingInstance.MinLength = 10
+	typingInstance.Init(&appData, objects.Filter{Excluded: objects.ExcludedAll})
+	typingInstance.SetData(0)
+	if typingInstance.CurrentData != nil || typingInstance.Input(ModeEnglish) != nil {
+		t.Errorf("SetData() on empty FilteredArray should clear the question")
+	}
+}
+
+func TestEnglishOptions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		en        string
+		options   EnglishOptions
+		keys      string
+		wantIndex int
+		wantDone  bool
+	}{
+		{"Exact", "It's fine.", EnglishOptions{}, "It's fine.", 10, true},
+		{"Case sensitive", "It", EnglishOptions{}, "it", 0, false},
+		{"Ignore case", "It's", EnglishOptions{IgnoreCase: true}, "iT'S", 4, true},
+		{"Smart quote", "It’s “ok”", EnglishOptions{}, "It's \"ok\"", 9, true},
+		{"Smart quote typed as is", "It’s", EnglishOptions{}, "It’s", 4, true},
+		{"Dash and ellipsis", "Wait—what…", EnglishOptions{}, "Wait-what...", 10, true},
+		{"Ellipsis pending", "So…", EnglishOptions{}, "So..", 2, false},
+		{"Skip punctuation", "“Yes,” she said.", EnglishOptions{SkipPunctuation: true}, "Yes she said", 16, true},
+		{"Skip punctuation keeps spaces", "a, b", EnglishOptions{SkipPunctuation: true}, "ab", 2, false},
+		{"Skip leading punctuation", "“Hi”", EnglishOptions{SkipPunctuation: true}, "", 1, false},
+	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			typingInstance.CurrentDataArrayE = tc.questionSlice
-			result := typingInstance.KeyDown(tc.userInput, tc.index, 1)
-			fmt.Printf("Slice: %v, Input: '%s', Index: %d -> Result: %d (Expected: %d)\n", tc.questionSlice, tc.userInput, tc.index, result, tc.expectedIndex)
-			if result != tc.expectedIndex {
-				t.Errorf("KeyDown(%q, %d) with slice %v failed: expected %d, got %d", tc.userInput, tc.index, tc.questionSlice, tc.expectedIndex, result)
+			typingInstance := Typing{English: tc.options, FilteredArray: []objects.Datum{{ExampleEn: tc.en}}}
+			typingInstance.SetData(0)
+			for _, key := range tc.keys {
+				typingInstance.KeyDown(string(key), ModeEnglish)
+			}
+			in := typingInstance.Input(ModeEnglish)
+			if in.Position() != tc.wantIndex || in.Done() != tc.wantDone {
+				t.Errorf("after %q: expected index %d done %v, got %d %v", tc.keys, tc.wantIndex, tc.wantDone, in.Position(), in.Done())
+			}
+			// 表示用の文字配列は元の文字のまま
+			if got := strings.Join(typingInstance.CurrentDataArrayE, ""); got != tc.en {
+				t.Errorf("CurrentDataArrayE changed: %q", got)
 			}
 		})
 	}
+
+	typingInstance := Typing{English: EnglishOptions{IgnoreCase: true, SkipPunctuation: true}, FilteredArray: []objects.Datum{{ExampleEn: "I’m OK."}}}
+	typingInstance.SetData(0)
+	if typed, rest := typingInstance.Guide(ModeEnglish); typed != "" || rest != "Im OK" {
+		t.Errorf("Guide() = %q + %q, expected \"\" + \"Im OK\"", typed, rest)
+	}
+	// ガイドは活版印刷用の記号の代わりに ASCII の綴りを表示する
+	typingInstance = Typing{FilteredArray: []objects.Datum{{ExampleEn: "It’s “ok”—wait…"}}}
+	typingInstance.SetData(0)
+	if _, rest := typingInstance.Guide(ModeEnglish); rest != "It's \"ok\"-wait..." {
+		t.Errorf("Guide() rest = %q, expected ASCII spellings", rest)
+	}
+}
+
+func TestKeyDownAtStats(t *testing.T) {
+	typingInstance := Typing{FilteredArray: []objects.Datum{
+		{ID: 1, ExampleEn: "cat", Kana: "ねこ"},
+		{ID: 2, ExampleEn: "a", Kana: "あ"},
+	}}
+	typingInstance.Recorder.Started = time.UnixMilli(1000)
+	typingInstance.SetData(0)
+	// 最初のキー入力 (1秒) から完了 (1.5秒) までの時間を計る。誤ったキーも時間に含める
+	for i, key := range []string{"c", "x", "a", "t"} {
+		typingInstance.KeyDownAt(key, ModeEnglish, time.Second+time.Duration(i)*500*time.Millisecond/3)
+	}
+	for i, key := range "neko" {
+		typingInstance.KeyDownAt(string(key), ModeJapanese, 2*time.Second+time.Duration(i)*time.Second)
+	}
+	q := typingInstance.Recorder.Current
+	if q.English != (ModeResult{Chars: 3, Keystrokes: 4, Mistakes: 1, Duration: 500}) {
+		t.Errorf("unexpected English result %+v", q.English)
+	}
+	if q.Japanese != (ModeResult{Chars: 2, Keystrokes: 4, Duration: 3000}) {
+		t.Errorf("unexpected Japanese result %+v", q.Japanese)
+	}
+	if cpm := q.English.CPM(); cpm != 360 {
+		t.Errorf("expected 360 CPM, got %v", cpm)
+	}
+	if wpm, kpm := q.English.WPM(), q.English.KPM(); wpm != 72 || kpm != 360 {
+		t.Errorf("expected 72 WPM and 360 KPM, got %v %v", wpm, kpm)
+	}
+
+	// 入力が完了していない問題は集計しない
+	typingInstance.SetData(1)
+	typingInstance.KeyDownAt("a", ModeEnglish, 10*time.Second)
+	session := typingInstance.Recorder.Session()
+	want := SessionResult{Date: 1000, Questions: 2, English: ModeResult{Chars: 4, Keystrokes: 5, Mistakes: 1, Duration: 500}, Japanese: q.Japanese}
+	if session != want {
+		t.Errorf("expected session %+v, got %+v", want, session)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	result := func(cpm int) ModeResult {
+		return ModeResult{Chars: cpm, Keystrokes: 10, Mistakes: 1, Duration: 60000}
+	}
+	var h History
+	if updated := h.Record(SessionResult{Date: 1, English: result(100)}); !slices.Equal(updated, []string{ModeKeyEnglish}) {
+		t.Errorf("expected English best to be updated, got %v", updated)
+	}
+	// 同じセッションの途中の結果は置き換える
+	if updated := h.Record(SessionResult{Date: 1, English: result(120), Japanese: result(50)}); !slices.Equal(updated, []string{ModeKeyEnglish, ModeKeyJapanese}) {
+		t.Errorf("expected both bests to be updated, got %v", updated)
+	}
+	if updated := h.Record(SessionResult{Date: 2, English: result(110)}); updated != nil {
+		t.Errorf("expected no best to be updated, got %v", updated)
+	}
+	if len(h.Sessions) != 2 || h.Bests[ModeKeyEnglish].Date != 1 || h.Bests[ModeKeyJapanese].Result.CPM() != 50 {
+		t.Errorf("unexpected history %+v", h)
+	}
+
+	for i := range HistoryLimit + 5 {
+		h.Record(SessionResult{Date: int64(i + 3), English: result(60 + i%TrendWindow)})
+	}
+	if len(h.Sessions) != HistoryLimit || h.Sessions[0].Date != 8 {
+		t.Errorf("expected %d sessions from date 8, got %d from %d", HistoryLimit, len(h.Sessions), h.Sessions[0].Date)
+	}
+	trend := h.Trend(ModeKeyEnglish)
+	if trend.Recent.Chars != 310 || trend.Previous.Chars != 310 || trend.CPMChange() != 0 {
+		t.Errorf("unexpected trend %+v", trend)
+	}
+	// 日本語の記録があるセッションは上限を超えて削除されたため、傾向は計算しない
+	if trend := h.Trend(ModeKeyJapanese); trend.Recent.Chars != 0 || trend.CPMChange() != 0 || trend.AccuracyChange() != 0 {
+		t.Errorf("unexpected Japanese trend %+v", trend)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	typingInstance := Typing{FilteredArray: []objects.Datum{{ID: 1, ExampleEn: "ab", Kana: "か"}}, Ghosts: Ghosts{}}
+	run := func(keys []Keystroke) Recording {
+		typingInstance.SetData(0)
+		for _, k := range keys {
+			typingInstance.KeyDownAt(k.Key, k.Mode, 5*time.Second+time.Duration(k.At)*time.Millisecond)
+		}
+		return typingInstance.Recording
+	}
+	slow := run([]Keystroke{{0, ModeEnglish, "a", 0, false}, {300, ModeEnglish, "b", 0, false}, {500, ModeJapanese, "x", 0, false}, {700, ModeJapanese, "k", 0, false}, {900, ModeJapanese, "a", 0, false}})
+	if !slow.Done() || slow.Duration() != 900 || slow.Mistakes() != 1 {
+		t.Fatalf("unexpected recording %+v", slow)
+	}
+
+	// キー入力は [at, mode, key, index, mistake] の配列で保存する
+	data, err := json.Marshal(slow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"keys":[[0,1,"a",1,0],[300,1,"b",2,0],[500,2,"x",0,1],[700,2,"k",0,0],[900,2,"a",1,0]]`; !strings.Contains(string(data), want) {
+		t.Errorf("expected %s in %s", want, data)
+	}
+	parsed, err := ParseRecording(string(data))
+	if err != nil || !slices.Equal(parsed.Keys, slow.Keys) || parsed.Text != "ab\nか" {
+		t.Errorf("ParseRecording() = %+v, %v", parsed, err)
+	}
+	for _, invalid := range []string{
+		`{"chars":[1,0],"keys":[[0,3,"a",1,0]]}`,
+		`{"chars":[2,0],"keys":[[10,1,"a",1,0],[5,1,"b",2,0]]}`,
+		`{"chars":[1,0],"keys":[[0,1,"a",2,0]]}`,
+		`{"chars":[1,0],"keys":[[0,1,"a"]]}`,
+	} {
+		if _, err := ParseRecording(invalid); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+	// 保存したゴーストも同じ検証を行い、不正な記録だけを取り除く
+	ghosts, dropped, err := ParseGhosts(`{"ab\nか":` + string(data) + `,"x\n":{"text":"x\n","chars":[1,0],"keys":[[0,0,"x",1,0]]},"y\n":{"text":"y\n","chars":[1,0],"keys":[[0,3,"y",1,0]]}}`)
+	if err != nil || dropped != 2 || len(ghosts) != 1 || ghosts["ab\nか"].Duration() != 900 {
+		t.Errorf("ParseGhosts() = %v, %d, %v", ghosts, dropped, err)
+	}
+	if _, _, err := ParseGhosts(`[`); err == nil {
+		t.Error("expected error for invalid ghosts JSON")
+	}
+
+	for _, tc := range []struct {
+		elapsed int64
+		want    GhostPosition
+	}{
+		{-1, GhostPosition{Mode: ModeEnglish}},
+		{299, GhostPosition{Mode: ModeEnglish, Index: 1, Progress: 1.0 / 3}},
+		{800, GhostPosition{Mode: ModeJapanese, Index: 0, Progress: 2.0 / 3}},
+		{900, GhostPosition{Mode: ModeJapanese, Index: 1, Progress: 1, Done: true}},
+	} {
+		if got := slow.Position(tc.elapsed); got != tc.want {
+			t.Errorf("Position(%d) = %+v, expected %+v", tc.elapsed, got, tc.want)
+		}
+	}
+
+	if updated, err := typingInstance.Ghosts.Record(slow); !updated || err != nil {
+		t.Errorf("expected the first recording to become the ghost, got %v %v", updated, err)
+	}
+	fast := run([]Keystroke{{0, ModeEnglish, "a", 0, false}, {100, ModeEnglish, "b", 0, false}, {400, ModeJapanese, "k", 0, false}, {600, ModeJapanese, "a", 0, false}})
+	if typingInstance.Ghost == nil || typingInstance.Ghost.Duration() != 900 {
+		t.Fatalf("expected the ghost to be set on SetData, got %+v", typingInstance.Ghost)
+	}
+	c := fast.Compare(*typingInstance.Ghost)
+	if c.Duration != -300 || c.Mistakes != -1 || !slices.Equal(c.Splits, []int64{0, -200, -300}) {
+		t.Errorf("unexpected comparison %+v", c)
+	}
+	if updated, _ := typingInstance.Ghosts.Record(fast); !updated || typingInstance.Ghosts[fast.Text].Duration() != 600 {
+		t.Errorf("expected the faster recording to replace the ghost")
+	}
+	if updated, _ := typingInstance.Ghosts.Record(slow); updated {
+		t.Errorf("expected the slower recording not to replace the ghost")
+	}
+	typingInstance.SetData(0)
+	if _, err := typingInstance.Ghosts.Record(typingInstance.Recording); err != ErrRecordingNotDone {
+		t.Errorf("expected ErrRecordingNotDone, got %v", err)
+	}
+	if len(typingInstance.Recordings) != 2 {
+		t.Errorf("expected 2 session recordings, got %d", len(typingInstance.Recordings))
+	}
 }